@@ -0,0 +1,255 @@
+// Package apperror is the error type behind every API error response. Its
+// JSON form follows RFC 7807 (problem+json), with a stable machine readable
+// code and the request id added.
+package apperror
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// Codes shared by many routes. Domain errors register their own.
+const (
+	CodeBadRequest       = "bad_request"
+	CodeInvalidBody      = "invalid_body"
+	CodeValidationFailed = "validation_failed"
+	CodeMissingParameter = "missing_parameter"
+	CodeInvalidID        = "invalid_id"
+	CodeUnauthorized     = "unauthorized"
+	CodeForbidden        = "forbidden"
+	CodeNotFound         = "not_found"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeConflict         = "conflict"
+	CodeInternal         = "internal_error"
+	CodeBadGateway       = "bad_gateway"
+)
+
+// FieldError is one failed validation rule
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Error is an API error. Type, Title and Status come from the status code;
+// Code and Detail say what went wrong.
+type Error struct {
+	Type       string       `json:"type"`
+	Title      string       `json:"title"`
+	Status     int          `json:"status"`
+	Code       string       `json:"code"`
+	Detail     string       `json:"detail"`
+	Instance   string       `json:"instance,omitempty"`
+	Request_ID string       `json:"request_id,omitempty"`
+	Fields     []FieldError `json:"fields,omitempty"`
+
+	// extra members of the problem document, e.g. the changed cart lines
+	Extensions map[string]interface{} `json:"-"`
+
+	cause error
+}
+
+func New(status int, code, detail string) *Error {
+	return &Error{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Code:   code,
+		Detail: detail,
+	}
+}
+
+// Wrap builds the error for cause, keeping cause for errors.Is and the logs
+func Wrap(cause error, status int, code string) *Error {
+	e := New(status, code, cause.Error())
+	e.cause = cause
+	return e
+}
+
+// BadRequest reports a malformed request. Validation errors and JSON decoding
+// errors get their own codes.
+func BadRequest(err error) *Error {
+
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		return Validation(validationErrs)
+	}
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return Wrap(err, http.StatusBadRequest, CodeInvalidBody)
+	}
+
+	return Wrap(err, http.StatusBadRequest, CodeBadRequest)
+}
+
+// Validation lists every failed rule as a field error
+func Validation(errs validator.ValidationErrors) *Error {
+
+	e := Wrap(errs, http.StatusBadRequest, CodeValidationFailed)
+	e.Detail = "request failed validation"
+	for _, fieldErr := range errs {
+		// drop the struct name, keeping the path inside the request body
+		field := fieldErr.Namespace()
+		if i := strings.Index(field, "."); i >= 0 {
+			field = field[i+1:]
+		}
+
+		e.Fields = append(e.Fields, FieldError{
+			Field:   field,
+			Rule:    fieldErr.Tag(),
+			Message: fieldMessage(fieldErr),
+		})
+	}
+
+	return e
+}
+
+// Missing reports a required query parameter that was not sent
+func Missing(name string) *Error {
+	return New(http.StatusBadRequest, CodeMissingParameter, name+" is empty")
+}
+
+// InvalidID reports a query parameter that is not an object id
+func InvalidID(name string) *Error {
+	return New(http.StatusBadRequest, CodeInvalidID, name+" provided is invalid")
+}
+
+// With adds a member to the problem document
+func (e *Error) With(key string, value interface{}) *Error {
+	if e.Extensions == nil {
+		e.Extensions = make(map[string]interface{})
+	}
+	e.Extensions[key] = value
+	return e
+}
+
+// WithDetail replaces the message shown to the client, e.g. to hide the cause
+func (e *Error) WithDetail(detail string) *Error {
+	e.Detail = detail
+	return e
+}
+
+func (e *Error) Error() string {
+	if e.cause != nil {
+		return e.cause.Error()
+	}
+	return e.Detail
+}
+
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+func (e *Error) MarshalJSON() ([]byte, error) {
+
+	type problem Error
+	body, err := json.Marshal((*problem)(e))
+	if err != nil || len(e.Extensions) == 0 {
+		return body, err
+	}
+
+	members := make(map[string]interface{}, len(e.Extensions))
+	for key, value := range e.Extensions {
+		members[key] = value
+	}
+	if err := json.Unmarshal(body, &members); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(members)
+}
+
+type registration struct {
+	err    error
+	status int
+	code   string
+}
+
+var (
+	mu       sync.RWMutex
+	registry []registration
+)
+
+// Register maps a sentinel error onto the status and code it is reported with
+func Register(err error, status int, code string) {
+	mu.Lock()
+	defer mu.Unlock()
+	registry = append(registry, registration{err: err, status: status, code: code})
+}
+
+// From turns any error into an Error. Errors that are not an Error and are not
+// registered become an internal error whose detail hides the cause.
+func From(err error) *Error {
+
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		return Validation(validationErrs)
+	}
+
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, r := range registry {
+		if errors.Is(err, r.err) {
+			return Wrap(err, r.status, r.code)
+		}
+	}
+
+	e := New(http.StatusInternalServerError, CodeInternal, "Internal Server Error")
+	e.cause = err
+	return e
+}
+
+func fieldMessage(fieldErr validator.FieldError) string {
+	switch fieldErr.Tag() {
+	case "required":
+		return fieldErr.Field() + " is required"
+	case "email":
+		return fieldErr.Field() + " must be an email address"
+	case "oneof":
+		return fmt.Sprintf("%s must be one of %s", fieldErr.Field(), fieldErr.Param())
+	case "url":
+		return fieldErr.Field() + " must be an absolute URL"
+	case "http_url":
+		return fieldErr.Field() + " must be an http or https URL"
+	case "phone":
+		return fieldErr.Field() + " must be an E.164 phone number such as +919876543210"
+	case "pincode":
+		return fieldErr.Field() + " must be a 6 digit pincode"
+	case "price":
+		return fieldErr.Field() + " must not be negative"
+	case "numeric":
+		return fieldErr.Field() + " must only contain digits"
+	case "unique":
+		return fieldErr.Field() + " must not contain duplicates"
+	case "max_variants":
+		return fmt.Sprintf("%s must combine into at most %s variants", fieldErr.Field(), fieldErr.Param())
+	case "min", "gte", "max", "lte":
+		bound := "at least"
+		if fieldErr.Tag() == "max" || fieldErr.Tag() == "lte" {
+			bound = "at most"
+		}
+		switch fieldErr.Kind() {
+		case reflect.String:
+			return fmt.Sprintf("%s must be %s %s characters", fieldErr.Field(), bound, fieldErr.Param())
+		case reflect.Slice, reflect.Map, reflect.Array:
+			return fmt.Sprintf("%s must have %s %s items", fieldErr.Field(), bound, fieldErr.Param())
+		}
+		return fmt.Sprintf("%s must be %s %s", fieldErr.Field(), bound, fieldErr.Param())
+	}
+	return fmt.Sprintf("%s failed the %s rule", fieldErr.Field(), fieldErr.Tag())
+}
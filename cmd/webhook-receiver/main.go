@@ -0,0 +1,74 @@
+// Command webhook-receiver is a local stand-in for a merchant webhook endpoint.
+// It verifies the signature of every delivery, logs the event and can be told
+// to fail requests so retries and the dead-letter queue can be exercised.
+// Start the service with WEBHOOK_ALLOW_PRIVATE=true so it delivers to
+// localhost.
+//
+//	go run ./cmd/webhook-receiver -secret whsec_... -fail 3
+package main
+
+import (
+	"flag"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/mayuka-c/e-commerce/webhooks"
+)
+
+func main() {
+
+	addr := flag.String("addr", ":9090", "address to listen on")
+	secret := flag.String("secret", "", "endpoint secret returned by /admin/addwebhook")
+	fail := flag.Int("fail", 0, "answer the first n deliveries with 500")
+	flag.Parse()
+
+	if *secret == "" {
+		log.Fatal("-secret is required")
+	}
+
+	var mu sync.Mutex
+	failures := *fail
+
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		fields := log.Fields{
+			"delivery": r.Header.Get(webhooks.DeliveryHeader),
+			"event":    r.Header.Get(webhooks.EventTypeHeader),
+		}
+
+		err = webhooks.Verify(*secret, r.Header.Get(webhooks.SignatureHeader), body, 5*time.Minute, time.Now())
+		if err != nil {
+			log.WithFields(fields).Error(err)
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		mu.Lock()
+		failing := failures > 0
+		if failing {
+			failures--
+		}
+		mu.Unlock()
+
+		if failing {
+			log.WithFields(fields).Warn("Failing delivery on purpose")
+			http.Error(w, "simulated failure", http.StatusInternalServerError)
+			return
+		}
+
+		log.WithFields(fields).Info(string(body))
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	log.Println("Webhook receiver listening on", *addr)
+	log.Fatal(http.ListenAndServe(*addr, nil))
+}
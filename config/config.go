@@ -1,40 +1,90 @@
+// Package config holds every setting of the service in one tree. Settings
+// are read from, in increasing precedence, the defaults below, a YAML or TOML
+// file, environment variables and command line flags; see Load.
+//
+// Each setting names its environment variable in the envconfig tag, its key
+// in the file in the yaml tag (the same keys are used for TOML) and the rules
+// it must pass in the validate tag. The flag is the environment variable in
+// lower case with dashes, e.g. --db-url. Settings tagged secret are redacted
+// from Redacted.
 package config
 
-import (
-	"context"
+import "time"
 
-	"github.com/kelseyhightower/envconfig"
-	log "github.com/sirupsen/logrus"
-)
+// Config is the whole configuration. Its sections are the top level keys of
+// the file.
+type Config struct {
+	Service ServiceConfig `yaml:"service"`
+	DB      DBConfig      `yaml:"db"`
+	Auth    AuthConfig    `yaml:"auth"`
+}
 
 type ServiceConfig struct {
-	APIPort int `envconfig:"PORT" default:"8181"`
+	APIPort int `envconfig:"PORT" default:"8181" yaml:"port" validate:"min=1,max=65535"`
+	// PublicURL is where clients reach the service. With an https URL
+	// cookies are only sent over HTTPS.
+	PublicURL string `envconfig:"PUBLIC_URL" default:"http://localhost:8181" yaml:"public_url" validate:"url"`
+	// PasswordResetURL is the frontend page password reset emails link to,
+	// with the token added as ?token=. It posts the new password to
+	// /api/v1/auth/reset-password.
+	PasswordResetURL string `envconfig:"PASSWORD_RESET_URL" default:"http://localhost:3000/reset-password" yaml:"password_reset_url" validate:"url"`
+
+	// RequestTimeout bounds the database work of a request
+	RequestTimeout time.Duration `envconfig:"REQUEST_TIMEOUT" default:"100s" yaml:"request_timeout" validate:"gt=0"`
+
+	// HTTP server timeouts. WriteTimeout must outlast the handler timeouts.
+	ReadHeaderTimeout time.Duration `envconfig:"READ_HEADER_TIMEOUT" default:"5s" yaml:"read_header_timeout" validate:"gt=0"`
+	ReadTimeout       time.Duration `envconfig:"READ_TIMEOUT" default:"30s" yaml:"read_timeout" validate:"gt=0"`
+	WriteTimeout      time.Duration `envconfig:"WRITE_TIMEOUT" default:"2m" yaml:"write_timeout" validate:"gtfield=RequestTimeout"`
+	IdleTimeout       time.Duration `envconfig:"IDLE_TIMEOUT" default:"2m" yaml:"idle_timeout" validate:"gt=0"`
+
+	// on SIGTERM readiness fails for ShutdownDelay before the server stops
+	// accepting; in-flight requests and jobs then get ShutdownTimeout
+	ShutdownDelay   time.Duration `envconfig:"SHUTDOWN_DELAY" default:"0s" yaml:"shutdown_delay" validate:"gte=0"`
+	ShutdownTimeout time.Duration `envconfig:"SHUTDOWN_TIMEOUT" default:"30s" yaml:"shutdown_timeout" validate:"gt=0"`
+
+	// LogLevel is one of debug, info, warn or error; LogFormat is json or text
+	LogLevel  string `envconfig:"LOG_LEVEL" default:"info" yaml:"log_level" validate:"oneof=debug info warn error"`
+	LogFormat string `envconfig:"LOG_FORMAT" default:"json" yaml:"log_format" validate:"oneof=json text"`
+
+	// tracing; TraceExporter is one of none, stdout, file or otlp
+	TraceExporter    string  `envconfig:"TRACE_EXPORTER" default:"none" yaml:"trace_exporter" validate:"oneof=none stdout file otlp"`
+	TraceFile        string  `envconfig:"TRACE_FILE" default:"traces.jsonl" yaml:"trace_file" validate:"required_if=TraceExporter file"`
+	TraceSampleRatio float64 `envconfig:"TRACE_SAMPLE_RATIO" default:"1" yaml:"trace_sample_ratio" validate:"gte=0,lte=1"`
+
+	// WebhookAllowPrivate lets merchant webhook endpoints resolve to
+	// loopback and private addresses, e.g. for cmd/webhook-receiver
+	WebhookAllowPrivate bool `envconfig:"WEBHOOK_ALLOW_PRIVATE" default:"false" yaml:"webhook_allow_private"`
+
+	// outbox event sinks besides the in-process bus
+	EventWebhookURLs []string `envconfig:"EVENT_WEBHOOK_URLS" yaml:"event_webhook_urls" validate:"dive,url"`
+	EventFile        string   `envconfig:"EVENT_FILE" yaml:"event_file"`
+
+	// transactional email; Mailer is one of smtp, file or log
+	Mailer       string `envconfig:"MAILER" default:"file" yaml:"mailer" validate:"oneof=smtp file log"`
+	MailFrom     string `envconfig:"MAIL_FROM" default:"E-commerce <no-reply@localhost>" yaml:"mail_from" validate:"required"`
+	MailDir      string `envconfig:"MAIL_DIR" default:"mail" yaml:"mail_dir" validate:"required_if=Mailer file"`
+	SMTPAddr     string `envconfig:"SMTP_ADDR" default:"localhost:25" yaml:"smtp_addr" validate:"required_if=Mailer smtp,omitempty,hostname_port"`
+	SMTPUsername string `envconfig:"SMTP_USERNAME" yaml:"smtp_username"`
+	SMTPPassword string `envconfig:"SMTP_PASSWORD" yaml:"smtp_password" secret:"true"`
 }
 
 type DBConfig struct {
-	DB_URL string `envconfig:"DB_URL" default:"localhost:27017"`
+	// DB_URL is a MongoDB connection string. A bare host:port, as older
+	// deployments set it, gets the mongodb:// scheme added.
+	DB_URL  string `envconfig:"DB_URL" default:"mongodb://localhost:27017" yaml:"url" validate:"mongodb_uri" secret:"password"`
+	DB_Name string `envconfig:"DB_NAME" default:"Ecommerce" yaml:"name" validate:"required,excludesall=/. $"`
+	// how long startup keeps retrying an unreachable server
+	ConnectTimeout time.Duration `envconfig:"DB_CONNECT_TIMEOUT" default:"1m" yaml:"connect_timeout" validate:"gt=0"`
 }
 
-// GetServiceConfig method to fetch the ServiceConfig
-func GetServiceConfig(ctx context.Context) ServiceConfig {
-	log.Println("Fetching Service configs")
-	config := ServiceConfig{}
-
-	err := envconfig.Process("e-commerce", &config)
-	if err != nil {
-		log.Fatalln(ctx, "Failed fetching service configs")
-		panic(err)
-	}
-	return config
-}
+// DefaultJWTSecret is the default of JWT_SECRET, only fit for development
+const DefaultJWTSecret = "my_secret_value"
 
-// GetDBConfig get db env vars or error
-func GetDBConfig(ctx context.Context) DBConfig {
-	dbConfig := DBConfig{}
-	err := envconfig.Process("e-commerce", &dbConfig)
-	if err != nil {
-		log.Fatalln(ctx, "Failed fetching db configs")
-		panic(err)
-	}
-	return dbConfig
+type AuthConfig struct {
+	JWTSecret       string        `envconfig:"JWT_SECRET" default:"my_secret_value" yaml:"jwt_secret" validate:"min=8" secret:"true"`
+	TokenTTL        time.Duration `envconfig:"TOKEN_TTL" default:"24h" yaml:"token_ttl" validate:"gt=0"`
+	RefreshTokenTTL time.Duration `envconfig:"REFRESH_TOKEN_TTL" default:"168h" yaml:"refresh_token_ttl" validate:"gtfield=TokenTTL"`
+	// BcryptCost is the work factor of password hashes, from 4 to 31
+	BcryptCost int `envconfig:"BCRYPT_COST" default:"14" yaml:"bcrypt_cost" validate:"min=4,max=31"`
 }
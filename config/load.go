@@ -0,0 +1,259 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	toml "github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFileEnv names the settings file when --config is not given
+const ConfigFileEnv = "CONFIG_FILE"
+
+// setting is one leaf of the tree
+type setting struct {
+	value reflect.Value
+	field reflect.StructField
+	// key is the dotted path in the file, e.g. db.url
+	key string
+}
+
+func (s setting) env() string {
+	return s.field.Tag.Get("envconfig")
+}
+
+func (s setting) flag() string {
+	return strings.ReplaceAll(strings.ToLower(s.env()), "_", "-")
+}
+
+// Load reads the configuration and validates it. Each source overrides the
+// one before it:
+//
+//  1. the default tags
+//  2. the file named by --config or CONFIG_FILE, YAML or TOML by its extension
+//  3. environment variables
+//  4. command line flags, parsed from args
+//
+// An unknown key in the file or an unknown flag is an error, so typos don't
+// silently leave a default in place.
+func Load(args []string) (Config, error) {
+
+	var config Config
+	settings := collect(reflect.ValueOf(&config).Elem(), "")
+
+	for _, s := range settings {
+		if def, ok := s.field.Tag.Lookup("default"); ok {
+			if err := set(s, def); err != nil {
+				return config, fmt.Errorf("default of %s: %w", s.env(), err)
+			}
+		}
+	}
+
+	flags := flag.NewFlagSet("e-commerce", flag.ContinueOnError)
+	file := flags.String("config", os.Getenv(ConfigFileEnv), "YAML or TOML settings file")
+	byFlag := make(map[string]setting, len(settings))
+	values := make(map[string]*string, len(settings))
+	for _, s := range settings {
+		byFlag[s.flag()] = s
+		values[s.flag()] = flags.String(s.flag(), "", "sets "+s.env())
+	}
+	if err := flags.Parse(args); err != nil {
+		return config, err
+	}
+
+	if *file != "" {
+		if err := loadFile(*file, settings); err != nil {
+			return config, err
+		}
+	}
+
+	for _, s := range settings {
+		if value, ok := os.LookupEnv(s.env()); ok {
+			if err := set(s, value); err != nil {
+				return config, fmt.Errorf("%s: %w", s.env(), err)
+			}
+		}
+	}
+
+	var flagErr error
+	flags.Visit(func(f *flag.Flag) {
+		s, ok := byFlag[f.Name]
+		if !ok || flagErr != nil {
+			return
+		}
+		if err := set(s, *values[f.Name]); err != nil {
+			flagErr = fmt.Errorf("--%s: %w", f.Name, err)
+		}
+	})
+	if flagErr != nil {
+		return config, flagErr
+	}
+
+	config.DB.DB_URL = mongoURI(config.DB.DB_URL)
+
+	return config, validate(config)
+}
+
+// collect lists the settings under v. Nested structs are sections.
+func collect(v reflect.Value, prefix string) []setting {
+
+	var settings []setting
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		key := prefix + field.Tag.Get("yaml")
+
+		if field.Type.Kind() == reflect.Struct && field.Type != durationType {
+			settings = append(settings, collect(v.Field(i), key+".")...)
+			continue
+		}
+		settings = append(settings, setting{value: v.Field(i), field: field, key: key})
+	}
+	return settings
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// set parses raw into the setting. Lists are comma separated.
+func set(s setting, raw string) error {
+
+	v := s.value
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 0, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(raw, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		items := make([]string, 0)
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// loadFile applies the settings of a YAML or TOML file
+func loadFile(path string, settings []setting) error {
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	tree := map[string]interface{}{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, &tree)
+	case ".toml":
+		err = toml.Unmarshal(content, &tree)
+	default:
+		return fmt.Errorf("config file %s must end in .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	byKey := make(map[string]setting, len(settings))
+	for _, s := range settings {
+		byKey[s.key] = s
+	}
+
+	values := map[string]interface{}{}
+	flatten(tree, "", values)
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		s, ok := byKey[key]
+		if !ok {
+			return fmt.Errorf("config file %s: unknown setting %s", path, key)
+		}
+		if err := setFromFile(s, values[key]); err != nil {
+			return fmt.Errorf("config file %s: %s: %w", path, key, err)
+		}
+	}
+	return nil
+}
+
+// flatten turns nested sections into dotted keys
+func flatten(tree map[string]interface{}, prefix string, values map[string]interface{}) {
+	for key, value := range tree {
+		if section, ok := value.(map[string]interface{}); ok {
+			flatten(section, prefix+key+".", values)
+			continue
+		}
+		values[prefix+key] = value
+	}
+}
+
+func setFromFile(s setting, value interface{}) error {
+
+	list, isList := value.([]interface{})
+	if isList != (s.value.Kind() == reflect.Slice) {
+		if isList {
+			return errors.New("must be a single value")
+		}
+		return errors.New("must be a list")
+	}
+
+	if isList {
+		items := make([]string, len(list))
+		for i, item := range list {
+			items[i] = fmt.Sprint(item)
+		}
+		s.value.Set(reflect.ValueOf(items))
+		return nil
+	}
+
+	return set(s, fmt.Sprint(value))
+}
+
+// mongoURI adds the scheme to a bare host:port
+func mongoURI(uri string) string {
+	if uri == "" || strings.Contains(uri, "://") {
+		return uri
+	}
+	return "mongodb://" + uri
+}
@@ -0,0 +1,103 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/connstring"
+
+	"github.com/mayuka-c/e-commerce/apperror"
+)
+
+// Redacted replaces secrets in Redacted
+const Redacted = "[REDACTED]"
+
+// validate checks every setting against its validate tag and reports all
+// failures at once, by environment variable name
+func validate(config Config) error {
+
+	v := validator.New()
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		if env := field.Tag.Get("envconfig"); env != "" {
+			return env
+		}
+		return field.Name
+	})
+	if err := v.RegisterValidation("mongodb_uri", func(fl validator.FieldLevel) bool {
+		_, err := connstring.ParseAndValidate(fl.Field().String())
+		return err == nil
+	}); err != nil {
+		return err
+	}
+
+	err := v.Struct(config)
+
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return err
+	}
+
+	problems := make([]string, len(validationErrs))
+	for i, fieldErr := range validationErrs {
+		problems[i] = message(fieldErr)
+	}
+	return fmt.Errorf("invalid configuration: %s", strings.Join(problems, "; "))
+}
+
+func message(fieldErr validator.FieldError) string {
+	switch fieldErr.Tag() {
+	case "mongodb_uri":
+		return fieldErr.Field() + " must be a mongodb:// or mongodb+srv:// connection string"
+	case "gtfield":
+		return fmt.Sprintf("%s must be longer than %s", fieldErr.Field(), envName(fieldErr))
+	case "required_if":
+		return fmt.Sprintf("%s is required when %s", fieldErr.Field(), fieldErr.Param())
+	case "hostname_port":
+		return fieldErr.Field() + " must be a host:port address"
+	}
+	return apperror.Validation(validator.ValidationErrors{fieldErr}).Fields[0].Message
+}
+
+// envName returns the environment variable of the field a gtfield rule
+// compares with, which the error only knows by its Go name
+func envName(fieldErr validator.FieldError) string {
+	for _, s := range collect(reflect.ValueOf(&Config{}).Elem(), "") {
+		if s.field.Name == fieldErr.Param() {
+			return s.env()
+		}
+	}
+	return fieldErr.Param()
+}
+
+var uriPassword = regexp.MustCompile(`(://[^:/@]*:)[^@]*@`)
+
+// Redacted returns the settings as the file would hold them, with secrets
+// replaced by Redacted and durations written out, e.g. 1m30s
+func (c Config) Redacted() map[string]interface{} {
+
+	view := map[string]interface{}{}
+	for _, s := range collect(reflect.ValueOf(&c).Elem(), "") {
+		section, key, _ := strings.Cut(s.key, ".")
+		if view[section] == nil {
+			view[section] = map[string]interface{}{}
+		}
+
+		var value interface{} = s.value.Interface()
+		switch secret := s.field.Tag.Get("secret"); {
+		case secret == "true" && !s.value.IsZero():
+			value = Redacted
+		case secret == "password":
+			value = uriPassword.ReplaceAllString(s.value.String(), "${1}"+Redacted+"@")
+		case s.value.Type() == durationType:
+			value = time.Duration(s.value.Int()).String()
+		}
+
+		view[section].(map[string]interface{})[key] = value
+	}
+	return view
+}
@@ -1,6 +1,18 @@
 package constants
 
 const (
-	UserCollectionName    = "Users"
-	ProductCollectionName = "Products"
+	UserCollectionName            = "Users"
+	ProductCollectionName         = "Products"
+	ShippingMethodCollectionName  = "ShippingMethods"
+	ShipmentCollectionName        = "Shipments"
+	CategoryCollectionName        = "Categories"
+	ReviewCollectionName          = "Reviews"
+	WishlistCollectionName        = "Wishlists"
+	GuestCartCollectionName       = "GuestCarts"
+	CartReminderCollectionName    = "CartReminders"
+	JobCollectionName             = "Jobs"
+	RecurringJobCollectionName    = "RecurringJobs"
+	OutboxCollectionName          = "Outbox"
+	WebhookCollectionName         = "Webhooks"
+	WebhookDeliveryCollectionName = "WebhookDeliveries"
 )
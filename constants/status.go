@@ -0,0 +1,62 @@
+package constants
+
+// Shipment statuses, in the order a parcel normally moves through them
+const (
+	ShipmentLabelCreated   = "label_created"
+	ShipmentInTransit      = "in_transit"
+	ShipmentOutForDelivery = "out_for_delivery"
+	ShipmentDelivered      = "delivered"
+	ShipmentException      = "exception"
+)
+
+// User roles. Users created before roles existed have none and count as
+// customers.
+const (
+	RoleCustomer = "customer"
+	RoleAdmin    = "admin"
+)
+
+// Review moderation statuses
+const (
+	ReviewPending  = "pending"
+	ReviewApproved = "approved"
+	ReviewRejected = "rejected"
+)
+
+// Wishlist kinds
+const (
+	WishlistKind      = "wishlist"
+	SavedForLaterKind = "saved_for_later"
+)
+
+// Cart issue kinds, reported when a cart line no longer matches the catalog
+const (
+	CartPriceChanged   = "price_changed"
+	CartProductRemoved = "product_removed"
+	CartOutOfStock     = "out_of_stock"
+)
+
+// Job statuses. Failed jobs wait for a retry; dead jobs used up their attempts.
+const (
+	JobPending   = "pending"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+	JobDead      = "dead"
+)
+
+// Order statuses. Orders placed before statuses existed have none and count
+// as placed.
+const (
+	OrderPlaced    = "placed"
+	OrderCancelled = "cancelled"
+)
+
+// Webhook delivery statuses. Dead deliveries used up their retries and wait
+// for a manual redelivery.
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+	DeliveryDead      = "dead"
+)
@@ -0,0 +1,130 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mayuka-c/e-commerce/database"
+	"github.com/mayuka-c/e-commerce/dto"
+	"github.com/mayuka-c/e-commerce/models"
+	"github.com/mayuka-c/e-commerce/notifications"
+)
+
+// ForgotPassword emails a password reset link. It answers the same whether or
+// not the email belongs to an account, so it can't be used to find accounts.
+func (app *Application) ForgotPassword() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), app.requestTimeout)
+		defer cancel()
+
+		var request dto.ForgotPassword
+		if !bind(c, &request) {
+			return
+		}
+
+		msg := gin.H{"msg": "If the email belongs to an account, a reset link is on its way"}
+
+		user, err := app.dbClient.GetUserByEmail(ctx, request.Email)
+		if err == database.ErrCantFindUser {
+			c.IndentedJSON(http.StatusOK, msg)
+			return
+		}
+		if err != nil {
+			abort(c, err)
+			return
+		}
+
+		err = app.emails.SendPasswordReset(ctx, user)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, msg)
+	}
+}
+
+// ResetPassword sets a new password with the token from the reset email
+func (app *Application) ResetPassword() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), app.requestTimeout)
+		defer cancel()
+
+		var request dto.ResetPassword
+		if !bind(c, &request) {
+			return
+		}
+
+		err := app.dbClient.ResetPassword(ctx, request.Token, app.hashPassword(request.Password))
+		if err != nil {
+			abort(c, err)
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, gin.H{"msg": "Password was reset, log in with the new password"})
+	}
+}
+
+func (app *Application) GetNotificationPrefs() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user_id, ok := currentUser(c)
+		if !ok {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), app.requestTimeout)
+		defer cancel()
+
+		user, err := app.dbClient.GetUser(ctx, user_id)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+
+		prefs := models.NotificationPrefs{Locale: user.Locale, Notifications_Off: user.Notifications_Off}
+		if prefs.Notifications_Off == nil {
+			prefs.Notifications_Off = make([]string, 0)
+		}
+		// users who unsubscribed before the list was kept in step only have the flag
+		if user.Cart_Reminders_Off {
+			listed := false
+			for _, kind := range prefs.Notifications_Off {
+				listed = listed || kind == notifications.CartReminderKind
+			}
+			if !listed {
+				prefs.Notifications_Off = append(prefs.Notifications_Off, notifications.CartReminderKind)
+			}
+		}
+
+		c.IndentedJSON(http.StatusOK, prefs)
+	}
+}
+
+// UpdateNotificationPrefs replaces the user's locale and the list of emails
+// they opted out of
+func (app *Application) UpdateNotificationPrefs() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user_id, ok := currentUser(c)
+		if !ok {
+			return
+		}
+
+		var prefs dto.NotificationPrefs
+		if !bind(c, &prefs) {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), app.requestTimeout)
+		defer cancel()
+
+		err := app.dbClient.UpdateNotificationPrefs(ctx, user_id, prefs.Model())
+		if err != nil {
+			abort(c, err)
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, gin.H{"msg": "Notification preferences updated"})
+	}
+}
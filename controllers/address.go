@@ -3,53 +3,30 @@ package controllers
 import (
 	"context"
 	"net/http"
-	"time"
-
-	"go.mongodb.org/mongo-driver/bson/primitive"
 
 	"github.com/gin-gonic/gin"
-	log "github.com/sirupsen/logrus"
 
-	"github.com/mayuka-c/e-commerce/database"
-	"github.com/mayuka-c/e-commerce/models"
+	"github.com/mayuka-c/e-commerce/dto"
 )
 
 func (app *Application) AddAddress() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		userId := c.Query("id")
-		if userId == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "user id not passed in query"})
-			c.Abort()
+		user_id, ok := currentUser(c)
+		if !ok {
 			return
 		}
 
-		var address models.Address
-		address.Address_ID = primitive.NewObjectID()
-
-		if err := c.BindJSON(&address); err != nil {
-			log.Error(err)
-			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		var address dto.Address
+		if !bind(c, &address) {
 			return
 		}
 
-		user_id, err := primitive.ObjectIDFromHex(userId)
-		if err != nil {
-			log.Error(err)
-			c.IndentedJSON(http.StatusExpectationFailed, gin.H{"error": "userID provided is invalid"})
-			return
-		}
-
-		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), app.requestTimeout)
 		defer cancel()
 
-		err = app.dbClient.AddAddress(ctx, user_id, address)
+		err := app.dbClient.AddAddress(ctx, user_id, address.Model())
 		if err != nil {
-			log.Error(err)
-			if err == database.ErrAddAddress {
-				c.IndentedJSON(http.StatusNotAcceptable, gin.H{"msg": database.ErrAddAddress.Error()})
-			} else {
-				c.AbortWithStatus(http.StatusInternalServerError)
-			}
+			abort(c, err)
 			return
 		}
 
@@ -59,33 +36,22 @@ func (app *Application) AddAddress() gin.HandlerFunc {
 
 func (app *Application) EditHomeAddress() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		userId := c.Query("id")
-		if userId == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "user id not passed in query"})
-			return
-		}
-
-		var editaddress models.Address
-		if err := c.BindJSON(&editaddress); err != nil {
-			log.Error(err)
-			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		user_id, ok := currentUser(c)
+		if !ok {
 			return
 		}
 
-		user_id, err := primitive.ObjectIDFromHex(userId)
-		if err != nil {
-			log.Error(err)
-			c.IndentedJSON(http.StatusExpectationFailed, gin.H{"error": "userID provided is invalid"})
+		var editaddress dto.Address
+		if !bind(c, &editaddress) {
 			return
 		}
 
-		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), app.requestTimeout)
 		defer cancel()
 
-		app.dbClient.EditHomeAddress(ctx, user_id, editaddress)
+		err := app.dbClient.EditHomeAddress(ctx, user_id, editaddress.Model())
 		if err != nil {
-			log.Error(err)
-			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+			abort(c, err)
 			return
 		}
 
@@ -95,33 +61,22 @@ func (app *Application) EditHomeAddress() gin.HandlerFunc {
 
 func (app *Application) EditWorkAddress() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		userId := c.Query("id")
-		if userId == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "user id not passed in query"})
+		user_id, ok := currentUser(c)
+		if !ok {
 			return
 		}
 
-		var editaddress models.Address
-		if err := c.BindJSON(&editaddress); err != nil {
-			log.Error(err)
-			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-			return
-		}
-
-		user_id, err := primitive.ObjectIDFromHex(userId)
-		if err != nil {
-			log.Error(err)
-			c.IndentedJSON(http.StatusExpectationFailed, gin.H{"error": "userID provided is invalid"})
+		var editaddress dto.Address
+		if !bind(c, &editaddress) {
 			return
 		}
 
-		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), app.requestTimeout)
 		defer cancel()
 
-		app.dbClient.EditWorkAddress(ctx, user_id, editaddress)
+		err := app.dbClient.EditWorkAddress(ctx, user_id, editaddress.Model())
 		if err != nil {
-			log.Println(err)
-			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+			abort(c, err)
 			return
 		}
 
@@ -131,26 +86,17 @@ func (app *Application) EditWorkAddress() gin.HandlerFunc {
 
 func (app *Application) DeleteAddress() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		userId := c.Query("id")
-		if userId == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "user id not passed in query"})
-			return
-		}
-
-		user_id, err := primitive.ObjectIDFromHex(userId)
-		if err != nil {
-			log.Error(err)
-			c.IndentedJSON(http.StatusExpectationFailed, gin.H{"error": "userID provided is invalid"})
+		user_id, ok := currentUser(c)
+		if !ok {
 			return
 		}
 
-		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), app.requestTimeout)
 		defer cancel()
 
-		app.dbClient.DeleteAddress(ctx, user_id)
+		err := app.dbClient.DeleteAddress(ctx, user_id)
 		if err != nil {
-			log.Error(err)
-			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+			abort(c, err)
 			return
 		}
 
@@ -3,51 +3,54 @@ package controllers
 import (
 	"context"
 	"net/http"
-	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 
 	"github.com/gin-gonic/gin"
-	log "github.com/sirupsen/logrus"
+
+	"github.com/mayuka-c/e-commerce/apperror"
+	"github.com/mayuka-c/e-commerce/database"
 )
 
+// variantQueryID reads the optional variantID query parameter
+func variantQueryID(c *gin.Context) (*primitive.ObjectID, error) {
+	variantQueryID := c.Query("variantID")
+	if variantQueryID == "" {
+		return nil, nil
+	}
+
+	variant_id, err := primitive.ObjectIDFromHex(variantQueryID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &variant_id, nil
+}
+
 func (app *Application) AddToCart() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		productQueryID := c.Query("id")
-		if productQueryID == "" {
-			log.Error("Product ID is empty")
-			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "product id is empty"})
+		user_id, ok := currentUser(c)
+		if !ok {
 			return
 		}
 
-		userQueryID := c.Query("userID")
-		if userQueryID == "" {
-			log.Error("User ID is empty")
-			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "user id is empty"})
+		product_id, ok := requiredObjectID(c, "id")
+		if !ok {
 			return
 		}
 
-		product_id, err := primitive.ObjectIDFromHex(productQueryID)
+		variant_id, err := variantQueryID(c)
 		if err != nil {
-			log.Error(err)
-			c.IndentedJSON(http.StatusExpectationFailed, gin.H{"error": "productID provided is invalid"})
+			abort(c, apperror.InvalidID("variantID"))
 			return
 		}
 
-		user_id, err := primitive.ObjectIDFromHex(userQueryID)
-		if err != nil {
-			log.Error(err)
-			c.IndentedJSON(http.StatusExpectationFailed, gin.H{"error": "userID provided is invalid"})
-			return
-		}
-
-		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		ctx, cancel := context.WithTimeout(c.Request.Context(), app.requestTimeout)
 		defer cancel()
 
-		err = app.dbClient.AddProductToCart(ctx, product_id, user_id)
+		err = app.dbClient.AddProductToCart(ctx, product_id, variant_id, user_id)
 		if err != nil {
-			log.Error(err)
-			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+			abort(c, err)
 			return
 		}
 
@@ -57,40 +60,28 @@ func (app *Application) AddToCart() gin.HandlerFunc {
 
 func (app *Application) RemoveItemFromCart() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		productQueryID := c.Query("id")
-		if productQueryID == "" {
-			log.Error("Product ID is empty")
-			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "product id is empty"})
-			return
-		}
-
-		userQueryID := c.Query("userID")
-		if userQueryID == "" {
-			log.Error("User ID is empty")
-			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "user id is empty"})
+		user_id, ok := currentUser(c)
+		if !ok {
 			return
 		}
 
-		product_id, err := primitive.ObjectIDFromHex(productQueryID)
-		if err != nil {
-			log.Error(err)
-			c.IndentedJSON(http.StatusExpectationFailed, gin.H{"error": "productID provided is invalid"})
+		product_id, ok := requiredObjectID(c, "id")
+		if !ok {
 			return
 		}
 
-		user_id, err := primitive.ObjectIDFromHex(userQueryID)
+		variant_id, err := variantQueryID(c)
 		if err != nil {
-			log.Error(err)
-			c.IndentedJSON(http.StatusExpectationFailed, gin.H{"error": "userID provided is invalid"})
+			abort(c, apperror.InvalidID("variantID"))
 			return
 		}
 
-		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		ctx, cancel := context.WithTimeout(c.Request.Context(), app.requestTimeout)
 		defer cancel()
 
-		err = app.dbClient.RemoveCartItem(ctx, product_id, user_id)
+		err = app.dbClient.RemoveCartItem(ctx, product_id, variant_id, user_id)
 		if err != nil {
-			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+			abort(c, err)
 			return
 		}
 
@@ -100,101 +91,76 @@ func (app *Application) RemoveItemFromCart() gin.HandlerFunc {
 
 func (app *Application) GetItemFromCart() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		userQueryID := c.Query("userID")
-		if userQueryID == "" {
-			log.Error("User ID is empty")
-			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "user id is empty"})
+		user_id, ok := currentUser(c)
+		if !ok {
 			return
 		}
 
-		user_id, err := primitive.ObjectIDFromHex(userQueryID)
-		if err != nil {
-			log.Error(err)
-			c.IndentedJSON(http.StatusExpectationFailed, gin.H{"error": "userID provided is invalid"})
-			return
-		}
-
-		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		ctx, cancel := context.WithTimeout(c.Request.Context(), app.requestTimeout)
 		defer cancel()
 
-		result, totalPrice, err := app.dbClient.GetItemFromCart(ctx, user_id)
+		result, totalPrice, issues, err := app.dbClient.GetItemFromCart(ctx, user_id)
 		if err != nil {
-			log.Error(err)
-			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+			abort(c, err)
 			return
 		}
 
-		c.IndentedJSON(http.StatusOK, gin.H{"result": result.UserCart, "totalPrice": totalPrice})
+		c.IndentedJSON(http.StatusOK, gin.H{"result": result.UserCart, "totalPrice": totalPrice, "issues": issues, "version": database.CartVersion(result.UserCart)})
 	}
 }
 
 func (app *Application) BuyFromCart() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		userQueryID := c.Query("userID")
-		if userQueryID == "" {
-			log.Error("User ID is empty")
-			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "user id is empty"})
+		user_id, ok := currentUser(c)
+		if !ok {
 			return
 		}
 
-		user_id, err := primitive.ObjectIDFromHex(userQueryID)
-		if err != nil {
-			log.Error(err)
-			c.IndentedJSON(http.StatusExpectationFailed, gin.H{"error": "userID provided is invalid"})
-			return
-		}
+		// the client sends the version of the cart the user reviewed, from
+		// the cart view or from the previous attempt
+		version := c.Query("version")
 
-		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		ctx, cancel := context.WithTimeout(c.Request.Context(), app.requestTimeout)
 		defer cancel()
 
-		err = app.dbClient.BuyItemFromCart(ctx, user_id)
+		issues, version, err := app.dbClient.BuyItemFromCart(ctx, user_id, version)
 		if err != nil {
-			log.Error(err)
-			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+			if err == database.ErrCartChanged {
+				abort(c, apperror.From(err).With("issues", issues).With("version", version))
+				return
+			}
+			abort(c, err)
 			return
 		}
 
-		c.IndentedJSON(http.StatusOK, gin.H{"msg": "Successfully placed the order!"})
+		c.IndentedJSON(http.StatusOK, gin.H{"msg": "Successfully placed the order!", "issues": issues})
 	}
 }
 
 func (app *Application) InstantBuy() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		productQueryID := c.Query("id")
-		if productQueryID == "" {
-			log.Error("Product ID is empty")
-			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "product id is empty"})
-			return
-		}
-
-		userQueryID := c.Query("userID")
-		if userQueryID == "" {
-			log.Error("User ID is empty")
-			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "user id is empty"})
+		user_id, ok := currentUser(c)
+		if !ok {
 			return
 		}
 
-		product_id, err := primitive.ObjectIDFromHex(productQueryID)
-		if err != nil {
-			log.Error(err)
-			c.IndentedJSON(http.StatusExpectationFailed, gin.H{"error": "productID provided is invalid"})
+		product_id, ok := requiredObjectID(c, "id")
+		if !ok {
 			return
 		}
 
-		user_id, err := primitive.ObjectIDFromHex(userQueryID)
+		variant_id, err := variantQueryID(c)
 		if err != nil {
-			log.Error(err)
-			c.IndentedJSON(http.StatusExpectationFailed, gin.H{"error": "userID provided is invalid"})
+			abort(c, apperror.InvalidID("variantID"))
 			return
 		}
 
-		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		ctx, cancel := context.WithTimeout(c.Request.Context(), app.requestTimeout)
 		defer cancel()
 
-		err = app.dbClient.InstantBuyer(ctx, product_id, user_id)
+		err = app.dbClient.InstantBuyer(ctx, product_id, variant_id, user_id)
 		if err != nil {
-			log.Error(err)
-			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+			abort(c, err)
 			return
 		}
 
@@ -0,0 +1,220 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mayuka-c/e-commerce/apperror"
+	"github.com/mayuka-c/e-commerce/dto"
+	"github.com/mayuka-c/e-commerce/models"
+	"github.com/mayuka-c/e-commerce/search"
+)
+
+type categoryNode struct {
+	models.Category
+	Children []*categoryNode `json:"children"`
+}
+
+func (app *Application) AddCategory() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request dto.Category
+		if !bind(c, &request) {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), app.requestTimeout)
+		defer cancel()
+
+		category, err := app.dbClient.AddCategory(ctx, request.Model())
+		if err != nil {
+			abort(c, err)
+			return
+		}
+
+		app.searchIndex.Upsert(categoryEntry(category))
+
+		c.IndentedJSON(http.StatusCreated, category)
+	}
+}
+
+// ListCategories returns the category tree, roots first and children sorted by name
+func (app *Application) ListCategories() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), app.requestTimeout)
+		defer cancel()
+
+		categories, err := app.dbClient.GetCategories(ctx)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, buildCategoryTree(categories))
+	}
+}
+
+func (app *Application) EditCategory() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		categoryQueryID := c.Query("id")
+		if categoryQueryID == "" {
+			abort(c, apperror.Missing("id"))
+			return
+		}
+
+		category_id, err := primitive.ObjectIDFromHex(categoryQueryID)
+		if err != nil {
+			abort(c, apperror.InvalidID("categoryID"))
+			return
+		}
+
+		var request dto.Category
+		if !bind(c, &request) {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), app.requestTimeout)
+		defer cancel()
+
+		category, err := app.dbClient.UpdateCategory(ctx, category_id, request.Model())
+		if err != nil {
+			abort(c, err)
+			return
+		}
+
+		app.searchIndex.Upsert(categoryEntry(category))
+
+		c.IndentedJSON(http.StatusOK, category)
+	}
+}
+
+func (app *Application) DeleteCategory() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		categoryQueryID := c.Query("id")
+		if categoryQueryID == "" {
+			abort(c, apperror.Missing("id"))
+			return
+		}
+
+		category_id, err := primitive.ObjectIDFromHex(categoryQueryID)
+		if err != nil {
+			abort(c, apperror.InvalidID("categoryID"))
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), app.requestTimeout)
+		defer cancel()
+
+		err = app.dbClient.DeleteCategory(ctx, category_id)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+
+		app.searchIndex.Remove(search.CategoryKind, category_id.Hex())
+
+		c.IndentedJSON(http.StatusOK, gin.H{"msg": "Successfully deleted the category!"})
+	}
+}
+
+func (app *Application) AssignCategories() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		productQueryID := c.Query("id")
+		if productQueryID == "" {
+			abort(c, apperror.Missing("id"))
+			return
+		}
+
+		product_id, err := primitive.ObjectIDFromHex(productQueryID)
+		if err != nil {
+			abort(c, apperror.InvalidID("productID"))
+			return
+		}
+
+		var assignment dto.CategoryAssignment
+		if !bind(c, &assignment) {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), app.requestTimeout)
+		defer cancel()
+
+		err = app.dbClient.AssignProductCategories(ctx, product_id, assignment.Category_IDs)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, gin.H{"msg": "Successfully assigned the categories!"})
+	}
+}
+
+func (app *Application) UnassignCategory() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		productQueryID := c.Query("id")
+		categoryQueryID := c.Query("categoryID")
+		if productQueryID == "" || categoryQueryID == "" {
+			abort(c, apperror.New(http.StatusBadRequest, apperror.CodeMissingParameter, "id and categoryID are required"))
+			return
+		}
+
+		product_id, err := primitive.ObjectIDFromHex(productQueryID)
+		if err != nil {
+			abort(c, apperror.InvalidID("productID"))
+			return
+		}
+
+		category_id, err := primitive.ObjectIDFromHex(categoryQueryID)
+		if err != nil {
+			abort(c, apperror.InvalidID("categoryID"))
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), app.requestTimeout)
+		defer cancel()
+
+		err = app.dbClient.UnassignProductCategory(ctx, product_id, category_id)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, gin.H{"msg": "Successfully removed the category!"})
+	}
+}
+
+func buildCategoryTree(categories []models.Category) []*categoryNode {
+
+	nodes := make(map[primitive.ObjectID]*categoryNode, len(categories))
+	for _, category := range categories {
+		nodes[category.Category_ID] = &categoryNode{Category: category, Children: make([]*categoryNode, 0)}
+	}
+
+	roots := make([]*categoryNode, 0)
+	for _, category := range categories {
+		node := nodes[category.Category_ID]
+		if category.Parent_ID != nil {
+			if parent, ok := nodes[*category.Parent_ID]; ok {
+				parent.Children = append(parent.Children, node)
+				continue
+			}
+		}
+		roots = append(roots, node)
+	}
+
+	sortCategoryNodes(roots)
+	return roots
+}
+
+func sortCategoryNodes(nodes []*categoryNode) {
+	sort.Slice(nodes, func(i, j int) bool {
+		return *nodes[i].Name < *nodes[j].Name
+	})
+	for _, node := range nodes {
+		sortCategoryNodes(node.Children)
+	}
+}
@@ -0,0 +1,15 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetConfig shows the settings the service runs with, with secrets redacted.
+// The rest still maps out the deployment, so the route is admin only.
+func (app *Application) GetConfig() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.IndentedJSON(http.StatusOK, app.config.Redacted())
+	}
+}
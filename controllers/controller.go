@@ -1,18 +1,95 @@
 package controllers
 
 import (
+	"net/http"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mayuka-c/e-commerce/apperror"
+	"github.com/mayuka-c/e-commerce/config"
 	"github.com/mayuka-c/e-commerce/database"
+	"github.com/mayuka-c/e-commerce/dto"
+	"github.com/mayuka-c/e-commerce/notifications"
+	"github.com/mayuka-c/e-commerce/search"
+	"github.com/mayuka-c/e-commerce/shipping"
 	"github.com/mayuka-c/e-commerce/tokens"
 )
 
 type Application struct {
 	dbClient    *database.DBClient
 	tokenClient *tokens.TokenGenrator
+	carriers    shipping.Carriers
+	searchIndex *search.Index
+	emails      *notifications.Emails
+	config      config.Config
+
+	requestTimeout time.Duration
+	bcryptCost     int
+	// secureCookies keeps cookies off plain HTTP when the service is
+	// served over HTTPS
+	secureCookies bool
 }
 
-func NewApplication(dbClient *database.DBClient, tokenClient *tokens.TokenGenrator) *Application {
+func NewApplication(dbClient *database.DBClient, tokenClient *tokens.TokenGenrator, carriers shipping.Carriers, emails *notifications.Emails, settings config.Config) *Application {
 	return &Application{
-		dbClient:    dbClient,
-		tokenClient: tokenClient,
+		dbClient:       dbClient,
+		tokenClient:    tokenClient,
+		carriers:       carriers,
+		searchIndex:    search.NewIndex(),
+		emails:         emails,
+		config:         settings,
+		requestTimeout: settings.Service.RequestTimeout,
+		bcryptCost:     settings.Auth.BcryptCost,
+		secureCookies:  strings.HasPrefix(settings.Service.PublicURL, "https://"),
+	}
+}
+
+// requiredObjectID reads an object id from the query and aborts with the
+// error when it is missing or malformed
+func requiredObjectID(c *gin.Context, key string) (primitive.ObjectID, bool) {
+	value := c.Query(key)
+	if value == "" {
+		abort(c, apperror.Missing(key))
+		return primitive.NilObjectID, false
+	}
+
+	id, err := primitive.ObjectIDFromHex(value)
+	if err != nil {
+		abort(c, apperror.InvalidID(key))
+		return primitive.NilObjectID, false
+	}
+
+	return id, true
+}
+
+// currentUser is the id of the user the token was issued to. Handlers acting
+// on the caller's own data use it instead of a userID the client could set.
+func currentUser(c *gin.Context) (primitive.ObjectID, bool) {
+	id, err := primitive.ObjectIDFromHex(c.GetString("uuid"))
+	if err != nil {
+		abort(c, apperror.New(http.StatusUnauthorized, apperror.CodeUnauthorized, "token does not belong to a user"))
+		return primitive.NilObjectID, false
+	}
+
+	return id, true
+}
+
+// bind decodes the JSON body into request, a pointer to a dto type, and
+// validates it. It aborts with the error when either fails.
+func bind(c *gin.Context, request interface{}) bool {
+	if err := c.ShouldBindJSON(request); err != nil {
+		abort(c, apperror.BadRequest(err))
+		return false
 	}
+
+	if err := dto.Validate.Struct(request); err != nil {
+		abort(c, apperror.BadRequest(err))
+		return false
+	}
+
+	return true
 }
@@ -0,0 +1,84 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mayuka-c/e-commerce/apperror"
+	"github.com/mayuka-c/e-commerce/database"
+	"github.com/mayuka-c/e-commerce/shipping"
+)
+
+// errorTypes are the status and code each domain error is reported with.
+// Anything not listed here is an internal error.
+var errorTypes = []struct {
+	err    error
+	status int
+	code   string
+}{
+	{database.ErrCantFindUser, http.StatusNotFound, "user_not_found"},
+	{database.ErrCantFindProduct, http.StatusNotFound, "product_not_found"},
+	{database.ErrCantFindVariant, http.StatusNotFound, "variant_not_found"},
+	{database.ErrCantFindCategory, http.StatusNotFound, "category_not_found"},
+	{database.ErrCantFindReview, http.StatusNotFound, "review_not_found"},
+	{database.ErrCantFindWishlist, http.StatusNotFound, "wishlist_not_found"},
+	{database.ErrCantFindWishlistItem, http.StatusNotFound, "wishlist_item_not_found"},
+	{database.ErrCantFindCartItem, http.StatusNotFound, "cart_item_not_found"},
+	{database.ErrCantFindGuestCart, http.StatusNotFound, "guest_cart_not_found"},
+	{database.ErrCantFindOrder, http.StatusNotFound, "order_not_found"},
+	{database.ErrCantFindShippingMethod, http.StatusNotFound, "shipping_method_not_found"},
+	{database.ErrCantFindShipment, http.StatusNotFound, "shipment_not_found"},
+	{database.ErrCantFindJob, http.StatusNotFound, "job_not_found"},
+	{database.ErrCantFindRecurring, http.StatusNotFound, "recurring_job_not_found"},
+	{database.ErrCantFindWebhook, http.StatusNotFound, "webhook_not_found"},
+	{database.ErrCantFindDelivery, http.StatusNotFound, "webhook_delivery_not_found"},
+	{database.ErrInvalidUnsubscribe, http.StatusNotFound, "invalid_unsubscribe_link"},
+	{shipping.ErrUnknownParcel, http.StatusNotFound, "parcel_not_found"},
+
+	{database.ErrNotVerifiedPurchaser, http.StatusForbidden, "not_verified_purchaser"},
+
+	{database.ErrCategorySlugTaken, http.StatusConflict, "category_slug_taken"},
+	{database.ErrCategoryHasChildren, http.StatusConflict, "category_has_children"},
+	{database.ErrAlreadyReviewed, http.StatusConflict, "already_reviewed"},
+	{database.ErrCantVoteReview, http.StatusConflict, "cannot_vote_review"},
+	{database.ErrWishlistNameTaken, http.StatusConflict, "wishlist_name_taken"},
+	{database.ErrOutOfStock, http.StatusConflict, "out_of_stock"},
+	{database.ErrSKUTaken, http.StatusConflict, "sku_taken"},
+	{database.ErrCartChanged, http.StatusConflict, "cart_changed"},
+	{database.ErrEmptyCart, http.StatusConflict, "empty_cart"},
+	{database.ErrOrderCancelled, http.StatusConflict, "order_cancelled"},
+	{database.ErrOrderShipped, http.StatusConflict, "order_shipped"},
+	{database.ErrAddAddress, http.StatusConflict, "address_limit_reached"},
+	{database.ErrJobNotRetryable, http.StatusConflict, "job_not_retryable"},
+	{database.ErrDeliveryInProgress, http.StatusConflict, "webhook_delivery_in_progress"},
+
+	{database.ErrCategoryCycle, http.StatusBadRequest, "category_cycle"},
+	{database.ErrInvalidSlug, http.StatusBadRequest, "invalid_slug"},
+	{database.ErrInvalidReviewSort, http.StatusBadRequest, "invalid_sort"},
+	{database.ErrInvalidSort, http.StatusBadRequest, "invalid_sort"},
+	{database.ErrInvalidPage, http.StatusBadRequest, "invalid_page"},
+	{database.ErrInvalidSearchMode, http.StatusBadRequest, "invalid_search_mode"},
+	{database.ErrEmptySearch, http.StatusBadRequest, "empty_search"},
+	{database.ErrCantDeleteSavedList, http.StatusBadRequest, "cannot_delete_saved_list"},
+	{database.ErrVariantRequired, http.StatusBadRequest, "variant_required"},
+	{database.ErrDuplicateOptions, http.StatusBadRequest, "duplicate_options"},
+	{database.ErrInvalidResetToken, http.StatusBadRequest, "invalid_reset_token"},
+	{database.ErrInvalidJobStatus, http.StatusBadRequest, "invalid_status"},
+	{database.ErrInvalidDeliveryStatus, http.StatusBadRequest, "invalid_status"},
+	{database.ErrUnknownWebhookEventType, http.StatusBadRequest, "unknown_event_type"},
+	{shipping.ErrNoZone, http.StatusBadRequest, "no_shipping_zone"},
+	{shipping.ErrNoRate, http.StatusBadRequest, "no_shipping_rate"},
+}
+
+func init() {
+	for _, t := range errorTypes {
+		apperror.Register(t.err, t.status, t.code)
+	}
+}
+
+// abort hands the error to the error middleware, which writes the response
+func abort(c *gin.Context, err error) {
+	_ = c.Error(err)
+	c.Abort()
+}
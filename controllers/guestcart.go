@@ -0,0 +1,159 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mayuka-c/e-commerce/apperror"
+	"github.com/mayuka-c/e-commerce/database"
+	"github.com/mayuka-c/e-commerce/logging"
+	"github.com/mayuka-c/e-commerce/models"
+)
+
+// The guest cart token travels in a cookie for browsers and in a header for
+// other clients; responses always carry both.
+const (
+	guestCartCookie = "cart_token"
+	guestCartHeader = "X-Cart-Token"
+)
+
+func guestCartToken(c *gin.Context) string {
+	if token := c.GetHeader(guestCartHeader); token != "" {
+		return token
+	}
+	token, err := c.Cookie(guestCartCookie)
+	if err != nil {
+		return ""
+	}
+	return token
+}
+
+func (app *Application) setGuestCartToken(c *gin.Context, token string) {
+	c.Header(guestCartHeader, token)
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(guestCartCookie, token, int(database.GuestCartTTL.Seconds()), "/", "", app.secureCookies, true)
+}
+
+func (app *Application) clearGuestCartToken(c *gin.Context) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(guestCartCookie, "", -1, "/", "", app.secureCookies, true)
+}
+
+func guestCartTotal(cart models.GuestCart) int {
+	total := 0
+	for _, item := range cart.Items {
+		total += item.Price
+	}
+	return total
+}
+
+func (app *Application) GuestAddToCart() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		product_id, ok := requiredObjectID(c, "id")
+		if !ok {
+			return
+		}
+
+		variant_id, err := variantQueryID(c)
+		if err != nil {
+			abort(c, apperror.InvalidID("variantID"))
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), app.requestTimeout)
+		defer cancel()
+
+		token := guestCartToken(c)
+		cart, err := app.dbClient.AddProductToGuestCart(ctx, token, product_id, variant_id)
+		if err == database.ErrCantFindGuestCart {
+			// first item, or the old cart expired: start a new one
+			token, _, err = app.dbClient.CreateGuestCart(ctx)
+			if err == nil {
+				cart, err = app.dbClient.AddProductToGuestCart(ctx, token, product_id, variant_id)
+			}
+		}
+		if err != nil {
+			abort(c, err)
+			return
+		}
+
+		app.setGuestCartToken(c, token)
+		c.IndentedJSON(http.StatusOK, gin.H{"result": cart.Items, "totalPrice": guestCartTotal(cart), "expires_at": cart.Expires_At})
+	}
+}
+
+func (app *Application) GuestRemoveItem() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		product_id, ok := requiredObjectID(c, "id")
+		if !ok {
+			return
+		}
+
+		variant_id, err := variantQueryID(c)
+		if err != nil {
+			abort(c, apperror.InvalidID("variantID"))
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), app.requestTimeout)
+		defer cancel()
+
+		token := guestCartToken(c)
+		cart, err := app.dbClient.RemoveGuestCartItem(ctx, token, product_id, variant_id)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+
+		app.setGuestCartToken(c, token)
+		c.IndentedJSON(http.StatusOK, gin.H{"result": cart.Items, "totalPrice": guestCartTotal(cart), "expires_at": cart.Expires_At})
+	}
+}
+
+func (app *Application) GuestListCart() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), app.requestTimeout)
+		defer cancel()
+
+		token := guestCartToken(c)
+		cart, err := app.dbClient.GetGuestCart(ctx, token)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+
+		items, issues, err := app.dbClient.RevalidateCart(ctx, cart.Items)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+
+		cart.Items = items
+
+		app.setGuestCartToken(c, token)
+		c.IndentedJSON(http.StatusOK, gin.H{"result": cart.Items, "totalPrice": guestCartTotal(cart), "issues": issues, "expires_at": cart.Expires_At})
+	}
+}
+
+// mergeGuestCart folds the visitor's guest cart, if any, into the user's cart
+// after signup or login. A failed merge never fails the login itself.
+func (app *Application) mergeGuestCart(ctx context.Context, c *gin.Context, user *models.User) {
+
+	token := guestCartToken(c)
+	if token == "" {
+		return
+	}
+
+	cart, err := app.dbClient.MergeGuestCart(ctx, token, user.ID)
+	if err != nil && err != database.ErrCantFindGuestCart {
+		logging.From(ctx).Error(err)
+		return
+	}
+
+	if err == nil {
+		user.UserCart = cart
+	}
+	app.clearGuestCartToken(c)
+}
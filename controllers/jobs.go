@@ -0,0 +1,134 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mayuka-c/e-commerce/apperror"
+	"github.com/mayuka-c/e-commerce/database"
+	"github.com/mayuka-c/e-commerce/logging"
+	"github.com/mayuka-c/e-commerce/models"
+)
+
+// ListJobs shows the newest jobs, filtered by the optional status and kind
+func (app *Application) ListJobs() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		status := c.Query("status")
+		if status != "" && !database.ValidJobStatus(status) {
+			abort(c, database.ErrInvalidJobStatus)
+			return
+		}
+
+		limit, err := strconv.ParseInt(c.DefaultQuery("limit", "50"), 10, 64)
+		if err != nil || limit < 1 || limit > 500 {
+			abort(c, apperror.New(http.StatusBadRequest, apperror.CodeBadRequest, "limit must be between 1 and 500"))
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), app.requestTimeout)
+		defer cancel()
+
+		jobs, err := app.dbClient.GetJobs(ctx, status, c.Query("kind"), limit)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+
+		for i := range jobs {
+			redactJob(&jobs[i])
+		}
+
+		c.IndentedJSON(http.StatusOK, jobs)
+	}
+}
+
+func (app *Application) GetJob() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		job_id, ok := requiredObjectID(c, "id")
+		if !ok {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), app.requestTimeout)
+		defer cancel()
+
+		job, err := app.dbClient.GetJob(ctx, job_id)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+
+		redactJob(&job)
+
+		c.IndentedJSON(http.StatusOK, job)
+	}
+}
+
+// redactJob hides the secrets a payload may carry, such as the token of a
+// password reset email, before a job is shown
+func redactJob(job *models.Job) {
+	job.Payload = logging.RedactMap(job.Payload)
+	job.Last_Error = logging.RedactString(job.Last_Error)
+}
+
+// RetryJob requeues a failed or dead job
+func (app *Application) RetryJob() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		job_id, ok := requiredObjectID(c, "id")
+		if !ok {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), app.requestTimeout)
+		defer cancel()
+
+		err := app.dbClient.RetryJob(ctx, job_id)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, gin.H{"msg": "Job queued for retry"})
+	}
+}
+
+func (app *Application) ListRecurringJobs() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), app.requestTimeout)
+		defer cancel()
+
+		recurring, err := app.dbClient.GetRecurringJobs(ctx)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, recurring)
+	}
+}
+
+// RunRecurringJob makes a recurring job run on the next poll instead of waiting
+// for its schedule
+func (app *Application) RunRecurringJob() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name := c.Query("name")
+		if name == "" {
+			abort(c, apperror.Missing("name"))
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), app.requestTimeout)
+		defer cancel()
+
+		err := app.dbClient.RunRecurringJobNow(ctx, name)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, gin.H{"msg": "Recurring job will run shortly"})
+	}
+}
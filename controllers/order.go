@@ -0,0 +1,42 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mayuka-c/e-commerce/dto"
+)
+
+// CancelOrder cancels an order of the caller that has not shipped. The JSON
+// body with a reason is optional.
+func (app *Application) CancelOrder() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user_id, ok := currentUser(c)
+		if !ok {
+			return
+		}
+
+		order_id, ok := requiredObjectID(c, "orderID")
+		if !ok {
+			return
+		}
+
+		var body dto.CancelOrder
+		if c.Request.ContentLength > 0 && !bind(c, &body) {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), app.requestTimeout)
+		defer cancel()
+
+		order, err := app.dbClient.CancelOrder(ctx, user_id, order_id, body.Reason)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, order)
+	}
+}
@@ -0,0 +1,195 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mayuka-c/e-commerce/apperror"
+	"github.com/mayuka-c/e-commerce/database"
+	"github.com/mayuka-c/e-commerce/models"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// parseProductQuery reads the paging, sorting and filter parameters shared by
+// the product listing endpoints
+func (app *Application) parseProductQuery(ctx context.Context, c *gin.Context) (models.ProductQuery, error) {
+
+	query := models.ProductQuery{
+		Sort:  c.Query("sort"),
+		Page:  1,
+		Limit: defaultPageLimit,
+	}
+
+	if !database.ValidProductSort(query.Sort) {
+		return query, database.ErrInvalidSort
+	}
+
+	var err error
+	if query.Page, query.Limit, err = pageParams(c); err != nil {
+		return query, err
+	}
+
+	if query.Min_Price, err = uintParam(c, "min_price", 64); err != nil {
+		return query, apperror.BadRequest(err)
+	}
+	if query.Max_Price, err = uintParam(c, "max_price", 64); err != nil {
+		return query, apperror.BadRequest(err)
+	}
+
+	minRating, err := uintParam(c, "min_rating", 8)
+	if err != nil {
+		return query, apperror.BadRequest(err)
+	}
+	maxRating, err := uintParam(c, "max_rating", 8)
+	if err != nil {
+		return query, apperror.BadRequest(err)
+	}
+	query.Min_Rating = toUint8(minRating)
+	query.Max_Rating = toUint8(maxRating)
+
+	// category, price_band and rating are multi-select facets and may repeat
+	for _, slug := range c.QueryArray("category") {
+		category, err := app.dbClient.GetCategoryBySlug(ctx, slug)
+		if err != nil {
+			return query, err
+		}
+
+		tree, err := app.dbClient.CategoryTreeIDs(ctx, category.Category_ID)
+		if err != nil {
+			return query, err
+		}
+
+		query.Selected_Categories = append(query.Selected_Categories, category.Category_ID)
+		query.Category_IDs = append(query.Category_IDs, tree...)
+	}
+
+	for _, band := range c.QueryArray("price_band") {
+		if !database.ValidPriceBand(band) {
+			return query, apperror.New(http.StatusBadRequest, apperror.CodeBadRequest, "unknown price_band "+band)
+		}
+		query.Price_Bands = append(query.Price_Bands, band)
+	}
+
+	for _, raw := range c.QueryArray("rating") {
+		rating, err := strconv.ParseUint(raw, 10, 8)
+		if err != nil || rating < 1 || rating > 5 {
+			return query, apperror.New(http.StatusBadRequest, apperror.CodeBadRequest, "rating must be between 1 and 5")
+		}
+		query.Ratings = append(query.Ratings, uint8(rating))
+	}
+
+	query.Facets = c.Query("facets") == "true"
+
+	return query, nil
+}
+
+// productPage wraps a page of products and the optional facet counts in the
+// listing envelope with links to the neighbouring pages
+func productPage(c *gin.Context, products []models.Product, total int64, facets *models.ProductFacets, query models.ProductQuery) models.ProductPage {
+
+	page := models.ProductPage{
+		Items:       products,
+		Total:       total,
+		Page:        query.Page,
+		Limit:       query.Limit,
+		Total_Pages: (total + query.Limit - 1) / query.Limit,
+		Facets:      facets,
+	}
+
+	if query.Page < page.Total_Pages {
+		next := pageLink(c, query.Page+1)
+		page.Next = &next
+	}
+	if query.Page > 1 {
+		prev := pageLink(c, min64(query.Page-1, max64(page.Total_Pages, 1)))
+		page.Prev = &prev
+	}
+
+	return page
+}
+
+func pageLink(c *gin.Context, page int64) string {
+	link := *c.Request.URL
+	values := link.Query()
+	values.Set("page", strconv.FormatInt(page, 10))
+	link.RawQuery = values.Encode()
+	return link.RequestURI()
+}
+
+// pageParams reads page and limit, capping limit at maxPageLimit and refusing
+// pages past database.MaxPage
+func pageParams(c *gin.Context) (int64, int64, error) {
+
+	page, err := positiveParam(c, "page", 1)
+	if err != nil {
+		return 0, 0, apperror.BadRequest(err)
+	}
+	if page > database.MaxPage {
+		return 0, 0, database.ErrInvalidPage
+	}
+
+	limit, err := positiveParam(c, "limit", defaultPageLimit)
+	if err != nil {
+		return 0, 0, apperror.BadRequest(err)
+	}
+
+	return page, min64(limit, maxPageLimit), nil
+}
+
+func positiveParam(c *gin.Context, name string, fallback int64) (int64, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return fallback, nil
+	}
+
+	value, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || value < 1 {
+		return 0, errors.New(name + " must be a positive integer")
+	}
+
+	return value, nil
+}
+
+func uintParam(c *gin.Context, name string, bitSize int) (*uint64, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return nil, nil
+	}
+
+	value, err := strconv.ParseUint(raw, 10, bitSize)
+	if err != nil {
+		return nil, errors.New(name + " must be a non-negative integer")
+	}
+
+	return &value, nil
+}
+
+func toUint8(value *uint64) *uint8 {
+	if value == nil {
+		return nil
+	}
+	v := uint8(*value)
+	return &v
+}
+
+func min64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,52 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mayuka-c/e-commerce/apperror"
+)
+
+// Unsubscribe is the link at the bottom of cart reminders. It is a GET so it
+// works straight from a mail client.
+func (app *Application) Unsubscribe() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), app.requestTimeout)
+		defer cancel()
+
+		err := app.dbClient.Unsubscribe(ctx, c.Query("token"))
+		if err != nil {
+			abort(c, err)
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, gin.H{"msg": "You will no longer receive cart reminders"})
+	}
+}
+
+// CartReminderStats reports reminders sent and checkouts that followed them
+// over the last `days` days (default 30)
+func (app *Application) CartReminderStats() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		days, err := strconv.Atoi(c.DefaultQuery("days", "30"))
+		if err != nil || days < 1 {
+			abort(c, apperror.New(http.StatusBadRequest, apperror.CodeBadRequest, "days must be a positive number"))
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), app.requestTimeout)
+		defer cancel()
+
+		stats, err := app.dbClient.CartReminderStats(ctx, time.Now().AddDate(0, 0, -days))
+		if err != nil {
+			abort(c, err)
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, stats)
+	}
+}
@@ -0,0 +1,179 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mayuka-c/e-commerce/apperror"
+	"github.com/mayuka-c/e-commerce/constants"
+	"github.com/mayuka-c/e-commerce/dto"
+)
+
+func (app *Application) AddReview() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		product_id, ok := requiredObjectID(c, "id")
+		if !ok {
+			return
+		}
+
+		user_id, ok := currentUser(c)
+		if !ok {
+			return
+		}
+
+		var request dto.Review
+		if !bind(c, &request) {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), app.requestTimeout)
+		defer cancel()
+
+		review, err := app.dbClient.AddReview(ctx, request.Model(product_id, user_id))
+		if err != nil {
+			abort(c, err)
+			return
+		}
+
+		c.IndentedJSON(http.StatusCreated, review)
+	}
+}
+
+func (app *Application) GetProductReviews() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		productQueryID := c.Query("id")
+		if productQueryID == "" {
+			abort(c, apperror.Missing("id"))
+			return
+		}
+
+		product_id, err := primitive.ObjectIDFromHex(productQueryID)
+		if err != nil {
+			abort(c, apperror.InvalidID("productID"))
+			return
+		}
+
+		page, limit, err := pageParams(c)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), app.requestTimeout)
+		defer cancel()
+
+		product, err := app.dbClient.GetProduct(ctx, product_id)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+
+		reviews, total, err := app.dbClient.GetReviews(ctx, &product_id, constants.ReviewApproved, c.Query("sort"), page, limit)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, gin.H{
+			"rating":       product.Rating,
+			"rating_count": product.Rating_Count,
+			"items":        reviews,
+			"total":        total,
+			"page":         page,
+			"limit":        limit,
+		})
+	}
+}
+
+func (app *Application) VoteReview() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		review_id, ok := requiredObjectID(c, "id")
+		if !ok {
+			return
+		}
+
+		user_id, ok := currentUser(c)
+		if !ok {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), app.requestTimeout)
+		defer cancel()
+
+		err := app.dbClient.VoteReviewHelpful(ctx, review_id, user_id)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, gin.H{"msg": "Thanks for the feedback!"})
+	}
+}
+
+func (app *Application) ListReviewsForModeration() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		status := c.DefaultQuery("status", constants.ReviewPending)
+		if !validReviewStatus(status) {
+			abort(c, apperror.New(http.StatusBadRequest, apperror.CodeBadRequest, "status must be pending, approved or rejected"))
+			return
+		}
+
+		page, limit, err := pageParams(c)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), app.requestTimeout)
+		defer cancel()
+
+		reviews, total, err := app.dbClient.GetReviews(ctx, nil, status, "newest", page, limit)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, gin.H{"items": reviews, "total": total, "page": page, "limit": limit})
+	}
+}
+
+func (app *Application) ModerateReview() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		reviewQueryID := c.Query("id")
+		if reviewQueryID == "" {
+			abort(c, apperror.Missing("id"))
+			return
+		}
+
+		review_id, err := primitive.ObjectIDFromHex(reviewQueryID)
+		if err != nil {
+			abort(c, apperror.InvalidID("reviewID"))
+			return
+		}
+
+		status := c.Query("status")
+		if status != constants.ReviewApproved && status != constants.ReviewRejected {
+			abort(c, apperror.New(http.StatusBadRequest, apperror.CodeBadRequest, "status must be approved or rejected"))
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), app.requestTimeout)
+		defer cancel()
+
+		review, err := app.dbClient.ModerateReview(ctx, review_id, status)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, review)
+	}
+}
+
+func validReviewStatus(status string) bool {
+	return status == constants.ReviewPending || status == constants.ReviewApproved || status == constants.ReviewRejected
+}
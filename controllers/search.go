@@ -0,0 +1,120 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/mayuka-c/e-commerce/apperror"
+	"github.com/mayuka-c/e-commerce/models"
+	"github.com/mayuka-c/e-commerce/search"
+)
+
+const (
+	defaultSuggestLimit = 8
+	maxSuggestLimit     = 20
+)
+
+// RebuildSearchIndex reloads every product and category name into the
+// suggestion index
+func (app *Application) RebuildSearchIndex(ctx context.Context) error {
+
+	products, err := app.dbClient.ListProductNames(ctx)
+	if err != nil {
+		return err
+	}
+
+	categories, err := app.dbClient.GetCategories(ctx)
+	if err != nil {
+		return err
+	}
+
+	entries := make([]search.Entry, 0, len(products)+len(categories))
+	for _, product := range products {
+		if product.Product_Name != nil {
+			entries = append(entries, productEntry(product))
+		}
+	}
+	for _, category := range categories {
+		entries = append(entries, categoryEntry(category))
+	}
+
+	app.searchIndex.Replace(entries)
+	return nil
+}
+
+func (app *Application) SuggestProducts() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		query := c.Query("q")
+		if query == "" {
+			abort(c, apperror.Missing("q"))
+			return
+		}
+
+		limit := defaultSuggestLimit
+		if raw := c.Query("limit"); raw != "" {
+			value, err := strconv.Atoi(raw)
+			if err != nil || value < 1 {
+				abort(c, apperror.New(http.StatusBadRequest, apperror.CodeBadRequest, "limit must be a positive integer"))
+				return
+			}
+			if value < maxSuggestLimit {
+				limit = value
+			} else {
+				limit = maxSuggestLimit
+			}
+		}
+
+		completions := app.searchIndex.Complete(query, limit)
+		didYouMean := app.searchIndex.DidYouMean(query, 3)
+
+		// nothing starts with what was typed, so complete the best correction instead
+		if len(completions) == 0 && len(didYouMean) > 0 {
+			completions = app.searchIndex.Complete(didYouMean[0], limit)
+		}
+
+		c.IndentedJSON(http.StatusOK, gin.H{"query": query, "completions": completions, "did_you_mean": didYouMean})
+	}
+}
+
+// RefreshSearchIndex runs RebuildSearchIndex in the background so changes made
+// through other replicas eventually show up in this one
+func (app *Application) RefreshSearchIndex(ctx context.Context, interval time.Duration) {
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			rebuildCtx, cancel := context.WithTimeout(ctx, interval)
+			if err := app.RebuildSearchIndex(rebuildCtx); err != nil {
+				log.Error(err)
+			}
+			cancel()
+		}
+	}
+}
+
+func productEntry(product models.Product) search.Entry {
+	return search.Entry{
+		ID:   product.Product_ID.Hex(),
+		Kind: search.ProductKind,
+		Name: *product.Product_Name,
+	}
+}
+
+func categoryEntry(category models.Category) search.Entry {
+	return search.Entry{
+		ID:   category.Category_ID.Hex(),
+		Kind: search.CategoryKind,
+		Name: *category.Name,
+		Slug: category.Slug,
+	}
+}
@@ -0,0 +1,289 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mayuka-c/e-commerce/apperror"
+	"github.com/mayuka-c/e-commerce/constants"
+	"github.com/mayuka-c/e-commerce/database"
+	"github.com/mayuka-c/e-commerce/dto"
+	"github.com/mayuka-c/e-commerce/logging"
+	"github.com/mayuka-c/e-commerce/models"
+	"github.com/mayuka-c/e-commerce/shipping"
+)
+
+func (app *Application) AddShippingMethod() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request dto.ShippingMethod
+		if !bind(c, &request) {
+			return
+		}
+
+		if _, err := app.carriers.Get(request.Carrier); err != nil {
+			abort(c, apperror.BadRequest(err))
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), app.requestTimeout)
+		defer cancel()
+
+		method := request.Model()
+		err := app.dbClient.AddShippingMethod(ctx, method)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+
+		c.IndentedJSON(http.StatusCreated, method)
+	}
+}
+
+func (app *Application) ShippingQuote() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user_id, ok := currentUser(c)
+		if !ok {
+			return
+		}
+
+		addressIndex := 0
+		switch c.DefaultQuery("address", "home") {
+		case "home":
+		case "work":
+			addressIndex = 1
+		default:
+			abort(c, apperror.New(http.StatusBadRequest, apperror.CodeBadRequest, "address must be home or work"))
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), app.requestTimeout)
+		defer cancel()
+
+		user, _, _, err := app.dbClient.GetItemFromCart(ctx, user_id)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+
+		if len(user.Address_Details) <= addressIndex || user.Address_Details[addressIndex].Pincode == nil {
+			abort(c, apperror.New(http.StatusBadRequest, "address_not_found", "address not found for the user"))
+			return
+		}
+		pincode := *user.Address_Details[addressIndex].Pincode
+
+		methods, err := app.dbClient.GetShippingMethods(ctx)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+
+		weight, value := shipping.CartTotals(user.UserCart)
+
+		quotes := make([]models.ShippingQuote, 0, len(methods))
+		for _, method := range methods {
+			quote, err := shipping.Quote(method, pincode, weight, value)
+			if err != nil {
+				continue
+			}
+			quotes = append(quotes, quote)
+		}
+
+		c.IndentedJSON(http.StatusOK, gin.H{"weight": weight, "order_value": value, "quotes": quotes})
+	}
+}
+
+func (app *Application) CreateShipment() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user_id, ok := requiredObjectID(c, "userID")
+		if !ok {
+			return
+		}
+
+		order_id, ok := requiredObjectID(c, "orderID")
+		if !ok {
+			return
+		}
+
+		method_id, ok := requiredObjectID(c, "methodID")
+		if !ok {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), app.requestTimeout)
+		defer cancel()
+
+		order, addresses, err := app.dbClient.GetUserOrder(ctx, user_id, order_id)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+		if order.Status == constants.OrderCancelled {
+			abort(c, database.ErrOrderCancelled)
+			return
+		}
+
+		method, err := app.dbClient.GetShippingMethod(ctx, method_id)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+
+		carrier, err := app.carriers.Get(*method.Carrier)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+
+		// parcels go to the home address, the first one
+		if len(addresses) == 0 || addresses[0].Pincode == nil {
+			abort(c, apperror.New(http.StatusBadRequest, "address_not_found", "address not found for the user"))
+			return
+		}
+		address := addresses[0]
+
+		shipment := models.Shipment{
+			Shipment_ID: primitive.NewObjectID(),
+			Order_ID:    order_id,
+			User_ID:     user_id,
+			Method_ID:   method_id,
+			Carrier:     carrier.Name(),
+			Created_At:  time.Now(),
+			Updated_At:  time.Now(),
+		}
+
+		shipment.Tracking_Number, err = carrier.CreateShipment(ctx, shipment, address)
+		if err != nil {
+			abort(c, apperror.Wrap(err, http.StatusBadGateway, apperror.CodeBadGateway).WithDetail("carrier could not book the shipment"))
+			return
+		}
+
+		shipment.Events, err = carrier.Track(ctx, shipment.Tracking_Number)
+		if err != nil {
+			logging.From(ctx).Error(err)
+			shipment.Events = make([]models.ShipmentEvent, 0)
+		}
+		shipment.Status = constants.ShipmentLabelCreated
+		if len(shipment.Events) > 0 {
+			shipment.Status = shipment.Events[len(shipment.Events)-1].Status
+		}
+
+		err = app.dbClient.CreateShipment(ctx, shipment)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+
+		c.IndentedJSON(http.StatusCreated, shipment)
+	}
+}
+
+func (app *Application) UpdateShipment() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		shipmentQueryID := c.Query("id")
+		if shipmentQueryID == "" {
+			abort(c, apperror.Missing("id"))
+			return
+		}
+
+		shipment_id, err := primitive.ObjectIDFromHex(shipmentQueryID)
+		if err != nil {
+			abort(c, apperror.InvalidID("shipmentID"))
+			return
+		}
+
+		var event dto.ShipmentEvent
+		if !bind(c, &event) {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), app.requestTimeout)
+		defer cancel()
+
+		err = app.dbClient.AddShipmentEvents(ctx, shipment_id, event.Model())
+		if err != nil {
+			abort(c, err)
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, gin.H{"msg": "Successfully updated the shipment!"})
+	}
+}
+
+func (app *Application) TrackShipment() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user_id, ok := currentUser(c)
+		if !ok {
+			return
+		}
+
+		order_id, ok := requiredObjectID(c, "orderID")
+		if !ok {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), app.requestTimeout)
+		defer cancel()
+
+		shipments, err := app.dbClient.GetShipmentsByOrder(ctx, user_id, order_id)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+
+		for i := range shipments {
+			app.syncShipment(ctx, &shipments[i])
+		}
+
+		c.IndentedJSON(http.StatusOK, shipments)
+	}
+}
+
+// syncShipment pulls the latest tracking events from the carrier and stores the
+// ones not seen before. Carrier failures leave the stored shipment as it is.
+func (app *Application) syncShipment(ctx context.Context, shipment *models.Shipment) {
+
+	carrier, err := app.carriers.Get(shipment.Carrier)
+	if err != nil {
+		logging.From(ctx).Error(err)
+		return
+	}
+
+	tracked, err := carrier.Track(ctx, shipment.Tracking_Number)
+	if err != nil {
+		logging.From(ctx).Error(err)
+		return
+	}
+
+	seen := make(map[string]bool, len(shipment.Events))
+	for _, event := range shipment.Events {
+		seen[eventKey(event)] = true
+	}
+
+	var fresh []models.ShipmentEvent
+	for _, event := range tracked {
+		if !seen[eventKey(event)] {
+			fresh = append(fresh, event)
+		}
+	}
+
+	if len(fresh) == 0 {
+		return
+	}
+
+	if err := app.dbClient.AddShipmentEvents(ctx, shipment.Shipment_ID, fresh...); err != nil {
+		logging.From(ctx).Error(err)
+		return
+	}
+
+	shipment.Events = append(shipment.Events, fresh...)
+	shipment.Status = fresh[len(fresh)-1].Status
+}
+
+func eventKey(event models.ShipmentEvent) string {
+	return event.Status + "|" + event.Occurred_At.UTC().Format(time.RFC3339)
+}
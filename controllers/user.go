@@ -7,20 +7,22 @@ import (
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"golang.org/x/crypto/bcrypt"
 
 	"github.com/gin-gonic/gin"
-	"github.com/go-playground/validator/v10"
 	log "github.com/sirupsen/logrus"
 
+	"github.com/mayuka-c/e-commerce/apperror"
+	"github.com/mayuka-c/e-commerce/constants"
+	"github.com/mayuka-c/e-commerce/database"
+	"github.com/mayuka-c/e-commerce/dto"
 	"github.com/mayuka-c/e-commerce/models"
 )
 
-var Validate = validator.New()
+func (app *Application) hashPassword(password string) string {
 
-func hashPassword(password string) string {
-
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 14)
+	bytes, err := bcrypt.GenerateFromPassword([]byte(password), app.bcryptCost)
 	if err != nil {
 		log.Panicln(err)
 	}
@@ -43,51 +45,38 @@ func verifyPassword(userPassword string, givenPassword string) (bool, string) {
 
 func (app *Application) SignUp() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		ctx, cancel := context.WithTimeout(c.Request.Context(), app.requestTimeout)
 		defer cancel()
 
-		var user models.User
-		err := c.BindJSON(&user)
-		if err != nil {
-			log.Error(err)
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-			return
-		}
-
-		validationErr := Validate.Struct(user)
-		if validationErr != nil {
-			log.Error(err)
-			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+		var request dto.SignUp
+		if !bind(c, &request) {
 			return
 		}
+		user := request.Model()
 
 		count, err := app.dbClient.CountDocuments(ctx, app.dbClient.GetUserCollection(), bson.M{"email": user.Email})
 		if err != nil {
-			log.Error(err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			abort(c, err)
 			return
 		}
 
 		if count > 0 {
-			log.Error("User already exist with provided email")
-			c.JSON(http.StatusBadRequest, gin.H{"error": "user already exist with provided email"})
+			abort(c, apperror.New(http.StatusConflict, "email_taken", "user already exist with provided email"))
 			return
 		}
 
 		count, err = app.dbClient.CountDocuments(ctx, app.dbClient.GetUserCollection(), bson.M{"phone": user.Phone})
 		if err != nil {
-			log.Error(err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			abort(c, err)
 			return
 		}
 
 		if count > 0 {
-			log.Error("Provided phone number is already in use")
-			c.JSON(http.StatusBadRequest, gin.H{"error": "this phone number is already in use"})
+			abort(c, apperror.New(http.StatusConflict, "phone_taken", "this phone number is already in use"))
 			return
 		}
 
-		password := hashPassword(*user.Password)
+		password := app.hashPassword(*user.Password)
 		user.Password = &password
 
 		user.Created_At, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
@@ -98,98 +87,127 @@ func (app *Application) SignUp() gin.HandlerFunc {
 		*userIDHex = user.ID.Hex()
 		user.User_ID = userIDHex
 
-		token, refreshToken, _ := app.tokenClient.TokenGenerator(*user.Email, *user.First_Name, *user.Last_Name, *user.User_ID)
+		user.Role = constants.RoleCustomer
+
+		token, refreshToken, _ := app.tokenClient.TokenGenerator(*user.Email, *user.First_Name, *user.Last_Name, *user.User_ID, user.Role)
 		user.Token = &token
 		user.Refresh_Token = &refreshToken
 		user.UserCart = make([]models.ProductUser, 0)
 		user.Address_Details = make([]models.Address, 0)
 		user.Order_Status = make([]models.Order, 0)
+		user.Notifications_Off = make([]string, 0)
+		if user.Locale == "" {
+			user.Locale = "en"
+		}
 
-		err = app.dbClient.InsertOne(ctx, app.dbClient.GetUserCollection(), user)
+		err = app.dbClient.CreateUser(ctx, user)
 		if err != nil {
-			log.Error(err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "the user did not get created"})
+			abort(c, err)
 			return
 		}
 
+		app.mergeGuestCart(ctx, c, &user)
+
 		c.JSON(http.StatusCreated, gin.H{"msg": "Successfully signed in!"})
 	}
 }
 
 func (app *Application) Login() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		ctx, cancel := context.WithTimeout(c.Request.Context(), app.requestTimeout)
 		defer cancel()
 
-		var user models.User
-		err := c.BindJSON(&user)
-		if err != nil {
-			log.Error(err)
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		var user dto.Login
+		if !bind(c, &user) {
 			return
 		}
 
+		// the same answer for an unknown email and a wrong password
+		loginErr := apperror.New(http.StatusUnauthorized, "invalid_credentials", "login is incorrect")
+
 		var founduser models.User
-		err = app.dbClient.FindOne(ctx, app.dbClient.GetUserCollection(), bson.M{"email": user.Email}).Decode(&founduser)
+		err := app.dbClient.FindOne(ctx, app.dbClient.GetUserCollection(), bson.M{"email": user.Email}).Decode(&founduser)
+		if err == mongo.ErrNoDocuments {
+			abort(c, loginErr)
+			return
+		}
 		if err != nil {
-			log.Error(err)
-			c.JSON(http.StatusBadRequest, gin.H{"error": "login is incorrect"})
+			abort(c, err)
 			return
 		}
 
-		PasswordIsValid, msg := verifyPassword(*user.Password, *founduser.Password)
+		PasswordIsValid, _ := verifyPassword(user.Password, *founduser.Password)
 		if !PasswordIsValid {
-			log.Error(msg)
-			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			abort(c, loginErr)
+			return
+		}
+
+		token, refreshToken, err := app.tokenClient.TokenGenerator(*founduser.Email, *founduser.First_Name, *founduser.Last_Name, *founduser.User_ID, founduser.Role)
+		if err != nil {
+			abort(c, err)
 			return
 		}
 
-		token, refreshToken, _ := app.tokenClient.TokenGenerator(*founduser.Email, *founduser.First_Name, *founduser.Last_Name, *founduser.User_ID)
+		err = app.tokenClient.UpdateAllTokens(ctx, token, refreshToken, *founduser.User_ID)
+		if err != nil {
+			abort(c, err)
+			return
+		}
 
-		app.tokenClient.UpdateAllTokens(token, refreshToken, *founduser.User_ID)
+		app.mergeGuestCart(ctx, c, &founduser)
 
-		c.JSON(http.StatusFound, founduser)
+		founduser.Password = nil
+		c.JSON(http.StatusOK, founduser)
 	}
 }
 
 func (app *Application) ProductViewerAdmin() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), app.requestTimeout)
 		defer cancel()
 
-		var products models.Product
+		var request dto.Product
+		if !bind(c, &request) {
+			return
+		}
+		products := request.Model()
 
-		if err := c.BindJSON(&products); err != nil {
-			log.Error(err)
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		err := app.dbClient.CategoriesExist(ctx, products.Category_IDs)
+		if err != nil {
+			abort(c, apperror.BadRequest(err))
 			return
 		}
 
-		products.Product_ID = primitive.NewObjectID()
-		err := app.dbClient.InsertOne(ctx, app.dbClient.GetProductCollection(), products)
+		err = app.dbClient.InsertOne(ctx, app.dbClient.GetProductCollection(), products)
 		if err != nil {
-			log.Error(err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Not Created"})
+			abort(c, err)
 			return
 		}
 
+		app.searchIndex.Upsert(productEntry(products))
+
 		c.JSON(http.StatusOK, gin.H{"msg": "Successfully added our Product Admin!!"})
 	}
 }
 
 func (app *Application) SearchProducts() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		ctx, cancel := context.WithTimeout(c.Request.Context(), app.requestTimeout)
 		defer cancel()
 
-		productList, err := app.dbClient.SearchProducts(ctx)
+		query, err := app.parseProductQuery(ctx, c)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+
+		productList, total, facets, err := app.dbClient.SearchProducts(ctx, query)
 		if err != nil {
-			log.Error(err)
-			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+			abort(c, err)
 			return
 		}
 
-		c.IndentedJSON(http.StatusOK, productList)
+		c.IndentedJSON(http.StatusOK, productPage(c, productList, total, facets, query))
 	}
 }
 
@@ -197,21 +215,28 @@ func (app *Application) SearchProductsByQuery() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		productName := c.Query("name")
 		if productName == "" {
-			log.Println("product name is empty")
-			c.JSON(http.StatusNotFound, gin.H{"error": "invalid search index"})
+			abort(c, apperror.Missing("name"))
 			return
 		}
 
-		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		ctx, cancel := context.WithTimeout(c.Request.Context(), app.requestTimeout)
 		defer cancel()
 
-		productList, err := app.dbClient.SearchProductsByQuery(ctx, productName)
+		query, err := app.parseProductQuery(ctx, c)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+
+		query.Search = productName
+		query.Search_Mode = c.DefaultQuery("mode", database.TextSearchMode)
+
+		productList, total, facets, err := app.dbClient.SearchProductsByQuery(ctx, query)
 		if err != nil {
-			log.Error(err)
-			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+			abort(c, err)
 			return
 		}
 
-		c.IndentedJSON(http.StatusOK, productList)
+		c.IndentedJSON(http.StatusOK, productPage(c, productList, total, facets, query))
 	}
 }
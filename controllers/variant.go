@@ -0,0 +1,84 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mayuka-c/e-commerce/apperror"
+	"github.com/mayuka-c/e-commerce/dto"
+)
+
+func (app *Application) SetProductOptions() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		productQueryID := c.Query("id")
+		if productQueryID == "" {
+			abort(c, apperror.Missing("id"))
+			return
+		}
+
+		product_id, err := primitive.ObjectIDFromHex(productQueryID)
+		if err != nil {
+			abort(c, apperror.InvalidID("productID"))
+			return
+		}
+
+		var body dto.ProductOptions
+		if !bind(c, &body) {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), app.requestTimeout)
+		defer cancel()
+
+		product, err := app.dbClient.SetProductOptions(ctx, product_id, body.Model())
+		if err != nil {
+			abort(c, err)
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, product)
+	}
+}
+
+func (app *Application) EditVariant() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		productQueryID := c.Query("id")
+		variantQueryID := c.Query("variantID")
+		if productQueryID == "" || variantQueryID == "" {
+			abort(c, apperror.New(http.StatusBadRequest, apperror.CodeMissingParameter, "id and variantID are required"))
+			return
+		}
+
+		product_id, err := primitive.ObjectIDFromHex(productQueryID)
+		if err != nil {
+			abort(c, apperror.InvalidID("productID"))
+			return
+		}
+
+		variant_id, err := primitive.ObjectIDFromHex(variantQueryID)
+		if err != nil {
+			abort(c, apperror.InvalidID("variantID"))
+			return
+		}
+
+		var variantUpdate dto.VariantUpdate
+		if !bind(c, &variantUpdate) {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), app.requestTimeout)
+		defer cancel()
+
+		err = app.dbClient.UpdateVariant(ctx, product_id, variant_id, variantUpdate.Model())
+		if err != nil {
+			abort(c, err)
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, gin.H{"msg": "Successfully updated the variant!"})
+	}
+}
@@ -0,0 +1,206 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mayuka-c/e-commerce/apperror"
+	"github.com/mayuka-c/e-commerce/database"
+	"github.com/mayuka-c/e-commerce/dto"
+	"github.com/mayuka-c/e-commerce/events"
+	"github.com/mayuka-c/e-commerce/webhooks"
+)
+
+func validWebhookEvents(eventTypes []string) bool {
+	for _, eventType := range eventTypes {
+		if eventType != "*" && !events.KnownType(eventType) {
+			return false
+		}
+	}
+	return true
+}
+
+// AddWebhook registers a merchant endpoint. The response is the only place the
+// signing secret is shown.
+func (app *Application) AddWebhook() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request dto.WebhookEndpoint
+		if !bind(c, &request) {
+			return
+		}
+
+		if !validWebhookEvents(request.Events) {
+			abort(c, apperror.From(database.ErrUnknownWebhookEventType).With("event_types", events.Types()))
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), app.requestTimeout)
+		defer cancel()
+
+		endpoint, err := app.dbClient.AddWebhookEndpoint(ctx, request.Model())
+		if err != nil {
+			abort(c, err)
+			return
+		}
+
+		c.IndentedJSON(http.StatusCreated, endpoint)
+	}
+}
+
+func (app *Application) ListWebhooks() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), app.requestTimeout)
+		defer cancel()
+
+		endpoints, err := app.dbClient.GetWebhookEndpoints(ctx)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, endpoints)
+	}
+}
+
+// EditWebhook changes the url, the subscribed events or whether the endpoint is active
+func (app *Application) EditWebhook() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		endpoint_id, ok := requiredObjectID(c, "id")
+		if !ok {
+			return
+		}
+
+		var endpointUpdate dto.WebhookEndpointUpdate
+		if !bind(c, &endpointUpdate) {
+			return
+		}
+
+		if !validWebhookEvents(endpointUpdate.Events) {
+			abort(c, apperror.From(database.ErrUnknownWebhookEventType).With("event_types", events.Types()))
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), app.requestTimeout)
+		defer cancel()
+
+		err := app.dbClient.UpdateWebhookEndpoint(ctx, endpoint_id, endpointUpdate.Model())
+		if err != nil {
+			abort(c, err)
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, gin.H{"msg": "Successfully updated the webhook"})
+	}
+}
+
+func (app *Application) DeleteWebhook() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		endpoint_id, ok := requiredObjectID(c, "id")
+		if !ok {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), app.requestTimeout)
+		defer cancel()
+
+		err := app.dbClient.DeleteWebhookEndpoint(ctx, endpoint_id)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, gin.H{"msg": "Successfully deleted the webhook"})
+	}
+}
+
+// ListWebhookDeliveries shows the delivery log, filtered by the optional
+// endpointID and status. status=dead lists the dead-letter queue.
+func (app *Application) ListWebhookDeliveries() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var endpoint_id *primitive.ObjectID
+		if c.Query("endpointID") != "" {
+			id, ok := requiredObjectID(c, "endpointID")
+			if !ok {
+				return
+			}
+			endpoint_id = &id
+		}
+
+		status := c.Query("status")
+		if status != "" && !database.ValidDeliveryStatus(status) {
+			abort(c, database.ErrInvalidDeliveryStatus)
+			return
+		}
+
+		limit, err := strconv.ParseInt(c.DefaultQuery("limit", "50"), 10, 64)
+		if err != nil || limit < 1 || limit > 500 {
+			abort(c, apperror.New(http.StatusBadRequest, apperror.CodeBadRequest, "limit must be between 1 and 500"))
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), app.requestTimeout)
+		defer cancel()
+
+		deliveries, err := app.dbClient.GetWebhookDeliveries(ctx, endpoint_id, status, limit)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, deliveries)
+	}
+}
+
+func (app *Application) GetWebhookDelivery() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		delivery_id, ok := requiredObjectID(c, "id")
+		if !ok {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), app.requestTimeout)
+		defer cancel()
+
+		delivery, err := app.dbClient.GetWebhookDelivery(ctx, delivery_id)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, delivery)
+	}
+}
+
+// RedeliverWebhook sends a delivered or dead delivery again, with the same body
+func (app *Application) RedeliverWebhook() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		delivery_id, ok := requiredObjectID(c, "id")
+		if !ok {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), app.requestTimeout)
+		defer cancel()
+
+		// a delivery left pending without its job could never be redelivered
+		err := app.dbClient.WithTransaction(ctx, func(ctx context.Context) error {
+			_, err := app.dbClient.ResetWebhookDelivery(ctx, delivery_id)
+			if err != nil {
+				return err
+			}
+
+			return webhooks.Enqueue(ctx, app.dbClient, delivery_id)
+		})
+		if err != nil {
+			abort(c, err)
+			return
+		}
+
+		c.IndentedJSON(http.StatusAccepted, gin.H{"msg": "Delivery queued"})
+	}
+}
@@ -0,0 +1,303 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mayuka-c/e-commerce/apperror"
+	"github.com/mayuka-c/e-commerce/dto"
+)
+
+func (app *Application) CreateWishlist() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user_id, ok := currentUser(c)
+		if !ok {
+			return
+		}
+
+		var body dto.Wishlist
+		if !bind(c, &body) {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), app.requestTimeout)
+		defer cancel()
+
+		wishlist, err := app.dbClient.CreateWishlist(ctx, user_id, body.Name)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+
+		c.IndentedJSON(http.StatusCreated, wishlist)
+	}
+}
+
+func (app *Application) ListWishlists() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user_id, ok := currentUser(c)
+		if !ok {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), app.requestTimeout)
+		defer cancel()
+
+		wishlists, err := app.dbClient.GetWishlists(ctx, user_id)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, wishlists)
+	}
+}
+
+func (app *Application) DeleteWishlist() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user_id, ok := currentUser(c)
+		if !ok {
+			return
+		}
+		wishlist_id, ok := requiredObjectID(c, "wishlistID")
+		if !ok {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), app.requestTimeout)
+		defer cancel()
+
+		err := app.dbClient.DeleteWishlist(ctx, user_id, wishlist_id)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, gin.H{"msg": "Successfully deleted the wishlist!"})
+	}
+}
+
+func (app *Application) AddToWishlist() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user_id, ok := currentUser(c)
+		if !ok {
+			return
+		}
+		wishlist_id, ok := requiredObjectID(c, "wishlistID")
+		if !ok {
+			return
+		}
+		product_id, ok := requiredObjectID(c, "id")
+		if !ok {
+			return
+		}
+
+		variant_id, err := variantQueryID(c)
+		if err != nil {
+			abort(c, apperror.InvalidID("variantID"))
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), app.requestTimeout)
+		defer cancel()
+
+		err = app.dbClient.AddWishlistItem(ctx, user_id, wishlist_id, product_id, variant_id)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, gin.H{"msg": "Successfully added to the wishlist"})
+	}
+}
+
+func (app *Application) RemoveFromWishlist() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user_id, ok := currentUser(c)
+		if !ok {
+			return
+		}
+		wishlist_id, ok := requiredObjectID(c, "wishlistID")
+		if !ok {
+			return
+		}
+		product_id, ok := requiredObjectID(c, "id")
+		if !ok {
+			return
+		}
+
+		variant_id, err := variantQueryID(c)
+		if err != nil {
+			abort(c, apperror.InvalidID("variantID"))
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), app.requestTimeout)
+		defer cancel()
+
+		err = app.dbClient.RemoveWishlistItem(ctx, user_id, wishlist_id, product_id, variant_id)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, gin.H{"msg": "Successfully removed from the wishlist"})
+	}
+}
+
+func (app *Application) MoveWishlistItemToCart() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user_id, ok := currentUser(c)
+		if !ok {
+			return
+		}
+		wishlist_id, ok := requiredObjectID(c, "wishlistID")
+		if !ok {
+			return
+		}
+		product_id, ok := requiredObjectID(c, "id")
+		if !ok {
+			return
+		}
+
+		variant_id, err := variantQueryID(c)
+		if err != nil {
+			abort(c, apperror.InvalidID("variantID"))
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), app.requestTimeout)
+		defer cancel()
+
+		err = app.dbClient.MoveWishlistItemToCart(ctx, user_id, wishlist_id, product_id, variant_id)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, gin.H{"msg": "Successfully moved to the cart"})
+	}
+}
+
+func (app *Application) SaveForLater() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user_id, ok := currentUser(c)
+		if !ok {
+			return
+		}
+		product_id, ok := requiredObjectID(c, "id")
+		if !ok {
+			return
+		}
+
+		variant_id, err := variantQueryID(c)
+		if err != nil {
+			abort(c, apperror.InvalidID("variantID"))
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), app.requestTimeout)
+		defer cancel()
+
+		err = app.dbClient.SaveForLater(ctx, user_id, product_id, variant_id)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, gin.H{"msg": "Successfully saved for later"})
+	}
+}
+
+func (app *Application) ShareWishlist() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user_id, ok := currentUser(c)
+		if !ok {
+			return
+		}
+		wishlist_id, ok := requiredObjectID(c, "wishlistID")
+		if !ok {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), app.requestTimeout)
+		defer cancel()
+
+		token, err := app.dbClient.ShareWishlist(ctx, user_id, wishlist_id)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, gin.H{"share_token": token, "share_link": "/users/sharedwishlist?token=" + token})
+	}
+}
+
+func (app *Application) UnshareWishlist() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user_id, ok := currentUser(c)
+		if !ok {
+			return
+		}
+		wishlist_id, ok := requiredObjectID(c, "wishlistID")
+		if !ok {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), app.requestTimeout)
+		defer cancel()
+
+		err := app.dbClient.UnshareWishlist(ctx, user_id, wishlist_id)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, gin.H{"msg": "Share link revoked"})
+	}
+}
+
+// SharedWishlist is the public read-only view of a shared list. It leaves out
+// the owner and the share token.
+func (app *Application) SharedWishlist() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := c.Query("token")
+		if token == "" {
+			abort(c, apperror.Missing("token"))
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), app.requestTimeout)
+		defer cancel()
+
+		wishlist, err := app.dbClient.GetSharedWishlist(ctx, token)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, gin.H{"name": wishlist.Name, "items": wishlist.Items, "updated_at": wishlist.Updated_At})
+	}
+}
+
+func (app *Application) WishlistPriceDrops() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user_id, ok := currentUser(c)
+		if !ok {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), app.requestTimeout)
+		defer cancel()
+
+		drops, err := app.dbClient.WishlistPriceDrops(ctx, user_id)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, drops)
+	}
+}
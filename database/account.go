@@ -0,0 +1,133 @@
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/mayuka-c/e-commerce/models"
+)
+
+var (
+	ErrCantFindUser      = errors.New("can't find the user")
+	ErrInvalidResetToken = errors.New("password reset link is invalid or has expired")
+)
+
+// PasswordResetTTL is how long a password reset link works
+const PasswordResetTTL = time.Hour
+
+func (d *DBClient) GetUser(ctx context.Context, user_id primitive.ObjectID) (models.User, error) {
+
+	var user models.User
+
+	err := d.userCollection.FindOne(ctx, bson.M{"_id": user_id}).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		return user, ErrCantFindUser
+	}
+	if err != nil {
+		return user, err
+	}
+
+	return user, nil
+}
+
+func (d *DBClient) GetUserByEmail(ctx context.Context, email string) (models.User, error) {
+
+	var user models.User
+
+	err := d.userCollection.FindOne(ctx, bson.M{"email": email}).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		return user, ErrCantFindUser
+	}
+	if err != nil {
+		return user, err
+	}
+
+	return user, nil
+}
+
+func (d *DBClient) UpdateNotificationPrefs(ctx context.Context, user_id primitive.ObjectID, prefs models.NotificationPrefs) error {
+
+	off := prefs.Notifications_Off
+	if off == nil {
+		off = make([]string, 0)
+	}
+
+	// cart reminders keep their own flag, which the unsubscribe link also sets
+	cartRemindersOff := false
+	for _, kind := range off {
+		if kind == cartReminderKind {
+			cartRemindersOff = true
+		}
+	}
+
+	set := bson.M{"notifications_off": off, "cart_reminders_off": cartRemindersOff, "updated_at": time.Now()}
+	if prefs.Locale != "" {
+		set["locale"] = prefs.Locale
+	}
+
+	result, err := d.userCollection.UpdateOne(ctx, bson.M{"_id": user_id}, bson.M{"$set": set})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrCantFindUser
+	}
+
+	return nil
+}
+
+// CreatePasswordReset gives the user a new reset token, replacing any earlier
+// one. Only the hash of the token is stored.
+func (d *DBClient) CreatePasswordReset(ctx context.Context, user_id primitive.ObjectID) (models.User, string, error) {
+
+	var user models.User
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return user, "", err
+	}
+	token := hex.EncodeToString(raw)
+
+	reset := models.PasswordReset{Token_Hash: hashToken(token), Expires_At: time.Now().Add(PasswordResetTTL)}
+
+	err := d.userCollection.FindOneAndUpdate(ctx, bson.M{"_id": user_id}, bson.M{"$set": bson.M{"password_reset": reset}}).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		return user, "", ErrCantFindUser
+	}
+	if err != nil {
+		return user, "", err
+	}
+
+	return user, token, nil
+}
+
+// ResetPassword sets the new, already hashed, password for the user the token
+// was issued to. A token works once.
+func (d *DBClient) ResetPassword(ctx context.Context, token, hashedPassword string) error {
+
+	filter := bson.M{
+		"password_reset.token_hash": hashToken(token),
+		"password_reset.expires_at": bson.M{"$gt": time.Now()},
+	}
+	update := bson.M{
+		"$set":   bson.M{"password": hashedPassword, "updated_at": time.Now()},
+		"$unset": bson.M{"password_reset": ""},
+	}
+
+	result, err := d.userCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrInvalidResetToken
+	}
+
+	return nil
+}
@@ -2,191 +2,278 @@ package database
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 
+	"github.com/mayuka-c/e-commerce/constants"
+	"github.com/mayuka-c/e-commerce/events"
+	"github.com/mayuka-c/e-commerce/metrics"
 	"github.com/mayuka-c/e-commerce/models"
 )
 
 var (
 	ErrCantFindProduct    = errors.New("can't find the product")
-	ErrCantDecodeProducts = errors.New("can't find the product")
+	ErrCantDecodeProducts = errors.New("can't decode the products")
 	ErrCantUpdateUser     = errors.New("cannot add this product to the cart")
 	ErrCantRemoveItem     = errors.New("cannot remove this product from the cart")
 	ErrCantGetItem        = errors.New("unable to get the item from the cart")
-	ErrCantBuyCartItem    = errors.New("cannot update the purchase")
-	ErrCantDoInstantBuyer = errors.New("cannot update the purchase")
+	ErrCantBuyCartItem    = errors.New("cannot place the order for the cart")
+	ErrCantDoInstantBuyer = errors.New("cannot place the instant order")
+	ErrCartChanged        = errors.New("cart items changed since they were reviewed, review the changes and check out with the new version")
+	ErrEmptyCart          = errors.New("cart is empty")
 )
 
-func (d *DBClient) AddProductToCart(ctx context.Context, product_id, user_id primitive.ObjectID) error {
+func (d *DBClient) AddProductToCart(ctx context.Context, product_id primitive.ObjectID, variant_id *primitive.ObjectID, user_id primitive.ObjectID) error {
 
-	searchfromdb, err := d.productCollection.Find(ctx, bson.M{"_id": product_id})
+	product, err := d.GetProduct(ctx, product_id)
 	if err != nil {
 		return ErrCantFindProduct
 	}
 
-	var productcart []models.ProductUser
-	err = searchfromdb.All(ctx, &productcart)
+	productcart, err := cartLine(product, variant_id)
 	if err != nil {
-		return ErrCantDecodeProducts
+		return err
 	}
 
 	filter := bson.D{{Key: "_id", Value: user_id}}
-	update := bson.D{{Key: "$push", Value: bson.D{{Key: "usercart", Value: bson.D{{Key: "$each", Value: productcart}}}}}}
+	update := bson.D{
+		{Key: "$push", Value: bson.D{{Key: "usercart", Value: productcart}}},
+		{Key: "$set", Value: bson.D{{Key: "cart_updated_at", Value: time.Now()}}},
+	}
 
-	_, err = d.userCollection.UpdateOne(ctx, filter, update)
+	err = d.WithTransaction(ctx, func(ctx context.Context) error {
+		_, err := d.userCollection.UpdateOne(ctx, filter, update)
+		if err != nil {
+			return ErrCantUpdateUser
+		}
+
+		return d.recordEvent(ctx, events.CartItemAdded{
+			User_ID:    user_id,
+			Product_ID: product_id,
+			Variant_ID: productcart.Variant_ID,
+			Price:      productcart.Price,
+		})
+	})
 	if err != nil {
-		return ErrCantUpdateUser
+		return err
 	}
 
+	metrics.AddedToCart(metrics.UserCart)
 	return nil
 }
 
-func (d *DBClient) RemoveCartItem(ctx context.Context, product_id, user_id primitive.ObjectID) error {
-
-	filter := bson.D{{Key: "_id", Value: user_id}}
-	update := bson.M{"$pull": bson.M{"usercart": bson.M{"_id": product_id}}}
+// RemoveCartItem removes the product from the cart, or only the given variant of it
+func (d *DBClient) RemoveCartItem(ctx context.Context, product_id primitive.ObjectID, variant_id *primitive.ObjectID, user_id primitive.ObjectID) error {
 
-	_, err := d.userCollection.UpdateMany(ctx, filter, update)
-	if err != nil {
-		return ErrCantRemoveItem
+	line := bson.M{"_id": product_id}
+	if variant_id != nil {
+		line["variant_id"] = *variant_id
 	}
 
-	return nil
+	filter := bson.D{{Key: "_id", Value: user_id}, {Key: "usercart", Value: bson.M{"$elemMatch": line}}}
+	update := bson.M{"$pull": bson.M{"usercart": line}, "$set": bson.M{"cart_updated_at": time.Now()}}
+
+	return d.WithTransaction(ctx, func(ctx context.Context) error {
+		result, err := d.userCollection.UpdateMany(ctx, filter, update)
+		if err != nil {
+			return ErrCantRemoveItem
+		}
+		if result.MatchedCount == 0 {
+			return nil
+		}
+
+		return d.recordEvent(ctx, events.CartItemRemoved{User_ID: user_id, Product_ID: product_id, Variant_ID: variant_id})
+	})
 }
 
-func (d *DBClient) GetItemFromCart(ctx context.Context, user_id primitive.ObjectID) (models.User, int32, error) {
+// GetItemFromCart returns the user with the cart as it would be bought now,
+// its total at current catalog prices and the lines that changed since they
+// were added
+func (d *DBClient) GetItemFromCart(ctx context.Context, user_id primitive.ObjectID) (models.User, int, []models.CartIssue, error) {
 
 	var filledCart models.User
-	var totalPrice int32
 
 	err := d.userCollection.FindOne(ctx, bson.D{{Key: "_id", Value: user_id}}).Decode(&filledCart)
 	if err != nil {
-		return filledCart, totalPrice, err
-	}
-
-	filter_match := bson.D{{Key: "$match", Value: bson.D{{Key: "_id", Value: user_id}}}}
-	unwind := bson.D{{Key: "$unwind", Value: bson.D{{Key: "path", Value: "$usercart"}}}}
-	grouping := bson.D{{Key: "$group", Value: bson.D{{Key: "_id", Value: "$_id"}, {Key: "total", Value: bson.D{{Key: "$sum", Value: "$usercart.price"}}}}}}
-
-	pointCursor, err := d.userCollection.Aggregate(ctx, mongo.Pipeline{filter_match, unwind, grouping})
-	if err != nil {
-		return filledCart, totalPrice, err
+		return filledCart, 0, nil, err
 	}
 
-	var listing []bson.M
-	err = pointCursor.All(ctx, &listing)
+	lines, issues, err := d.RevalidateCart(ctx, filledCart.UserCart)
 	if err != nil {
-		return filledCart, totalPrice, err
+		return filledCart, 0, nil, err
 	}
+	filledCart.UserCart = lines
 
-	for _, json := range listing {
-		totalPrice = json["total"].(int32)
-	}
-
-	return filledCart, totalPrice, nil
+	return filledCart, cartTotal(lines), issues, nil
 }
 
-func (d *DBClient) BuyItemFromCart(ctx context.Context, user_id primitive.ObjectID) error {
-
-	var getCartItems models.User
-	var orderCart models.Order
-
-	orderCart.Order_ID = primitive.NewObjectID()
-	orderCart.Ordered_At = time.Now()
-	orderCart.Order_Cart = make([]models.ProductUser, 0)
-	orderCart.Payment_Method.CashOnDelivery = true
-
-	unwind := bson.D{{Key: "$unwind", Value: bson.D{{Key: "path", Value: "$usercart"}}}}
-	grouping := bson.D{{Key: "$group", Value: bson.D{{Key: "_id", Value: "$_id"}, {Key: "total_price", Value: bson.D{{Key: "$sum", Value: "$usercart.price"}}}}}}
-
-	totalPriceCursor, err := d.userCollection.Aggregate(ctx, mongo.Pipeline{unwind, grouping})
+// BuyItemFromCart places an order for the cart at current catalog prices.
+// When a line changed since it was added the order is only placed if version
+// is the CartVersion of the cart the user reviewed; otherwise ErrCartChanged is
+// returned with the issues and the version to review. A version that no
+// longer matches is refused even without issues.
+//
+// The cart is read and the ordered lines pulled from it in one transaction.
+// Lines added meanwhile and out of stock lines stay in the cart; lines of
+// removed products are dropped.
+func (d *DBClient) BuyItemFromCart(ctx context.Context, user_id primitive.ObjectID, version string) ([]models.CartIssue, string, error) {
+
+	var (
+		orderCart models.Order
+		issues    []models.CartIssue
+		current   string
+	)
+
+	err := d.WithTransaction(ctx, func(ctx context.Context) error {
+
+		var user models.User
+		err := d.userCollection.FindOne(ctx, bson.D{{Key: "_id", Value: user_id}}).Decode(&user)
+		if err != nil {
+			return ErrCantBuyCartItem
+		}
+
+		var lines []models.ProductUser
+		lines, issues, err = d.RevalidateCart(ctx, user.UserCart)
+		if err != nil {
+			return ErrCantBuyCartItem
+		}
+
+		current = CartVersion(lines)
+		if (len(issues) > 0 || version != "") && version != current {
+			return ErrCartChanged
+		}
+		if len(lines) == 0 {
+			return ErrEmptyCart
+		}
+
+		orderCart = models.Order{
+			Order_ID:   primitive.NewObjectID(),
+			Ordered_At: time.Now(),
+			Order_Cart: lines,
+			Price:      cartTotal(lines),
+			Status:     constants.OrderPlaced,
+		}
+		orderCart.Payment_Method.CashOnDelivery = true
+
+		pulled := make(bson.A, 0, len(lines))
+		for _, line := range lines {
+			pulled = append(pulled, lineFilter(line.Product_ID, line.Variant_ID))
+		}
+		for _, issue := range issues {
+			if issue.Kind == constants.CartProductRemoved {
+				pulled = append(pulled, lineFilter(issue.Product_ID, issue.Variant_ID))
+			}
+		}
+
+		filter := bson.D{{Key: "_id", Value: user_id}}
+		update := bson.D{
+			{Key: "$push", Value: bson.D{{Key: "orders", Value: orderCart}}},
+			{Key: "$pull", Value: bson.D{{Key: "usercart", Value: bson.M{"$or": pulled}}}},
+			{Key: "$unset", Value: bson.D{{Key: "cart_updated_at", Value: ""}, {Key: "cart_reminded_at", Value: ""}}},
+		}
+
+		err = d.reserveStock(ctx, lines)
+		if err != nil {
+			return err
+		}
+
+		_, err = d.userCollection.UpdateOne(ctx, filter, update)
+		if err != nil {
+			d.releaseStock(ctx, lines)
+			return ErrCantBuyCartItem
+		}
+
+		return d.recordEvent(ctx, orderPlaced(user_id, orderCart))
+	})
 	if err != nil {
-		return ErrCantBuyCartItem
-	}
-
-	var totalPriceDoc []bson.M
-
-	if err = totalPriceCursor.All(ctx, &totalPriceDoc); err != nil {
-		return ErrCantBuyCartItem
-	}
-
-	var total_price int32
-
-	for _, user_item := range totalPriceDoc {
-		total_price = user_item["total_price"].(int32)
+		return issues, current, err
 	}
 
-	orderCart.Price = int(total_price)
-
-	filter := bson.D{{Key: "_id", Value: user_id}}
-	update := bson.D{{Key: "$push", Value: bson.D{{Key: "orders", Value: orderCart}}}}
-
-	_, err = d.userCollection.UpdateOne(ctx, filter, update)
-	if err != nil {
-		return ErrCantBuyCartItem
-	}
+	metrics.OrderPlaced(metrics.CartCheckout, orderCart.Price)
+	_ = d.recordCartConversion(ctx, user_id, orderCart)
 
-	err = d.userCollection.FindOne(ctx, bson.D{{Key: "_id", Value: user_id}}).Decode(&getCartItems)
-	if err != nil {
-		return ErrCantBuyCartItem
-	}
+	return issues, current, nil
+}
 
-	filter2 := bson.D{{Key: "_id", Value: user_id}}
-	update2 := bson.M{"$push": bson.M{"orders.$[].order_list": bson.M{"$each": getCartItems.UserCart}}}
+// CartVersion identifies the order a cart would place: its lines with their
+// current prices. Checkout compares it with the version the user reviewed.
+func CartVersion(lines []models.ProductUser) string {
 
-	_, err = d.userCollection.UpdateOne(ctx, filter2, update2)
-	if err != nil {
-		return ErrCantBuyCartItem
+	hash := sha256.New()
+	for _, line := range lines {
+		fmt.Fprintf(hash, "%s:%d\n", lineKey(line), line.Price)
 	}
 
-	usercart_empty := make([]models.ProductUser, 0)
-	filtered := bson.D{{Key: "_id", Value: user_id}}
-	updated := bson.D{{Key: "$set", Value: bson.D{{Key: "usercart", Value: usercart_empty}}}}
+	return hex.EncodeToString(hash.Sum(nil))[:32]
+}
 
-	_, err = d.userCollection.UpdateOne(ctx, filtered, updated)
-	if err != nil {
-		return ErrCantBuyCartItem
+// lineFilter matches the cart line of the product, or of one of its variants
+func lineFilter(product_id primitive.ObjectID, variant_id *primitive.ObjectID) bson.M {
+	if variant_id == nil {
+		return bson.M{"_id": product_id, "variant_id": bson.M{"$exists": false}}
 	}
-
-	return nil
+	return bson.M{"_id": product_id, "variant_id": *variant_id}
 }
 
-func (d *DBClient) InstantBuyer(ctx context.Context, product_id, user_id primitive.ObjectID) error {
+func (d *DBClient) InstantBuyer(ctx context.Context, product_id primitive.ObjectID, variant_id *primitive.ObjectID, user_id primitive.ObjectID) error {
 
-	var product_details models.ProductUser
 	var orders_detail models.Order
 
 	orders_detail.Order_ID = primitive.NewObjectID()
 	orders_detail.Ordered_At = time.Now()
-	orders_detail.Order_Cart = make([]models.ProductUser, 0)
 	orders_detail.Payment_Method.CashOnDelivery = true
+	orders_detail.Status = constants.OrderPlaced
+
+	product, err := d.GetProduct(ctx, product_id)
+	if err != nil {
+		return err
+	}
 
-	err := d.productCollection.FindOne(ctx, bson.D{{Key: "_id", Value: product_id}}).Decode(&product_details)
+	product_details, err := cartLine(product, variant_id)
 	if err != nil {
-		return ErrCantDoInstantBuyer
+		return err
 	}
 
+	orders_detail.Order_Cart = []models.ProductUser{product_details}
 	orders_detail.Price = product_details.Price
 	filter := bson.D{{Key: "_id", Value: user_id}}
 	update := bson.D{{Key: "$push", Value: bson.D{{Key: "orders", Value: orders_detail}}}}
 
-	_, err = d.userCollection.UpdateOne(ctx, filter, update)
-	if err != nil {
-		return ErrCantDoInstantBuyer
-	}
+	err = d.WithTransaction(ctx, func(ctx context.Context) error {
+		err := d.reserveStock(ctx, orders_detail.Order_Cart)
+		if err != nil {
+			return err
+		}
 
-	filter2 := bson.D{{Key: "_id", Value: user_id}}
-	update2 := bson.M{"$push": bson.M{"orders.$[].order_list": product_details}}
+		_, err = d.userCollection.UpdateOne(ctx, filter, update)
+		if err != nil {
+			d.releaseStock(ctx, orders_detail.Order_Cart)
+			return ErrCantDoInstantBuyer
+		}
 
-	_, err = d.userCollection.UpdateOne(ctx, filter2, update2)
+		return d.recordEvent(ctx, orderPlaced(user_id, orders_detail))
+	})
 	if err != nil {
-		return ErrCantDoInstantBuyer
+		return err
 	}
 
+	metrics.OrderPlaced(metrics.InstantBuy, orders_detail.Price)
 	return nil
 }
+
+func orderPlaced(user_id primitive.ObjectID, order models.Order) events.OrderPlaced {
+	return events.OrderPlaced{
+		User_ID:        user_id,
+		Order_ID:       order.Order_ID,
+		Items:          order.Order_Cart,
+		Total_Price:    order.Price,
+		Payment_Method: order.Payment_Method,
+		Ordered_At:     order.Ordered_At,
+	}
+}
@@ -0,0 +1,376 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/mayuka-c/e-commerce/models"
+)
+
+var (
+	ErrCantFindCategory    = errors.New("can't find the category")
+	ErrCategorySlugTaken   = errors.New("category slug is already in use")
+	ErrCategoryHasChildren = errors.New("category has subcategories, so cannot delete it")
+	ErrCategoryCycle       = errors.New("category cannot be moved under itself or its subcategories")
+	ErrInvalidSlug         = errors.New("category slug may only contain lowercase letters, digits and hyphens")
+)
+
+// Slugify turns a display name into a URL safe slug, e.g. "Men's Shoes" -> "mens-shoes"
+func Slugify(name string) string {
+
+	stripMarks := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	plain, _, err := transform.String(stripMarks, name)
+	if err != nil {
+		plain = name
+	}
+
+	var b strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(plain) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+			hyphen = false
+		case r == '\'':
+		default:
+			if b.Len() > 0 && !hyphen {
+				b.WriteRune('-')
+				hyphen = true
+			}
+		}
+	}
+
+	return strings.TrimSuffix(b.String(), "-")
+}
+
+func validSlug(slug string) bool {
+	return slug != "" && Slugify(slug) == slug
+}
+
+func (d *DBClient) AddCategory(ctx context.Context, category models.Category) (models.Category, error) {
+
+	if category.Slug == "" {
+		category.Slug = Slugify(*category.Name)
+	}
+	if !validSlug(category.Slug) {
+		return category, ErrInvalidSlug
+	}
+
+	var err error
+	category.Ancestors, err = d.categoryAncestors(ctx, category.Parent_ID)
+	if err != nil {
+		return category, err
+	}
+
+	category.Category_ID = primitive.NewObjectID()
+	category.Created_At = time.Now()
+	category.Updated_At = category.Created_At
+
+	// the unique slug index catches concurrent adds of the same slug
+	_, err = d.categoryCollection.InsertOne(ctx, category)
+	if mongo.IsDuplicateKeyError(err) {
+		return category, ErrCategorySlugTaken
+	}
+	if err != nil {
+		return category, err
+	}
+
+	return category, nil
+}
+
+func (d *DBClient) GetCategories(ctx context.Context) ([]models.Category, error) {
+
+	categories := make([]models.Category, 0)
+
+	cursor, err := d.categoryCollection.Find(ctx, bson.D{})
+	if err != nil {
+		return categories, err
+	}
+
+	err = cursor.All(ctx, &categories)
+	if err != nil {
+		return categories, err
+	}
+
+	return categories, nil
+}
+
+func (d *DBClient) GetCategory(ctx context.Context, category_id primitive.ObjectID) (models.Category, error) {
+
+	var category models.Category
+
+	err := d.categoryCollection.FindOne(ctx, bson.D{{Key: "_id", Value: category_id}}).Decode(&category)
+	if err != nil {
+		return category, ErrCantFindCategory
+	}
+
+	return category, nil
+}
+
+func (d *DBClient) GetCategoryBySlug(ctx context.Context, slug string) (models.Category, error) {
+
+	var category models.Category
+
+	err := d.categoryCollection.FindOne(ctx, bson.D{{Key: "slug", Value: slug}}).Decode(&category)
+	if err != nil {
+		return category, ErrCantFindCategory
+	}
+
+	return category, nil
+}
+
+// CategoryTreeIDs returns the category together with all of its subcategories
+func (d *DBClient) CategoryTreeIDs(ctx context.Context, category_id primitive.ObjectID) ([]primitive.ObjectID, error) {
+
+	ids := []primitive.ObjectID{category_id}
+
+	cursor, err := d.categoryCollection.Find(ctx, bson.M{"ancestors": category_id})
+	if err != nil {
+		return ids, err
+	}
+
+	var descendants []models.Category
+	if err = cursor.All(ctx, &descendants); err != nil {
+		return ids, err
+	}
+
+	for _, category := range descendants {
+		ids = append(ids, category.Category_ID)
+	}
+
+	return ids, nil
+}
+
+// UpdateCategory renames and/or moves the category. Moving a category rewrites
+// the ancestors of its whole subtree.
+func (d *DBClient) UpdateCategory(ctx context.Context, category_id primitive.ObjectID, update models.Category) (models.Category, error) {
+
+	current, err := d.GetCategory(ctx, category_id)
+	if err != nil {
+		return current, err
+	}
+
+	if update.Slug == "" {
+		update.Slug = Slugify(*update.Name)
+	}
+	if !validSlug(update.Slug) {
+		return current, ErrInvalidSlug
+	}
+
+	ancestors, err := d.categoryAncestors(ctx, update.Parent_ID)
+	if err != nil {
+		return current, err
+	}
+	for _, ancestor := range ancestors {
+		if ancestor == category_id {
+			return current, ErrCategoryCycle
+		}
+	}
+	if update.Parent_ID != nil && *update.Parent_ID == category_id {
+		return current, ErrCategoryCycle
+	}
+
+	current.Name = update.Name
+	current.Slug = update.Slug
+	current.Parent_ID = update.Parent_ID
+	current.Updated_At = time.Now()
+
+	moved := !sameIDs(current.Ancestors, ancestors)
+	current.Ancestors = ancestors
+
+	filter := bson.D{{Key: "_id", Value: category_id}}
+	set := bson.D{{Key: "$set", Value: bson.D{
+		{Key: "name", Value: current.Name},
+		{Key: "slug", Value: current.Slug},
+		{Key: "parent_id", Value: current.Parent_ID},
+		{Key: "ancestors", Value: current.Ancestors},
+		{Key: "updated_at", Value: current.Updated_At},
+	}}}
+
+	_, err = d.categoryCollection.UpdateOne(ctx, filter, set)
+	if mongo.IsDuplicateKeyError(err) {
+		return current, ErrCategorySlugTaken
+	}
+	if err != nil {
+		return current, err
+	}
+
+	if moved {
+		err = d.rebaseSubtree(ctx, category_id, append(ancestors, category_id))
+		if err != nil {
+			return current, err
+		}
+	}
+
+	return current, nil
+}
+
+func (d *DBClient) DeleteCategory(ctx context.Context, category_id primitive.ObjectID) error {
+
+	count, err := d.categoryCollection.CountDocuments(ctx, bson.M{"parent_id": category_id})
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrCategoryHasChildren
+	}
+
+	result, err := d.categoryCollection.DeleteOne(ctx, bson.D{{Key: "_id", Value: category_id}})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrCantFindCategory
+	}
+
+	_, err = d.productCollection.UpdateMany(ctx, bson.M{"category_ids": category_id}, bson.M{"$pull": bson.M{"category_ids": category_id}})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (d *DBClient) AssignProductCategories(ctx context.Context, product_id primitive.ObjectID, category_ids []primitive.ObjectID) error {
+
+	err := d.CategoriesExist(ctx, category_ids)
+	if err != nil {
+		return err
+	}
+
+	// products created before categories existed may hold a null list, which $addToSet rejects
+	_, err = d.productCollection.UpdateOne(ctx, bson.M{"_id": product_id, "category_ids": nil}, bson.M{"$set": bson.M{"category_ids": bson.A{}}})
+	if err != nil {
+		return err
+	}
+
+	filter := bson.D{{Key: "_id", Value: product_id}}
+	update := bson.M{"$addToSet": bson.M{"category_ids": bson.M{"$each": category_ids}}}
+
+	result, err := d.productCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrCantFindProduct
+	}
+
+	return nil
+}
+
+// CategoriesExist returns ErrCantFindCategory unless every given category exists
+func (d *DBClient) CategoriesExist(ctx context.Context, category_ids []primitive.ObjectID) error {
+
+	unique := uniqueIDs(category_ids)
+	if len(unique) == 0 {
+		return nil
+	}
+
+	count, err := d.categoryCollection.CountDocuments(ctx, bson.M{"_id": bson.M{"$in": unique}})
+	if err != nil {
+		return err
+	}
+	if int(count) != len(unique) {
+		return ErrCantFindCategory
+	}
+
+	return nil
+}
+
+func (d *DBClient) UnassignProductCategory(ctx context.Context, product_id, category_id primitive.ObjectID) error {
+
+	filter := bson.D{{Key: "_id", Value: product_id}}
+	update := bson.M{"$pull": bson.M{"category_ids": category_id}}
+
+	result, err := d.productCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrCantFindProduct
+	}
+
+	return nil
+}
+
+func (d *DBClient) categoryAncestors(ctx context.Context, parent_id *primitive.ObjectID) ([]primitive.ObjectID, error) {
+
+	if parent_id == nil {
+		return make([]primitive.ObjectID, 0), nil
+	}
+
+	parent, err := d.GetCategory(ctx, *parent_id)
+	if err != nil {
+		return nil, fmt.Errorf("parent: %w", err)
+	}
+
+	return append(parent.Ancestors, parent.Category_ID), nil
+}
+
+// rebaseSubtree replaces everything up to and including root in the ancestors
+// of every descendant of root with prefix
+func (d *DBClient) rebaseSubtree(ctx context.Context, root primitive.ObjectID, prefix []primitive.ObjectID) error {
+
+	cursor, err := d.categoryCollection.Find(ctx, bson.M{"ancestors": root})
+	if err != nil {
+		return err
+	}
+
+	var descendants []models.Category
+	if err = cursor.All(ctx, &descendants); err != nil {
+		return err
+	}
+
+	for _, category := range descendants {
+		var rest []primitive.ObjectID
+		for i, ancestor := range category.Ancestors {
+			if ancestor == root {
+				rest = category.Ancestors[i+1:]
+				break
+			}
+		}
+
+		ancestors := append(append(make([]primitive.ObjectID, 0, len(prefix)+len(rest)), prefix...), rest...)
+		_, err = d.categoryCollection.UpdateOne(ctx, bson.D{{Key: "_id", Value: category.Category_ID}}, bson.M{"$set": bson.M{"ancestors": ancestors}})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func sameIDs(a, b []primitive.ObjectID) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func uniqueIDs(ids []primitive.ObjectID) []primitive.ObjectID {
+	seen := make(map[primitive.ObjectID]bool, len(ids))
+	unique := make([]primitive.ObjectID, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
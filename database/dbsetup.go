@@ -2,8 +2,11 @@ package database
 
 import (
 	"context"
+	"fmt"
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 
@@ -11,43 +14,341 @@ import (
 
 	"github.com/mayuka-c/e-commerce/config"
 	"github.com/mayuka-c/e-commerce/constants"
+	"github.com/mayuka-c/e-commerce/metrics"
+	"github.com/mayuka-c/e-commerce/telemetry"
 )
 
 type DBClient struct {
-	client            *mongo.Client
-	userCollection    *mongo.Collection
-	productCollection *mongo.Collection
+	client                    *mongo.Client
+	transactions              bool
+	userCollection            *mongo.Collection
+	productCollection         *mongo.Collection
+	shippingMethodCollection  *mongo.Collection
+	shipmentCollection        *mongo.Collection
+	categoryCollection        *mongo.Collection
+	reviewCollection          *mongo.Collection
+	wishlistCollection        *mongo.Collection
+	guestCartCollection       *mongo.Collection
+	cartReminderCollection    *mongo.Collection
+	jobCollection             *mongo.Collection
+	recurringJobCollection    *mongo.Collection
+	outboxCollection          *mongo.Collection
+	webhookCollection         *mongo.Collection
+	webhookDeliveryCollection *mongo.Collection
 }
 
-func DBSet(dbConfig config.DBConfig) *DBClient {
+// DBSet connects to MongoDB and makes sure the indexes exist. A server that
+// is not reachable yet, e.g. while the containers start together, is retried
+// with backoff for up to dbConfig.ConnectTimeout.
+func DBSet(ctx context.Context, dbConfig config.DBConfig) (*DBClient, error) {
 
-	mongoClient, err := mongo.NewClient(options.Client().ApplyURI("mongodb://" + dbConfig.DB_URL))
+	mongoClient, err := mongo.NewClient(options.Client().ApplyURI(dbConfig.DB_URL).SetMonitor(commandMonitors(metrics.MongoMonitor(), telemetry.MongoMonitor())))
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
-	defer cancel()
-
+	// Connect only starts the background monitoring, Ping does the round trip
 	err = mongoClient.Connect(ctx)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
-	err = mongoClient.Ping(context.TODO(), nil)
+	err = waitForServer(ctx, mongoClient, dbConfig.ConnectTimeout)
 	if err != nil {
-		panic(err)
+		_ = mongoClient.Disconnect(context.Background())
+		return nil, err
 	}
 
 	log.Println("Successfully connected to mongoDB")
 
-	userCollection := mongoClient.Database("Ecommerce").Collection(constants.UserCollectionName)
-	productCollection := mongoClient.Database("Ecommerce").Collection(constants.ProductCollectionName)
+	db := mongoClient.Database(dbConfig.DB_Name)
+	userCollection := db.Collection(constants.UserCollectionName)
+	productCollection := db.Collection(constants.ProductCollectionName)
+	shippingMethodCollection := db.Collection(constants.ShippingMethodCollectionName)
+	shipmentCollection := db.Collection(constants.ShipmentCollectionName)
+	categoryCollection := db.Collection(constants.CategoryCollectionName)
+	reviewCollection := db.Collection(constants.ReviewCollectionName)
+	wishlistCollection := db.Collection(constants.WishlistCollectionName)
+	guestCartCollection := db.Collection(constants.GuestCartCollectionName)
+	cartReminderCollection := db.Collection(constants.CartReminderCollectionName)
+	jobCollection := db.Collection(constants.JobCollectionName)
+	recurringJobCollection := db.Collection(constants.RecurringJobCollectionName)
+	outboxCollection := db.Collection(constants.OutboxCollectionName)
+	webhookCollection := db.Collection(constants.WebhookCollectionName)
+	webhookDeliveryCollection := db.Collection(constants.WebhookDeliveryCollectionName)
+
+	dbClient := &DBClient{
+		client:                    mongoClient,
+		transactions:              supportsTransactions(mongoClient),
+		userCollection:            userCollection,
+		productCollection:         productCollection,
+		shippingMethodCollection:  shippingMethodCollection,
+		shipmentCollection:        shipmentCollection,
+		categoryCollection:        categoryCollection,
+		reviewCollection:          reviewCollection,
+		wishlistCollection:        wishlistCollection,
+		guestCartCollection:       guestCartCollection,
+		cartReminderCollection:    cartReminderCollection,
+		jobCollection:             jobCollection,
+		recurringJobCollection:    recurringJobCollection,
+		outboxCollection:          outboxCollection,
+		webhookCollection:         webhookCollection,
+		webhookDeliveryCollection: webhookDeliveryCollection,
+	}
+
+	if !dbClient.transactions {
+		log.Warn("MongoDB is running standalone, so multi-document transactions are off and outbox events are written after the change they describe")
+	}
+
+	err = dbClient.EnsureIndexes(ctx)
+	if err != nil {
+		_ = mongoClient.Disconnect(context.Background())
+		return nil, err
+	}
+
+	return dbClient, nil
+}
+
+// waitForServer pings the server until it answers, waiting twice as long
+// after every failed attempt, and gives up once timeout has passed
+func waitForServer(ctx context.Context, client *mongo.Client, timeout time.Duration) error {
+
+	deadline := time.Now().Add(timeout)
+	delay := 500 * time.Millisecond
+
+	for attempt := 1; ; attempt++ {
+		pingCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+		err := client.Ping(pingCtx, nil)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if time.Now().Add(delay).After(deadline) {
+			return fmt.Errorf("mongodb not reachable after %d attempts: %w", attempt, err)
+		}
+		log.WithFields(log.Fields{"attempt": attempt, "retry_in": delay.String()}).Warnf("mongodb not reachable: %v", err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+
+		delay *= 2
+		if delay > 10*time.Second {
+			delay = 10 * time.Second
+		}
+	}
+}
+
+// Ping checks that the server answers, for the readiness check
+func (d *DBClient) Ping(ctx context.Context) error {
+	return d.client.Ping(ctx, nil)
+}
+
+// Disconnect closes the connections once in-flight operations are done
+func (d *DBClient) Disconnect(ctx context.Context) error {
+	return d.client.Disconnect(ctx)
+}
+
+// EnsureIndexes creates the indexes the queries rely on. Creating an index that
+// already exists is a no-op.
+func (d *DBClient) EnsureIndexes(ctx context.Context) error {
+
+	productIndexes := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "price", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "rating", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "product_name", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "category_ids", Value: 1}}},
+		{
+			// products without variants have no sku to index
+			Keys:    bson.D{{Key: "variants.sku", Value: 1}},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"variants.sku": bson.M{"$exists": true}}),
+		},
+		{
+			// text indexes are case and diacritic insensitive, "none" keeps
+			// brand names and short words from being stemmed or dropped
+			Keys:    bson.D{{Key: "product_name", Value: "text"}},
+			Options: options.Index().SetName("product_text").SetDefaultLanguage("none"),
+		},
+	}
+
+	_, err := d.productCollection.Indexes().CreateMany(ctx, productIndexes)
+	if err != nil {
+		return err
+	}
+
+	categoryIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "slug", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		// subtree lookups and moves match on a single ancestor
+		{Keys: bson.D{{Key: "ancestors", Value: 1}}},
+	}
+
+	_, err = d.categoryCollection.Indexes().CreateMany(ctx, categoryIndexes)
+	if err != nil {
+		return err
+	}
+
+	reviewIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "product_id", Value: 1}, {Key: "user_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "product_id", Value: 1}, {Key: "status", Value: 1}, {Key: "helpful_votes", Value: -1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: 1}}},
+	}
+
+	_, err = d.reviewCollection.Indexes().CreateMany(ctx, reviewIndexes)
+	if err != nil {
+		return err
+	}
+
+	wishlistIndexes := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "kind", Value: 1}}},
+		{
+			// a user has one saved for later list, which the first save
+			// upserts; named lists may share the kind
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "kind", Value: 1}},
+			Options: options.Index().SetName("one_saved_for_later").SetUnique(true).SetPartialFilterExpression(bson.M{"kind": constants.SavedForLaterKind}),
+		},
+		{
+			// CreateWishlist relies on it for unique names per user
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "name", Value: 1}},
+			Options: options.Index().SetName("unique_wishlist_name").SetUnique(true).SetPartialFilterExpression(bson.M{"kind": constants.WishlistKind}),
+		},
+		{
+			Keys:    bson.D{{Key: "share_token", Value: 1}},
+			Options: options.Index().SetUnique(true).SetSparse(true),
+		},
+	}
+
+	_, err = d.wishlistCollection.Indexes().CreateMany(ctx, wishlistIndexes)
+	if err != nil {
+		return err
+	}
+
+	guestCartIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "token_hash", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+
+	_, err = d.guestCartCollection.Indexes().CreateMany(ctx, guestCartIndexes)
+	if err != nil {
+		return err
+	}
+
+	userIndexes := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "cart_updated_at", Value: 1}}},
+		{
+			Keys:    bson.D{{Key: "unsubscribe_token", Value: 1}},
+			Options: options.Index().SetUnique(true).SetSparse(true),
+		},
+		{
+			Keys:    bson.D{{Key: "password_reset.token_hash", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
+	}
+
+	_, err = d.userCollection.Indexes().CreateMany(ctx, userIndexes)
+	if err != nil {
+		return err
+	}
+
+	cartReminderIndexes := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "sent_at", Value: -1}}},
+		{Keys: bson.D{{Key: "sent_at", Value: 1}}},
+	}
+
+	_, err = d.cartReminderCollection.Indexes().CreateMany(ctx, cartReminderIndexes)
+	if err != nil {
+		return err
+	}
+
+	jobIndexes := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "run_at", Value: 1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "locked_until", Value: 1}}},
+		{Keys: bson.D{{Key: "kind", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "dedupe_key", Value: 1}}, Options: options.Index().SetUnique(true).SetSparse(true)},
+		{
+			// only succeeded jobs get finished_at, failed and dead ones stay
+			// around for inspection
+			Keys:    bson.D{{Key: "finished_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(FinishedJobTTL.Seconds())),
+		},
+	}
+
+	_, err = d.jobCollection.Indexes().CreateMany(ctx, jobIndexes)
+	if err != nil {
+		return err
+	}
+
+	outboxIndexes := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "published_at", Value: 1}, {Key: "next_attempt_at", Value: 1}}},
+		{
+			// published_at is only set once every sink took the event
+			Keys:    bson.D{{Key: "published_at", Value: 1}},
+			Options: options.Index().SetName("published_ttl").SetExpireAfterSeconds(int32(PublishedEventTTL.Seconds())),
+		},
+	}
+
+	_, err = d.outboxCollection.Indexes().CreateMany(ctx, outboxIndexes)
+	if err != nil {
+		return err
+	}
+
+	_, err = d.webhookCollection.Indexes().CreateOne(ctx, mongo.IndexModel{Keys: bson.D{{Key: "active", Value: 1}, {Key: "events", Value: 1}}})
+	if err != nil {
+		return err
+	}
+
+	webhookDeliveryIndexes := []mongo.IndexModel{
+		{
+			// an event redelivered by the outbox must not reach an endpoint twice
+			Keys:    bson.D{{Key: "endpoint_id", Value: 1}, {Key: "event_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: -1}}},
+	}
+
+	_, err = d.webhookDeliveryCollection.Indexes().CreateMany(ctx, webhookDeliveryIndexes)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
 
-	return &DBClient{
-		client:            mongoClient,
-		userCollection:    userCollection,
-		productCollection: productCollection,
+// commandMonitors passes the command events of the driver on to every monitor
+func commandMonitors(monitors ...*event.CommandMonitor) *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, started *event.CommandStartedEvent) {
+			for _, monitor := range monitors {
+				monitor.Started(ctx, started)
+			}
+		},
+		Succeeded: func(ctx context.Context, succeeded *event.CommandSucceededEvent) {
+			for _, monitor := range monitors {
+				monitor.Succeeded(ctx, succeeded)
+			}
+		},
+		Failed: func(ctx context.Context, failed *event.CommandFailedEvent) {
+			for _, monitor := range monitors {
+				monitor.Failed(ctx, failed)
+			}
+		},
 	}
 }
 
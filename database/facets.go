@@ -0,0 +1,336 @@
+package database
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/mayuka-c/e-commerce/constants"
+	"github.com/mayuka-c/e-commerce/models"
+)
+
+// PriceBand is one bucket of the price facet. Min is inclusive, Max is
+// exclusive and zero means unbounded.
+type PriceBand struct {
+	Name string
+	Min  uint64
+	Max  uint64
+}
+
+var PriceBands = []PriceBand{
+	{Name: "0-500", Min: 0, Max: 500},
+	{Name: "500-1000", Min: 500, Max: 1000},
+	{Name: "1000-5000", Min: 1000, Max: 5000},
+	{Name: "5000-", Min: 5000},
+}
+
+// RatingThresholds are the "N stars & up" options of the rating facet
+var RatingThresholds = []uint8{4, 3, 2, 1}
+
+const (
+	categoryFacet = "category"
+	priceFacet    = "price"
+	ratingFacet   = "rating"
+)
+
+func ValidPriceBand(name string) bool {
+	_, ok := priceBand(name)
+	return ok
+}
+
+func priceBand(name string) (PriceBand, bool) {
+	for _, band := range PriceBands {
+		if band.Name == name {
+			return band, true
+		}
+	}
+	return PriceBand{}, false
+}
+
+// facetClauses returns the facet selections of the query as match clauses,
+// leaving out the facet named skip
+func facetClauses(query models.ProductQuery, skip string) bson.A {
+
+	clauses := bson.A{}
+
+	if skip != categoryFacet && len(query.Category_IDs) > 0 {
+		clauses = append(clauses, bson.M{"category_ids": bson.M{"$in": query.Category_IDs}})
+	}
+
+	if skip != priceFacet && len(query.Price_Bands) > 0 {
+		bands := bson.A{}
+		for _, name := range query.Price_Bands {
+			band, ok := priceBand(name)
+			if !ok {
+				continue
+			}
+			price := bson.M{"$gte": band.Min}
+			if band.Max > 0 {
+				price["$lt"] = band.Max
+			}
+			bands = append(bands, bson.M{"price": price})
+		}
+		if len(bands) > 0 {
+			clauses = append(clauses, bson.M{"$or": bands})
+		}
+	}
+
+	if skip != ratingFacet && len(query.Ratings) > 0 {
+		lowest := query.Ratings[0]
+		for _, rating := range query.Ratings[1:] {
+			if rating < lowest {
+				lowest = rating
+			}
+		}
+		clauses = append(clauses, bson.M{"rating": bson.M{"$gte": lowest}})
+	}
+
+	return clauses
+}
+
+// withClauses ANDs the clauses onto the base filter, keeping any $text
+// condition first as MongoDB requires
+func withClauses(base bson.D, clauses bson.A) bson.D {
+
+	if len(clauses) == 0 {
+		return base
+	}
+
+	filter := make(bson.D, 0, len(base)+1)
+	filter = append(filter, base...)
+	return append(filter, bson.E{Key: "$and", Value: clauses})
+}
+
+func matchStage(filter bson.D) bson.D {
+	return bson.D{{Key: "$match", Value: filter}}
+}
+
+type facetResult struct {
+	Items []models.Product `bson:"items"`
+	Total []struct {
+		Count int64 `bson:"count"`
+	} `bson:"total"`
+	Categories []struct {
+		ID    primitive.ObjectID `bson:"_id"`
+		Count int64              `bson:"count"`
+	} `bson:"categories"`
+	Price []struct {
+		Band  string `bson:"_id"`
+		Count int64  `bson:"count"`
+	} `bson:"price"`
+	Rating []bson.M `bson:"rating"`
+}
+
+// facetProducts runs the listing and every facet count in a single $facet
+// aggregation over the products matching the base filter
+func (d *DBClient) facetProducts(ctx context.Context, base bson.D, query models.ProductQuery) ([]models.Product, int64, *models.ProductFacets, error) {
+
+	sort, ok := productSorts[query.Sort]
+	if !ok {
+		return make([]models.Product, 0), 0, nil, ErrInvalidSort
+	}
+	skip, err := pageSkip(query.Page, query.Limit)
+	if err != nil {
+		return make([]models.Product, 0), 0, nil, err
+	}
+
+	pipeline := mongo.Pipeline{matchStage(base)}
+	if isTextSearch(base) {
+		pipeline = append(pipeline, bson.D{{Key: "$addFields", Value: bson.M{"score": bson.M{"$meta": "textScore"}}}})
+		if query.Sort == "" {
+			sort = bson.D{{Key: "score", Value: -1}, {Key: "_id", Value: 1}}
+		}
+	}
+
+	all := matchStage(withClauses(bson.D{}, facetClauses(query, "")))
+
+	categoryCounts := bson.A{
+		matchStage(withClauses(bson.D{}, facetClauses(query, categoryFacet))),
+		bson.D{{Key: "$lookup", Value: bson.M{
+			"from":         constants.CategoryCollectionName,
+			"localField":   "category_ids",
+			"foreignField": "_id",
+			"as":           "facet_categories",
+		}}},
+		// a product counts once towards each assigned category and each of their ancestors
+		bson.D{{Key: "$project", Value: bson.M{"ids": bson.M{"$setUnion": bson.A{
+			"$category_ids",
+			bson.M{"$reduce": bson.M{
+				"input":        "$facet_categories.ancestors",
+				"initialValue": bson.A{},
+				"in":           bson.M{"$setUnion": bson.A{"$$value", "$$this"}},
+			}},
+		}}}}},
+		bson.D{{Key: "$unwind", Value: "$ids"}},
+		bson.D{{Key: "$group", Value: bson.M{"_id": "$ids", "count": bson.M{"$sum": 1}}}},
+	}
+
+	bandBranches := bson.A{}
+	for _, band := range PriceBands {
+		condition := bson.A{bson.M{"$gte": bson.A{"$price", band.Min}}}
+		if band.Max > 0 {
+			condition = append(condition, bson.M{"$lt": bson.A{"$price", band.Max}})
+		}
+		bandBranches = append(bandBranches, bson.M{"case": bson.M{"$and": condition}, "then": band.Name})
+	}
+	priceCounts := bson.A{
+		matchStage(withClauses(bson.D{}, facetClauses(query, priceFacet))),
+		bson.D{{Key: "$group", Value: bson.M{
+			"_id":   bson.M{"$switch": bson.M{"branches": bandBranches, "default": ""}},
+			"count": bson.M{"$sum": 1},
+		}}},
+	}
+
+	ratingSums := bson.M{"_id": nil}
+	for _, threshold := range RatingThresholds {
+		ratingSums[ratingKey(threshold)] = bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$gte": bson.A{"$rating", threshold}}, 1, 0}}}
+	}
+	ratingCounts := bson.A{
+		matchStage(withClauses(bson.D{}, facetClauses(query, ratingFacet))),
+		bson.D{{Key: "$group", Value: ratingSums}},
+	}
+
+	pipeline = append(pipeline, bson.D{{Key: "$facet", Value: bson.D{
+		{Key: "items", Value: bson.A{
+			all,
+			bson.D{{Key: "$sort", Value: sort}},
+			bson.D{{Key: "$skip", Value: skip}},
+			bson.D{{Key: "$limit", Value: query.Limit}},
+		}},
+		{Key: "total", Value: bson.A{all, bson.D{{Key: "$count", Value: "count"}}}},
+		{Key: "categories", Value: categoryCounts},
+		{Key: "price", Value: priceCounts},
+		{Key: "rating", Value: ratingCounts},
+	}}})
+
+	cursor, err := d.productCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return make([]models.Product, 0), 0, nil, err
+	}
+
+	var results []facetResult
+	if err = cursor.All(ctx, &results); err != nil {
+		return make([]models.Product, 0), 0, nil, err
+	}
+
+	result := facetResult{}
+	if len(results) > 0 {
+		result = results[0]
+	}
+
+	products := result.Items
+	if products == nil {
+		products = make([]models.Product, 0)
+	}
+
+	var total int64
+	if len(result.Total) > 0 {
+		total = result.Total[0].Count
+	}
+
+	facets := &models.ProductFacets{
+		Categories: make([]models.CategoryFacet, 0, len(result.Categories)),
+		Price:      make([]models.PriceFacet, 0, len(PriceBands)),
+		Rating:     make([]models.RatingFacet, 0, len(RatingThresholds)),
+	}
+
+	categories, err := d.GetCategories(ctx)
+	if err != nil {
+		return products, total, nil, err
+	}
+	byID := make(map[primitive.ObjectID]models.Category, len(categories))
+	for _, category := range categories {
+		byID[category.Category_ID] = category
+	}
+	for _, count := range result.Categories {
+		category, ok := byID[count.ID]
+		if !ok {
+			continue
+		}
+		facets.Categories = append(facets.Categories, models.CategoryFacet{
+			Category_ID: category.Category_ID,
+			Name:        *category.Name,
+			Slug:        category.Slug,
+			Parent_ID:   category.Parent_ID,
+			Count:       count.Count,
+			Selected:    containsID(query.Selected_Categories, category.Category_ID),
+		})
+	}
+
+	bandCounts := make(map[string]int64, len(result.Price))
+	for _, count := range result.Price {
+		bandCounts[count.Band] = count.Count
+	}
+	for _, band := range PriceBands {
+		facet := models.PriceFacet{
+			Band:     band.Name,
+			Min:      band.Min,
+			Count:    bandCounts[band.Name],
+			Selected: containsString(query.Price_Bands, band.Name),
+		}
+		if band.Max > 0 {
+			max := band.Max
+			facet.Max = &max
+		}
+		facets.Price = append(facets.Price, facet)
+	}
+
+	var ratingTotals bson.M
+	if len(result.Rating) > 0 {
+		ratingTotals = result.Rating[0]
+	}
+	for _, threshold := range RatingThresholds {
+		facets.Rating = append(facets.Rating, models.RatingFacet{
+			Min_Rating: threshold,
+			Count:      toInt64(ratingTotals[ratingKey(threshold)]),
+			Selected:   containsUint8(query.Ratings, threshold),
+		})
+	}
+
+	return products, total, facets, nil
+}
+
+func ratingKey(threshold uint8) string {
+	return "stars_" + string(rune('0'+threshold))
+}
+
+func toInt64(value interface{}) int64 {
+	switch v := value.(type) {
+	case int32:
+		return int64(v)
+	case int64:
+		return v
+	case float64:
+		return int64(v)
+	}
+	return 0
+}
+
+func containsID(ids []primitive.ObjectID, id primitive.ObjectID) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsUint8(values []uint8, value uint8) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
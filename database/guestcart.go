@@ -0,0 +1,213 @@
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/mayuka-c/e-commerce/events"
+	"github.com/mayuka-c/e-commerce/metrics"
+	"github.com/mayuka-c/e-commerce/models"
+)
+
+var (
+	ErrCantFindGuestCart = errors.New("guest cart not found or expired")
+)
+
+// GuestCartTTL is how long a guest cart survives without any activity
+const GuestCartTTL = 7 * 24 * time.Hour
+
+// CreateGuestCart starts an empty guest cart and returns its token. Only the
+// hash of the token is stored.
+func (d *DBClient) CreateGuestCart(ctx context.Context) (string, models.GuestCart, error) {
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", models.GuestCart{}, err
+	}
+	token := hex.EncodeToString(raw)
+
+	now := time.Now()
+	cart := models.GuestCart{
+		Cart_ID:    primitive.NewObjectID(),
+		Token_Hash: hashToken(token),
+		Items:      make([]models.ProductUser, 0),
+		Created_At: now,
+		Updated_At: now,
+		Expires_At: now.Add(GuestCartTTL),
+	}
+
+	_, err := d.guestCartCollection.InsertOne(ctx, cart)
+	if err != nil {
+		return "", cart, err
+	}
+
+	return token, cart, nil
+}
+
+// GetGuestCart returns the cart of the token and pushes its expiry back, since
+// looking at the cart counts as activity
+func (d *DBClient) GetGuestCart(ctx context.Context, token string) (models.GuestCart, error) {
+
+	return d.updateGuestCart(ctx, token, bson.M{})
+}
+
+func (d *DBClient) AddProductToGuestCart(ctx context.Context, token string, product_id primitive.ObjectID, variant_id *primitive.ObjectID) (models.GuestCart, error) {
+
+	product, err := d.GetProduct(ctx, product_id)
+	if err != nil {
+		return models.GuestCart{}, err
+	}
+
+	line, err := cartLine(product, variant_id)
+	if err != nil {
+		return models.GuestCart{}, err
+	}
+
+	cart, err := d.updateGuestCart(ctx, token, bson.M{"$push": bson.M{"items": line}})
+	if err != nil {
+		return cart, err
+	}
+
+	metrics.AddedToCart(metrics.GuestCart)
+	return cart, nil
+}
+
+func (d *DBClient) RemoveGuestCartItem(ctx context.Context, token string, product_id primitive.ObjectID, variant_id *primitive.ObjectID) (models.GuestCart, error) {
+
+	line := bson.M{"_id": product_id}
+	if variant_id != nil {
+		line["variant_id"] = *variant_id
+	}
+
+	return d.updateGuestCart(ctx, token, bson.M{"$pull": bson.M{"items": line}})
+}
+
+// MergeGuestCart moves the guest cart into the user's cart and returns the
+// merged user cart. Cart lines carry no quantity, so a guest line for a
+// product and variant the user cart already holds is dropped rather than
+// doubling the order; every other guest line is appended.
+//
+// Both carts are read, the user cart updated and the guest cart deleted in one
+// transaction, so a failed update keeps the guest's items.
+func (d *DBClient) MergeGuestCart(ctx context.Context, token string, user_id primitive.ObjectID) ([]models.ProductUser, error) {
+
+	var merged []models.ProductUser
+
+	err := d.WithTransaction(ctx, func(ctx context.Context) error {
+
+		var user models.User
+		err := d.userCollection.FindOne(ctx, bson.M{"_id": user_id}).Decode(&user)
+		if err != nil {
+			return err
+		}
+
+		var cart models.GuestCart
+		filter := bson.M{"token_hash": hashToken(token), "expires_at": bson.M{"$gt": time.Now()}}
+		err = d.guestCartCollection.FindOne(ctx, filter).Decode(&cart)
+		if err == mongo.ErrNoDocuments {
+			return ErrCantFindGuestCart
+		}
+		if err != nil {
+			return err
+		}
+
+		merged = mergeCartLines(user.UserCart, cart.Items)
+		added := merged[len(user.UserCart):]
+
+		if len(added) > 0 {
+			update := bson.D{
+				{Key: "$push", Value: bson.D{{Key: "usercart", Value: bson.D{{Key: "$each", Value: added}}}}},
+				{Key: "$set", Value: bson.D{{Key: "cart_updated_at", Value: time.Now()}}},
+			}
+			_, err = d.userCollection.UpdateOne(ctx, bson.D{{Key: "_id", Value: user_id}}, update)
+			if err != nil {
+				return ErrCantUpdateUser
+			}
+		}
+
+		// deleted last, so without transactions a failure leaves the guest
+		// cart to merge again
+		_, err = d.guestCartCollection.DeleteOne(ctx, bson.M{"_id": cart.Cart_ID})
+		if err != nil {
+			return err
+		}
+
+		for _, line := range added {
+			err = d.recordEvent(ctx, events.CartItemAdded{
+				User_ID:    user_id,
+				Product_ID: line.Product_ID,
+				Variant_ID: line.Variant_ID,
+				Price:      line.Price,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return merged, nil
+}
+
+// mergeCartLines appends the guest lines whose product and variant are not in
+// the user cart yet
+func mergeCartLines(userCart, guestItems []models.ProductUser) []models.ProductUser {
+
+	merged := append(make([]models.ProductUser, 0, len(userCart)+len(guestItems)), userCart...)
+
+	seen := make(map[string]bool, len(userCart)+len(guestItems))
+	for _, line := range userCart {
+		seen[lineKey(line)] = true
+	}
+
+	for _, line := range guestItems {
+		if seen[lineKey(line)] {
+			continue
+		}
+		seen[lineKey(line)] = true
+		merged = append(merged, line)
+	}
+
+	return merged
+}
+
+func lineKey(line models.ProductUser) string {
+	if line.Variant_ID == nil {
+		return line.Product_ID.Hex()
+	}
+	return line.Product_ID.Hex() + "/" + line.Variant_ID.Hex()
+}
+
+func (d *DBClient) updateGuestCart(ctx context.Context, token string, update bson.M) (models.GuestCart, error) {
+
+	var cart models.GuestCart
+
+	now := time.Now()
+	update["$set"] = bson.M{"updated_at": now, "expires_at": now.Add(GuestCartTTL)}
+
+	// the TTL monitor only runs once a minute, so expired carts are filtered out here too
+	filter := bson.M{"token_hash": hashToken(token), "expires_at": bson.M{"$gt": now}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	err := d.guestCartCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&cart)
+	if err == mongo.ErrNoDocuments {
+		return cart, ErrCantFindGuestCart
+	}
+	if err != nil {
+		return cart, err
+	}
+
+	return cart, nil
+}
@@ -0,0 +1,363 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/mayuka-c/e-commerce/constants"
+	"github.com/mayuka-c/e-commerce/models"
+)
+
+var (
+	ErrNoJob               = errors.New("no job is due")
+	ErrCantFindJob         = errors.New("job not found")
+	ErrJobExists           = errors.New("a job with the same dedupe key was already queued")
+	ErrJobNotRetryable     = errors.New("only failed or dead jobs can be retried")
+	ErrCantFindRecurring   = errors.New("recurring job not found")
+	ErrLostJobLease        = errors.New("job lease was lost to another worker")
+	ErrJobLeaseExpired     = errors.New("job lease expired on the last attempt, the worker crashed or hung")
+	ErrInvalidJobStatus    = errors.New("status must be one of pending, running, succeeded, failed, dead")
+	ErrRecurringJobClaimed = errors.New("recurring job was already enqueued by another worker")
+)
+
+// FinishedJobTTL is how long succeeded jobs are kept
+const FinishedJobTTL = 7 * 24 * time.Hour
+
+// DefaultJobAttempts is used when a job is enqueued without a limit
+const DefaultJobAttempts = 5
+
+// ValidJobStatus reports whether the status filter value is a job status
+func ValidJobStatus(status string) bool {
+	switch status {
+	case constants.JobPending, constants.JobRunning, constants.JobSucceeded, constants.JobFailed, constants.JobDead:
+		return true
+	}
+	return false
+}
+
+// EnqueueJob stores a job to run at runAt
+func (d *DBClient) EnqueueJob(ctx context.Context, kind string, payload map[string]interface{}, runAt time.Time, maxAttempts int) (models.Job, error) {
+	return d.EnqueueUniqueJob(ctx, kind, "", payload, runAt, maxAttempts)
+}
+
+// EnqueueUniqueJob stores a job unless one with the dedupe key was queued
+// before, in which case it returns ErrJobExists. An empty key never clashes.
+func (d *DBClient) EnqueueUniqueJob(ctx context.Context, kind, dedupeKey string, payload map[string]interface{}, runAt time.Time, maxAttempts int) (models.Job, error) {
+
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultJobAttempts
+	}
+	if payload == nil {
+		payload = map[string]interface{}{}
+	}
+
+	now := time.Now()
+	job := models.Job{
+		Job_ID:       primitive.NewObjectID(),
+		Kind:         kind,
+		Payload:      payload,
+		Status:       constants.JobPending,
+		Max_Attempts: maxAttempts,
+		Run_At:       runAt,
+		Dedupe_Key:   dedupeKey,
+		Created_At:   now,
+		Updated_At:   now,
+	}
+
+	_, err := d.jobCollection.InsertOne(ctx, job)
+	if mongo.IsDuplicateKeyError(err) {
+		return job, ErrJobExists
+	}
+	if err != nil {
+		return job, err
+	}
+
+	return job, nil
+}
+
+// ClaimJob atomically takes the oldest due job of one of the kinds and leases
+// it to the worker. Running jobs whose lease ran out count as due while they
+// have attempts left, which is what makes execution at-least-once.
+func (d *DBClient) ClaimJob(ctx context.Context, worker string, kinds []string, lease time.Duration) (models.Job, error) {
+
+	var job models.Job
+	now := time.Now()
+
+	filter := bson.M{
+		"kind": bson.M{"$in": kinds},
+		"$or": bson.A{
+			bson.M{"status": bson.M{"$in": bson.A{constants.JobPending, constants.JobFailed}}, "run_at": bson.M{"$lte": now}},
+			bson.M{
+				"status":       constants.JobRunning,
+				"locked_until": bson.M{"$lte": now},
+				"$expr":        bson.M{"$lt": bson.A{"$attempts", "$max_attempts"}},
+			},
+		},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"status":       constants.JobRunning,
+			"locked_by":    worker,
+			"locked_until": now.Add(lease),
+			"updated_at":   now,
+		},
+		"$inc": bson.M{"attempts": 1},
+	}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "run_at", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	err := d.jobCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&job)
+	if err == mongo.ErrNoDocuments {
+		return job, ErrNoJob
+	}
+	if err != nil {
+		return job, err
+	}
+
+	return job, nil
+}
+
+// BuryAbandonedJobs marks dead the running jobs whose lease ran out on their
+// last attempt. The worker running them crashed or hung, and the job would
+// keep taking workers down if it was claimed again.
+func (d *DBClient) BuryAbandonedJobs(ctx context.Context) (int64, error) {
+
+	now := time.Now()
+	filter := bson.M{
+		"status":       constants.JobRunning,
+		"locked_until": bson.M{"$lte": now},
+		"$expr":        bson.M{"$gte": bson.A{"$attempts", "$max_attempts"}},
+	}
+	update := bson.M{
+		"$set":   bson.M{"status": constants.JobDead, "last_error": ErrJobLeaseExpired.Error(), "updated_at": now},
+		"$unset": bson.M{"locked_by": "", "locked_until": ""},
+	}
+
+	result, err := d.jobCollection.UpdateMany(ctx, filter, update)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.ModifiedCount, nil
+}
+
+// CompleteJob marks the job succeeded, provided the worker still holds it
+func (d *DBClient) CompleteJob(ctx context.Context, job models.Job, worker string) error {
+
+	now := time.Now()
+	filter := bson.M{"_id": job.Job_ID, "status": constants.JobRunning, "locked_by": worker}
+	update := bson.M{
+		"$set":   bson.M{"status": constants.JobSucceeded, "finished_at": now, "updated_at": now},
+		"$unset": bson.M{"locked_by": "", "locked_until": "", "last_error": ""},
+	}
+
+	result, err := d.jobCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrLostJobLease
+	}
+
+	return nil
+}
+
+// FailJob records the error and schedules the retry at retryAt, or marks the
+// job dead when it has used up its attempts
+func (d *DBClient) FailJob(ctx context.Context, job models.Job, worker string, jobErr error, retryAt time.Time) error {
+
+	status := constants.JobFailed
+	if job.Attempts >= job.Max_Attempts {
+		status = constants.JobDead
+	}
+
+	filter := bson.M{"_id": job.Job_ID, "status": constants.JobRunning, "locked_by": worker}
+	update := bson.M{
+		"$set":   bson.M{"status": status, "last_error": jobErr.Error(), "run_at": retryAt, "updated_at": time.Now()},
+		"$unset": bson.M{"locked_by": "", "locked_until": ""},
+	}
+
+	result, err := d.jobCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrLostJobLease
+	}
+
+	return nil
+}
+
+// GetJobs lists the newest jobs, optionally only those of a status and kind
+func (d *DBClient) GetJobs(ctx context.Context, status, kind string, limit int64) ([]models.Job, error) {
+
+	jobs := make([]models.Job, 0)
+
+	filter := bson.M{}
+	if status != "" {
+		filter["status"] = status
+	}
+	if kind != "" {
+		filter["kind"] = kind
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(limit)
+
+	cursor, err := d.jobCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return jobs, err
+	}
+
+	err = cursor.All(ctx, &jobs)
+	if err != nil {
+		return jobs, err
+	}
+
+	return jobs, nil
+}
+
+func (d *DBClient) GetJob(ctx context.Context, job_id primitive.ObjectID) (models.Job, error) {
+
+	var job models.Job
+
+	err := d.jobCollection.FindOne(ctx, bson.M{"_id": job_id}).Decode(&job)
+	if err == mongo.ErrNoDocuments {
+		return job, ErrCantFindJob
+	}
+	if err != nil {
+		return job, err
+	}
+
+	return job, nil
+}
+
+// RetryJob puts a failed or dead job back in the queue with fresh attempts
+func (d *DBClient) RetryJob(ctx context.Context, job_id primitive.ObjectID) error {
+
+	now := time.Now()
+	filter := bson.M{"_id": job_id, "status": bson.M{"$in": bson.A{constants.JobFailed, constants.JobDead}}}
+	update := bson.M{"$set": bson.M{"status": constants.JobPending, "attempts": 0, "run_at": now, "updated_at": now}}
+
+	result, err := d.jobCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		if _, err := d.GetJob(ctx, job_id); err != nil {
+			return err
+		}
+		return ErrJobNotRetryable
+	}
+
+	return nil
+}
+
+// RegisterRecurringJob stores the schedule, keeping the next run time when the
+// schedule did not change so a restart does not skip or repeat a run
+func (d *DBClient) RegisterRecurringJob(ctx context.Context, name, schedule, kind string, next time.Time) error {
+
+	filter := bson.M{"_id": name, "schedule": schedule, "kind": kind}
+	count, err := d.recurringJobCollection.CountDocuments(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	update := bson.M{"$set": bson.M{"schedule": schedule, "kind": kind, "next_run_at": next}}
+	_, err = d.recurringJobCollection.UpdateOne(ctx, bson.M{"_id": name}, update, options.Update().SetUpsert(true))
+	return err
+}
+
+// DueRecurringJobs returns the recurring jobs whose next run time has passed
+func (d *DBClient) DueRecurringJobs(ctx context.Context, now time.Time) ([]models.RecurringJob, error) {
+
+	recurring := make([]models.RecurringJob, 0)
+
+	cursor, err := d.recurringJobCollection.Find(ctx, bson.M{"next_run_at": bson.M{"$lte": now}})
+	if err != nil {
+		return recurring, err
+	}
+
+	err = cursor.All(ctx, &recurring)
+	if err != nil {
+		return recurring, err
+	}
+
+	return recurring, nil
+}
+
+// ClaimRecurringJob moves the recurring job on to its next run time and
+// enqueues this run. Only the worker whose update matched the old run time
+// enqueues the job, so each run happens once however many replicas are polling.
+func (d *DBClient) ClaimRecurringJob(ctx context.Context, recurring models.RecurringJob, now, next time.Time) (models.Job, error) {
+
+	filter := bson.M{"_id": recurring.Name, "next_run_at": recurring.Next_Run_At}
+	update := bson.M{"$set": bson.M{"next_run_at": next, "last_run_at": now}}
+
+	result, err := d.recurringJobCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return models.Job{}, err
+	}
+	if result.ModifiedCount == 0 {
+		return models.Job{}, ErrRecurringJobClaimed
+	}
+
+	job := models.Job{
+		Job_ID:       primitive.NewObjectID(),
+		Kind:         recurring.Kind,
+		Payload:      map[string]interface{}{},
+		Status:       constants.JobPending,
+		Max_Attempts: 1,
+		Run_At:       now,
+		Recurring:    recurring.Name,
+		Created_At:   now,
+		Updated_At:   now,
+	}
+
+	_, err = d.jobCollection.InsertOne(ctx, job)
+	if err != nil {
+		return job, err
+	}
+
+	return job, nil
+}
+
+func (d *DBClient) GetRecurringJobs(ctx context.Context) ([]models.RecurringJob, error) {
+
+	recurring := make([]models.RecurringJob, 0)
+
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+	cursor, err := d.recurringJobCollection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return recurring, err
+	}
+
+	err = cursor.All(ctx, &recurring)
+	if err != nil {
+		return recurring, err
+	}
+
+	return recurring, nil
+}
+
+// RunRecurringJobNow makes the recurring job due on the next poll
+func (d *DBClient) RunRecurringJobNow(ctx context.Context, name string) error {
+
+	result, err := d.recurringJobCollection.UpdateOne(ctx, bson.M{"_id": name}, bson.M{"$set": bson.M{"next_run_at": time.Now()}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrCantFindRecurring
+	}
+
+	return nil
+}
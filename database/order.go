@@ -0,0 +1,98 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/mayuka-c/e-commerce/constants"
+	"github.com/mayuka-c/e-commerce/events"
+	"github.com/mayuka-c/e-commerce/models"
+)
+
+var (
+	ErrCantFindOrder  = errors.New("can't find the order")
+	ErrOrderCancelled = errors.New("order is already cancelled")
+	ErrOrderShipped   = errors.New("order has shipped and can no longer be cancelled")
+)
+
+// GetUserOrder returns the order placed by the user along with the user's addresses
+func (d *DBClient) GetUserOrder(ctx context.Context, user_id, order_id primitive.ObjectID) (models.Order, []models.Address, error) {
+
+	var user models.User
+
+	filter := bson.D{{Key: "_id", Value: user_id}, {Key: "orders._id", Value: order_id}}
+	err := d.userCollection.FindOne(ctx, filter).Decode(&user)
+	if err != nil {
+		return models.Order{}, nil, ErrCantFindOrder
+	}
+
+	for _, order := range user.Order_Status {
+		if order.Order_ID == order_id {
+			return order, user.Address_Details, nil
+		}
+	}
+
+	return models.Order{}, nil, ErrCantFindOrder
+}
+
+// CancelOrder cancels an order that has not shipped yet, puts its variant
+// stock back and records the OrderCancelled event
+func (d *DBClient) CancelOrder(ctx context.Context, user_id, order_id primitive.ObjectID, reason string) (models.Order, error) {
+
+	var cancelled models.Order
+
+	err := d.WithTransaction(ctx, func(ctx context.Context) error {
+		order, _, err := d.GetUserOrder(ctx, user_id, order_id)
+		if err != nil {
+			return err
+		}
+		if order.Status == constants.OrderCancelled {
+			return ErrOrderCancelled
+		}
+
+		shipments, err := d.GetShipmentsByOrder(ctx, user_id, order_id)
+		if err != nil {
+			return err
+		}
+		if len(shipments) > 0 {
+			return ErrOrderShipped
+		}
+
+		now := time.Now()
+		filter := bson.M{"_id": user_id, "orders": bson.M{"$elemMatch": bson.M{"_id": order_id, "status": bson.M{"$ne": constants.OrderCancelled}}}}
+		update := bson.M{"$set": bson.M{"orders.$.status": constants.OrderCancelled, "orders.$.cancelled_at": now}}
+
+		result, err := d.userCollection.UpdateOne(ctx, filter, update)
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount == 0 {
+			return ErrOrderCancelled
+		}
+
+		d.releaseStock(ctx, order.Order_Cart)
+
+		order.Status = constants.OrderCancelled
+		order.Cancelled_At = &now
+		cancelled = order
+
+		orderCancelled := events.OrderCancelled{
+			User_ID:      user_id,
+			Order_ID:     order_id,
+			Total_Price:  order.Price,
+			Reason:       reason,
+			Cancelled_At: now,
+		}
+		if order.Payment_Method.Digital {
+			orderCancelled.Refund_Amount = order.Price
+		}
+
+		return d.recordEvent(ctx, orderCancelled)
+	})
+
+	return cancelled, err
+}
@@ -0,0 +1,137 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/mayuka-c/e-commerce/events"
+	"github.com/mayuka-c/e-commerce/models"
+)
+
+var (
+	// the dispatcher in package events checks for this error
+	ErrNoOutboxEvent = events.ErrNoEvent
+)
+
+// PublishedEventTTL is how long published events stay in the outbox
+const PublishedEventTTL = 7 * 24 * time.Hour
+
+// supportsTransactions reports whether the deployment is a replica set or a
+// sharded cluster; standalone servers reject multi-document transactions
+func supportsTransactions(client *mongo.Client) bool {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var hello bson.M
+	err := client.Database("admin").RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello)
+	if err != nil {
+		return false
+	}
+
+	_, replicaSet := hello["setName"]
+	return replicaSet || hello["msg"] == "isdbgrid"
+}
+
+// WithTransaction runs fn in a multi-document transaction, passing it the
+// context every operation of the transaction must use. fn may be called more
+// than once when the transaction hits a transient error. On a standalone
+// server fn runs once without a transaction.
+func (d *DBClient) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+
+	if !d.transactions {
+		return fn(ctx)
+	}
+
+	session, err := d.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sessCtx)
+	})
+
+	return err
+}
+
+// recordEvent writes the event to the outbox. Call it inside the transaction
+// of the change the event describes.
+func (d *DBClient) recordEvent(ctx context.Context, payload events.Payload) error {
+
+	record, err := events.NewOutboxEvent(payload, time.Now())
+	if err != nil {
+		return err
+	}
+
+	_, err = d.outboxCollection.InsertOne(ctx, record)
+	return err
+}
+
+// ClaimOutboxEvent leases the oldest unpublished event that is due
+func (d *DBClient) ClaimOutboxEvent(ctx context.Context, lease time.Duration) (models.OutboxEvent, error) {
+
+	var record models.OutboxEvent
+	now := time.Now()
+
+	filter := bson.M{
+		"published_at":    bson.M{"$exists": false},
+		"next_attempt_at": bson.M{"$lte": now},
+		"$or": bson.A{
+			bson.M{"locked_until": bson.M{"$exists": false}},
+			bson.M{"locked_until": bson.M{"$lte": now}},
+		},
+	}
+	update := bson.M{"$set": bson.M{"locked_until": now.Add(lease)}}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "next_attempt_at", Value: 1}, {Key: "_id", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	err := d.outboxCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&record)
+	if err == mongo.ErrNoDocuments {
+		return record, ErrNoOutboxEvent
+	}
+	if err != nil {
+		return record, err
+	}
+
+	return record, nil
+}
+
+// MarkEventPublishedTo records that the sink took the event
+func (d *DBClient) MarkEventPublishedTo(ctx context.Context, event_id primitive.ObjectID, sink string) error {
+
+	_, err := d.outboxCollection.UpdateOne(ctx, bson.M{"_id": event_id}, bson.M{"$addToSet": bson.M{"published_to": sink}})
+	return err
+}
+
+// CompleteOutboxEvent marks the event published to every sink
+func (d *DBClient) CompleteOutboxEvent(ctx context.Context, event_id primitive.ObjectID) error {
+
+	update := bson.M{
+		"$set":   bson.M{"published_at": time.Now()},
+		"$unset": bson.M{"locked_until": "", "last_error": ""},
+	}
+
+	_, err := d.outboxCollection.UpdateOne(ctx, bson.M{"_id": event_id}, update)
+	return err
+}
+
+// FailOutboxEvent releases the event for another attempt at next
+func (d *DBClient) FailOutboxEvent(ctx context.Context, event_id primitive.ObjectID, publishErr error, next time.Time) error {
+
+	update := bson.M{
+		"$set":   bson.M{"last_error": publishErr.Error(), "next_attempt_at": next},
+		"$inc":   bson.M{"attempts": 1},
+		"$unset": bson.M{"locked_until": ""},
+	}
+
+	_, err := d.outboxCollection.UpdateOne(ctx, bson.M{"_id": event_id}, update)
+	return err
+}
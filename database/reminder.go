@@ -0,0 +1,192 @@
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/mayuka-c/e-commerce/models"
+)
+
+var (
+	ErrInvalidUnsubscribe = errors.New("unsubscribe link is invalid")
+)
+
+// cartReminderKind is the email kind of cart reminders in notifications_off
+const cartReminderKind = "cart_reminder"
+
+// ConversionWindow is how long after a reminder a checkout still counts as
+// converted by it
+const ConversionWindow = 7 * 24 * time.Hour
+
+// AbandonedCarts returns users with a non-empty cart untouched since
+// idleSince, who have not opted out and have not been reminded about this
+// version of the cart. Users reminded after remindedBefore are skipped too, so
+// nobody gets more than one reminder per throttle period.
+func (d *DBClient) AbandonedCarts(ctx context.Context, idleSince, remindedBefore time.Time, limit int64) ([]models.User, error) {
+
+	users := make([]models.User, 0)
+
+	filter := bson.M{
+		"usercart.0":         bson.M{"$exists": true},
+		"cart_updated_at":    bson.M{"$lte": idleSince},
+		"cart_reminders_off": bson.M{"$ne": true},
+		"$or": bson.A{
+			bson.M{"cart_reminded_at": bson.M{"$exists": false}},
+			bson.M{"$expr": bson.M{"$and": bson.A{
+				bson.M{"$lt": bson.A{"$cart_reminded_at", "$cart_updated_at"}},
+				bson.M{"$lte": bson.A{"$cart_reminded_at", remindedBefore}},
+			}}},
+		},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "cart_updated_at", Value: 1}}).SetLimit(limit)
+
+	cursor, err := d.userCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return users, err
+	}
+
+	err = cursor.All(ctx, &users)
+	if err != nil {
+		return users, err
+	}
+
+	return users, nil
+}
+
+// ClaimCartReminder marks the user as reminded now, unless the cart changed or
+// another replica claimed it since AbandonedCarts returned it. The returned
+// user carries the unsubscribe token, created on first use.
+func (d *DBClient) ClaimCartReminder(ctx context.Context, user models.User, now time.Time) (models.User, bool, error) {
+
+	set := bson.M{"cart_reminded_at": now}
+	if user.Unsubscribe_Token == nil {
+		raw := make([]byte, 16)
+		if _, err := rand.Read(raw); err != nil {
+			return user, false, err
+		}
+		token := hex.EncodeToString(raw)
+		set["unsubscribe_token"] = token
+		user.Unsubscribe_Token = &token
+	}
+
+	filter := bson.M{"_id": user.ID, "cart_updated_at": user.Cart_Updated_At}
+	if user.Cart_Reminded_At == nil {
+		filter["cart_reminded_at"] = bson.M{"$exists": false}
+	} else {
+		filter["cart_reminded_at"] = *user.Cart_Reminded_At
+	}
+
+	result, err := d.userCollection.UpdateOne(ctx, filter, bson.M{"$set": set})
+	if err != nil {
+		return user, false, err
+	}
+	if result.ModifiedCount == 0 {
+		return user, false, nil
+	}
+
+	user.Cart_Reminded_At = &now
+	return user, true, nil
+}
+
+func (d *DBClient) RecordCartReminder(ctx context.Context, reminder models.CartReminder) error {
+
+	reminder.Reminder_ID = primitive.NewObjectID()
+
+	_, err := d.cartReminderCollection.InsertOne(ctx, reminder)
+	return err
+}
+
+// recordCartConversion credits the order to the latest reminder sent to the
+// user within the conversion window
+func (d *DBClient) recordCartConversion(ctx context.Context, user_id primitive.ObjectID, order models.Order) error {
+
+	filter := bson.M{
+		"user_id":      user_id,
+		"converted_at": nil,
+		"sent_at":      bson.M{"$gte": order.Ordered_At.Add(-ConversionWindow)},
+	}
+	update := bson.M{"$set": bson.M{
+		"converted_at": order.Ordered_At,
+		"order_id":     order.Order_ID,
+		"order_value":  order.Price,
+	}}
+	opts := options.FindOneAndUpdate().SetSort(bson.D{{Key: "sent_at", Value: -1}})
+
+	err := d.cartReminderCollection.FindOneAndUpdate(ctx, filter, update, opts).Err()
+	if err == mongo.ErrNoDocuments {
+		return nil
+	}
+
+	return err
+}
+
+func (d *DBClient) CartReminderStats(ctx context.Context, since time.Time) (models.CartReminderStats, error) {
+
+	stats := models.CartReminderStats{Since: since}
+
+	match := bson.D{{Key: "$match", Value: bson.M{"sent_at": bson.M{"$gte": since}}}}
+	group := bson.D{{Key: "$group", Value: bson.M{
+		"_id":             nil,
+		"sent":            bson.M{"$sum": 1},
+		"converted":       bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$ifNull": bson.A{"$converted_at", false}}, 1, 0}}},
+		"converted_value": bson.M{"$sum": "$order_value"},
+	}}}
+
+	cursor, err := d.cartReminderCollection.Aggregate(ctx, mongo.Pipeline{match, group})
+	if err != nil {
+		return stats, err
+	}
+
+	var result []struct {
+		Sent            int64 `bson:"sent"`
+		Converted       int64 `bson:"converted"`
+		Converted_Value int64 `bson:"converted_value"`
+	}
+	err = cursor.All(ctx, &result)
+	if err != nil {
+		return stats, err
+	}
+
+	if len(result) > 0 {
+		stats.Sent = result[0].Sent
+		stats.Converted = result[0].Converted
+		stats.Converted_Value = result[0].Converted_Value
+	}
+	if stats.Sent > 0 {
+		stats.Conversion_Rate = float64(stats.Converted) / float64(stats.Sent)
+	}
+
+	return stats, nil
+}
+
+// Unsubscribe turns cart reminders off for the user the token was sent to
+func (d *DBClient) Unsubscribe(ctx context.Context, token string) error {
+
+	if token == "" {
+		return ErrInvalidUnsubscribe
+	}
+
+	// keep the preference list in step with the flag, as UpdateNotificationPrefs does
+	update := bson.M{
+		"$set":      bson.M{"cart_reminders_off": true, "updated_at": time.Now()},
+		"$addToSet": bson.M{"notifications_off": cartReminderKind},
+	}
+
+	result, err := d.userCollection.UpdateOne(ctx, bson.M{"unsubscribe_token": token}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrInvalidUnsubscribe
+	}
+
+	return nil
+}
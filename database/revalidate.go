@@ -0,0 +1,93 @@
+package database
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/mayuka-c/e-commerce/constants"
+	"github.com/mayuka-c/e-commerce/models"
+)
+
+// RevalidateCart checks the cart lines against the live Products collection.
+// It returns the lines as they would be bought now, repriced to the current
+// catalog price and without the products or variants that were removed or ran
+// out of stock, together with one issue for every line that changed.
+func (d *DBClient) RevalidateCart(ctx context.Context, lines []models.ProductUser) ([]models.ProductUser, []models.CartIssue, error) {
+
+	current := make([]models.ProductUser, 0, len(lines))
+	issues := make([]models.CartIssue, 0)
+
+	if len(lines) == 0 {
+		return current, issues, nil
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(lines))
+	for _, line := range lines {
+		ids = append(ids, line.Product_ID)
+	}
+
+	cursor, err := d.productCollection.Find(ctx, bson.M{"_id": bson.M{"$in": uniqueIDs(ids)}})
+	if err != nil {
+		return current, issues, err
+	}
+
+	var products []models.Product
+	if err = cursor.All(ctx, &products); err != nil {
+		return current, issues, err
+	}
+
+	catalog := make(map[primitive.ObjectID]models.Product, len(products))
+	for _, product := range products {
+		catalog[product.Product_ID] = product
+	}
+
+	for _, line := range lines {
+		issue := models.CartIssue{
+			Product_ID:   line.Product_ID,
+			Variant_ID:   line.Variant_ID,
+			Product_Name: line.Product_Name,
+		}
+
+		product, ok := catalog[line.Product_ID]
+		if !ok {
+			issue.Kind = constants.CartProductRemoved
+			issues = append(issues, issue)
+			continue
+		}
+
+		fresh, variant, err := productLine(product, line.Variant_ID)
+		if err != nil {
+			// the variant is gone, or the product gained variants since
+			issue.Kind = constants.CartProductRemoved
+			issues = append(issues, issue)
+			continue
+		}
+
+		if variant != nil && variant.Stock <= 0 {
+			issue.Kind = constants.CartOutOfStock
+			issues = append(issues, issue)
+			continue
+		}
+
+		if fresh.Price != line.Price {
+			issue.Kind = constants.CartPriceChanged
+			issue.Old_Price = line.Price
+			issue.New_Price = fresh.Price
+			issues = append(issues, issue)
+		}
+
+		current = append(current, fresh)
+	}
+
+	return current, issues, nil
+}
+
+func cartTotal(lines []models.ProductUser) int {
+	total := 0
+	for _, line := range lines {
+		total += line.Price
+	}
+	return total
+}
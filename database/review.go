@@ -0,0 +1,255 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/mayuka-c/e-commerce/constants"
+	"github.com/mayuka-c/e-commerce/models"
+)
+
+var (
+	ErrNotVerifiedPurchaser = errors.New("only customers who ordered the product can review it")
+	ErrAlreadyReviewed      = errors.New("product is already reviewed by this user")
+	ErrCantFindReview       = errors.New("can't find the review")
+	ErrCantVoteReview       = errors.New("review is already voted by this user or is not open for votes")
+	ErrInvalidReviewSort    = errors.New("sort must be helpful or newest")
+)
+
+var reviewSorts = map[string]bson.D{
+	"":        {{Key: "helpful_votes", Value: -1}, {Key: "_id", Value: -1}},
+	"helpful": {{Key: "helpful_votes", Value: -1}, {Key: "_id", Value: -1}},
+	"newest":  {{Key: "_id", Value: -1}},
+}
+
+// FindPurchase returns the most recent order of the user that contains the
+// product and was not cancelled
+func (d *DBClient) FindPurchase(ctx context.Context, user_id, product_id primitive.ObjectID) (models.User, primitive.ObjectID, error) {
+
+	var user models.User
+
+	// a cancelled order doesn't make a verified purchase
+	filter := bson.D{
+		{Key: "_id", Value: user_id},
+		{Key: "orders", Value: bson.M{"$elemMatch": bson.M{
+			"order_list._id": product_id,
+			"status":         bson.M{"$ne": constants.OrderCancelled},
+		}}},
+	}
+	err := d.userCollection.FindOne(ctx, filter).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		return user, primitive.NilObjectID, ErrNotVerifiedPurchaser
+	}
+	if err != nil {
+		return user, primitive.NilObjectID, err
+	}
+
+	var latest *models.Order
+	for i, order := range user.Order_Status {
+		if order.Status == constants.OrderCancelled {
+			continue
+		}
+		for _, line := range order.Order_Cart {
+			if line.Product_ID == product_id && (latest == nil || order.Ordered_At.After(latest.Ordered_At)) {
+				latest = &user.Order_Status[i]
+			}
+		}
+	}
+	if latest == nil {
+		return user, primitive.NilObjectID, ErrNotVerifiedPurchaser
+	}
+
+	return user, latest.Order_ID, nil
+}
+
+// AddReview stores the review for moderation after checking that the author
+// has ordered the product and has not reviewed it before
+func (d *DBClient) AddReview(ctx context.Context, review models.Review) (models.Review, error) {
+
+	if _, err := d.GetProduct(ctx, review.Product_ID); err != nil {
+		return review, err
+	}
+
+	user, order_id, err := d.FindPurchase(ctx, review.User_ID, review.Product_ID)
+	if err != nil {
+		return review, err
+	}
+
+	review.Review_ID = primitive.NewObjectID()
+	review.Order_ID = order_id
+	review.Author = reviewAuthor(user)
+	review.Status = constants.ReviewPending
+	review.Helpful_Votes = 0
+	review.Voters = make([]primitive.ObjectID, 0)
+	review.Created_At = time.Now()
+	review.Updated_At = review.Created_At
+
+	_, err = d.reviewCollection.InsertOne(ctx, review)
+	if mongo.IsDuplicateKeyError(err) {
+		return review, ErrAlreadyReviewed
+	}
+	if err != nil {
+		return review, err
+	}
+
+	return review, nil
+}
+
+// GetReviews pages through the reviews of a product, or through every review
+// when product_id is nil, in the given status
+func (d *DBClient) GetReviews(ctx context.Context, product_id *primitive.ObjectID, status, sort string, page, limit int64) ([]models.Review, int64, error) {
+
+	reviews := make([]models.Review, 0)
+
+	order, ok := reviewSorts[sort]
+	if !ok {
+		return reviews, 0, ErrInvalidReviewSort
+	}
+
+	filter := bson.D{{Key: "status", Value: status}}
+	if product_id != nil {
+		filter = append(filter, bson.E{Key: "product_id", Value: *product_id})
+	}
+
+	total, err := d.reviewCollection.CountDocuments(ctx, filter)
+	if err != nil {
+		return reviews, 0, err
+	}
+
+	skip, err := pageSkip(page, limit)
+	if err != nil {
+		return reviews, total, err
+	}
+
+	opts := options.Find().SetSort(order).SetSkip(skip).SetLimit(limit)
+	cursor, err := d.reviewCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return reviews, total, err
+	}
+
+	err = cursor.All(ctx, &reviews)
+	if err != nil {
+		return reviews, total, err
+	}
+
+	return reviews, total, nil
+}
+
+// VoteReviewHelpful counts one helpful vote per user on an approved review
+// written by someone else
+func (d *DBClient) VoteReviewHelpful(ctx context.Context, review_id, user_id primitive.ObjectID) error {
+
+	filter := bson.D{
+		{Key: "_id", Value: review_id},
+		{Key: "status", Value: constants.ReviewApproved},
+		{Key: "user_id", Value: bson.M{"$ne": user_id}},
+		{Key: "voters", Value: bson.M{"$ne": user_id}},
+	}
+	update := bson.D{
+		{Key: "$addToSet", Value: bson.M{"voters": user_id}},
+		{Key: "$inc", Value: bson.M{"helpful_votes": 1}},
+	}
+
+	result, err := d.reviewCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		count, err := d.reviewCollection.CountDocuments(ctx, bson.M{"_id": review_id})
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrCantFindReview
+		}
+		return ErrCantVoteReview
+	}
+
+	return nil
+}
+
+// ModerateReview approves or rejects the review and refreshes the rating of its product
+func (d *DBClient) ModerateReview(ctx context.Context, review_id primitive.ObjectID, status string) (models.Review, error) {
+
+	var review models.Review
+
+	filter := bson.D{{Key: "_id", Value: review_id}}
+	update := bson.D{{Key: "$set", Value: bson.M{"status": status, "updated_at": time.Now()}}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	err := d.reviewCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&review)
+	if err == mongo.ErrNoDocuments {
+		return review, ErrCantFindReview
+	}
+	if err != nil {
+		return review, err
+	}
+
+	err = d.RefreshProductRating(ctx, review.Product_ID)
+	if err != nil {
+		return review, err
+	}
+
+	return review, nil
+}
+
+// RefreshProductRating recomputes the average stars and review count of the
+// product from its approved reviews and stores them on the product
+func (d *DBClient) RefreshProductRating(ctx context.Context, product_id primitive.ObjectID) error {
+
+	match := bson.D{{Key: "$match", Value: bson.D{{Key: "product_id", Value: product_id}, {Key: "status", Value: constants.ReviewApproved}}}}
+	group := bson.D{{Key: "$group", Value: bson.D{
+		{Key: "_id", Value: "$product_id"},
+		{Key: "average", Value: bson.M{"$avg": "$stars"}},
+		{Key: "count", Value: bson.M{"$sum": 1}},
+	}}}
+
+	cursor, err := d.reviewCollection.Aggregate(ctx, mongo.Pipeline{match, group})
+	if err != nil {
+		return err
+	}
+
+	var totals []struct {
+		Average float64 `bson:"average"`
+		Count   int64   `bson:"count"`
+	}
+	if err = cursor.All(ctx, &totals); err != nil {
+		return err
+	}
+
+	var rating float64
+	var count int64
+	if len(totals) > 0 {
+		rating = math.Round(totals[0].Average*10) / 10
+		count = totals[0].Count
+	}
+
+	filter := bson.D{{Key: "_id", Value: product_id}}
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "rating", Value: rating}, {Key: "rating_count", Value: count}}}}
+
+	_, err = d.productCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// reviewAuthor shows reviewers by first name and last initial
+func reviewAuthor(user models.User) string {
+	author := ""
+	if user.First_Name != nil {
+		author = *user.First_Name
+	}
+	if user.Last_Name != nil && *user.Last_Name != "" {
+		author += " " + string([]rune(*user.Last_Name)[0]) + "."
+	}
+	return author
+}
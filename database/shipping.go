@@ -0,0 +1,137 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/mayuka-c/e-commerce/events"
+	"github.com/mayuka-c/e-commerce/models"
+)
+
+var (
+	ErrCantFindShippingMethod = errors.New("can't find the shipping method")
+	ErrCantFindShipment       = errors.New("can't find the shipment")
+	ErrCantUpdateShipment     = errors.New("cannot update the shipment")
+)
+
+func (d *DBClient) AddShippingMethod(ctx context.Context, method models.ShippingMethod) error {
+
+	_, err := d.shippingMethodCollection.InsertOne(ctx, method)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (d *DBClient) GetShippingMethods(ctx context.Context) ([]models.ShippingMethod, error) {
+
+	var methods []models.ShippingMethod
+
+	cursor, err := d.shippingMethodCollection.Find(ctx, bson.D{})
+	if err != nil {
+		return methods, err
+	}
+
+	err = cursor.All(ctx, &methods)
+	if err != nil {
+		return methods, err
+	}
+
+	return methods, nil
+}
+
+func (d *DBClient) GetShippingMethod(ctx context.Context, method_id primitive.ObjectID) (models.ShippingMethod, error) {
+
+	var method models.ShippingMethod
+
+	err := d.shippingMethodCollection.FindOne(ctx, bson.D{{Key: "_id", Value: method_id}}).Decode(&method)
+	if err != nil {
+		return method, ErrCantFindShippingMethod
+	}
+
+	return method, nil
+}
+
+func (d *DBClient) CreateShipment(ctx context.Context, shipment models.Shipment) error {
+
+	_, err := d.shipmentCollection.InsertOne(ctx, shipment)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (d *DBClient) GetShipmentsByOrder(ctx context.Context, user_id, order_id primitive.ObjectID) ([]models.Shipment, error) {
+
+	shipments := make([]models.Shipment, 0)
+
+	filter := bson.D{{Key: "user_id", Value: user_id}, {Key: "order_id", Value: order_id}}
+	cursor, err := d.shipmentCollection.Find(ctx, filter)
+	if err != nil {
+		return shipments, err
+	}
+
+	err = cursor.All(ctx, &shipments)
+	if err != nil {
+		return shipments, err
+	}
+
+	return shipments, nil
+}
+
+// AddShipmentEvents appends tracking events to the shipment and moves its
+// status to the latest one, recording a ShipmentStatusChanged event when the
+// status changes
+func (d *DBClient) AddShipmentEvents(ctx context.Context, shipment_id primitive.ObjectID, shipmentEvents ...models.ShipmentEvent) error {
+
+	if len(shipmentEvents) == 0 {
+		return nil
+	}
+
+	latest := shipmentEvents[0]
+	for _, event := range shipmentEvents[1:] {
+		if !event.Occurred_At.Before(latest.Occurred_At) {
+			latest = event
+		}
+	}
+
+	filter := bson.D{{Key: "_id", Value: shipment_id}}
+	update := bson.D{
+		{Key: "$push", Value: bson.D{{Key: "events", Value: bson.D{{Key: "$each", Value: shipmentEvents}}}}},
+		{Key: "$set", Value: bson.D{{Key: "status", Value: latest.Status}, {Key: "updated_at", Value: time.Now()}}},
+	}
+
+	return d.WithTransaction(ctx, func(ctx context.Context) error {
+		var before models.Shipment
+
+		err := d.shipmentCollection.FindOneAndUpdate(ctx, filter, update).Decode(&before)
+		if err == mongo.ErrNoDocuments {
+			return ErrCantFindShipment
+		}
+		if err != nil {
+			return ErrCantUpdateShipment
+		}
+
+		if before.Status == latest.Status {
+			return nil
+		}
+
+		return d.recordEvent(ctx, events.ShipmentStatusChanged{
+			Shipment_ID:     shipment_id,
+			Order_ID:        before.Order_ID,
+			User_ID:         before.User_ID,
+			Carrier:         before.Carrier,
+			Tracking_Number: before.Tracking_Number,
+			Old_Status:      before.Status,
+			Status:          latest.Status,
+			Changed_At:      latest.Occurred_At,
+		})
+	})
+}
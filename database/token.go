@@ -0,0 +1,13 @@
+package database
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// hashToken is what gets stored of a bearer token, such as a guest cart or
+// password reset token, so a leaked database doesn't leak the tokens
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -2,64 +2,270 @@ package database
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
 
+	"github.com/mayuka-c/e-commerce/events"
+	"github.com/mayuka-c/e-commerce/metrics"
 	"github.com/mayuka-c/e-commerce/models"
 )
 
-func (d *DBClient) SearchProducts(ctx context.Context) ([]models.Product, error) {
+var (
+	ErrInvalidSort       = errors.New("sort must be one of price, -price, rating, -rating, name, -name, newest")
+	ErrInvalidSearchMode = errors.New("mode must be text or contains")
+	ErrEmptySearch       = errors.New("search query has no searchable words")
+	ErrInvalidPage       = fmt.Errorf("page must be between 1 and %d", MaxPage)
+)
 
-	var productList []models.Product
+// MaxPage is the last page the product and review listings serve. Deeper
+// pages would make the server skip more documents than is worth it, and a
+// page large enough would overflow the skip.
+const MaxPage = 10000
 
-	cursor, err := d.productCollection.Find(ctx, bson.D{{}})
-	if err != nil {
-		return productList, err
+// Search modes of SearchProductsByQuery. Text uses the product text index and
+// ranks by relevance, contains is a case-insensitive substring match on the name.
+const (
+	TextSearchMode     = "text"
+	ContainsSearchMode = "contains"
+)
+
+const (
+	maxSearchLength = 200
+	maxSearchTerms  = 10
+)
+
+// productSorts maps the sort query values onto MongoDB sort documents. _id is
+// always the last key so pages are stable when the other keys tie.
+var productSorts = map[string]bson.D{
+	"":        {{Key: "_id", Value: 1}},
+	"price":   {{Key: "price", Value: 1}, {Key: "_id", Value: 1}},
+	"-price":  {{Key: "price", Value: -1}, {Key: "_id", Value: 1}},
+	"rating":  {{Key: "rating", Value: 1}, {Key: "_id", Value: 1}},
+	"-rating": {{Key: "rating", Value: -1}, {Key: "_id", Value: 1}},
+	"name":    {{Key: "product_name", Value: 1}, {Key: "_id", Value: 1}},
+	"-name":   {{Key: "product_name", Value: -1}, {Key: "_id", Value: 1}},
+	"newest":  {{Key: "_id", Value: -1}},
+}
+
+// ValidProductSort reports whether the sort query value is supported
+func ValidProductSort(sort string) bool {
+	_, ok := productSorts[sort]
+	return ok
+}
+
+// SearchProducts lists a page of products, with facet counts when query.Facets is set
+func (d *DBClient) SearchProducts(ctx context.Context, query models.ProductQuery) ([]models.Product, int64, *models.ProductFacets, error) {
+
+	return d.listProducts(ctx, productFilter(query), query)
+}
+
+func (d *DBClient) SearchProductsByQuery(ctx context.Context, query models.ProductQuery) ([]models.Product, int64, *models.ProductFacets, error) {
+
+	filter := productFilter(query)
+
+	switch query.Search_Mode {
+	case TextSearchMode, "":
+		terms := textSearchTerms(query.Search)
+		if terms == "" {
+			return make([]models.Product, 0), 0, nil, ErrEmptySearch
+		}
+		filter = append(bson.D{{Key: "$text", Value: bson.M{"$search": terms}}}, filter...)
+	case ContainsSearchMode:
+		search := strings.TrimSpace(query.Search)
+		if len(search) > maxSearchLength {
+			search = strings.ToValidUTF8(search[:maxSearchLength], "")
+		}
+		if search == "" {
+			return make([]models.Product, 0), 0, nil, ErrEmptySearch
+		}
+		pattern := primitive.Regex{Pattern: regexp.QuoteMeta(search), Options: "i"}
+		filter = append(filter, bson.E{Key: "product_name", Value: pattern})
+	default:
+		return make([]models.Product, 0), 0, nil, ErrInvalidSearchMode
 	}
 
-	defer func() {
-		err := cursor.Close(ctx)
-		if err != nil {
-			panic(err)
+	return d.listProducts(ctx, filter, query)
+}
+
+// listProducts pages through the products matching the base filter and the
+// facet selections of the query
+func (d *DBClient) listProducts(ctx context.Context, base bson.D, query models.ProductQuery) ([]models.Product, int64, *models.ProductFacets, error) {
+
+	if query.Facets {
+		return d.facetProducts(ctx, base, query)
+	}
+
+	products, total, err := d.findProducts(ctx, withClauses(base, facetClauses(query, "")), query)
+	return products, total, nil, err
+}
+
+// textSearchTerms turns free user input into a $text search string. Quotes,
+// backslashes and leading minus signs carry meaning in $text (phrases and
+// negation), so they are dropped and every word becomes a plain term.
+func textSearchTerms(search string) string {
+
+	if len(search) > maxSearchLength {
+		search = strings.ToValidUTF8(search[:maxSearchLength], "")
+	}
+
+	search = strings.NewReplacer(`"`, " ", `\`, " ").Replace(search)
+
+	terms := make([]string, 0, maxSearchTerms)
+	for _, term := range strings.Fields(search) {
+		term = strings.TrimLeft(term, "-")
+		if term == "" {
+			continue
+		}
+		terms = append(terms, term)
+		if len(terms) == maxSearchTerms {
+			break
 		}
-	}()
+	}
 
-	err = cursor.All(ctx, &productList)
-	if err != nil {
-		return productList, err
+	return strings.Join(terms, " ")
+}
+
+// productFilter turns the price and rating ranges of the query into a match
+// document. Category, price band and rating facet selections are added by
+// facetClauses.
+func productFilter(query models.ProductQuery) bson.D {
+
+	filter := bson.D{}
+
+	price := bson.M{}
+	if query.Min_Price != nil {
+		price["$gte"] = *query.Min_Price
+	}
+	if query.Max_Price != nil {
+		price["$lte"] = *query.Max_Price
+	}
+	if len(price) > 0 {
+		filter = append(filter, bson.E{Key: "price", Value: price})
 	}
 
-	if curserErr := cursor.Err(); curserErr != nil {
-		return productList, err
+	rating := bson.M{}
+	if query.Min_Rating != nil {
+		rating["$gte"] = *query.Min_Rating
+	}
+	if query.Max_Rating != nil {
+		rating["$lte"] = *query.Max_Rating
+	}
+	if len(rating) > 0 {
+		filter = append(filter, bson.E{Key: "rating", Value: rating})
 	}
 
-	return productList, err
+	return filter
+}
+
+// pageSkip is the number of documents before the requested page
+func pageSkip(page, limit int64) (int64, error) {
+	if page < 1 || page > MaxPage || limit < 0 {
+		return 0, ErrInvalidPage
+	}
+	return (page - 1) * limit, nil
 }
 
-func (d *DBClient) SearchProductsByQuery(ctx context.Context, productname string) ([]models.Product, error) {
+func (d *DBClient) findProducts(ctx context.Context, filter bson.D, query models.ProductQuery) ([]models.Product, int64, error) {
 
-	var productList []models.Product
+	productList := make([]models.Product, 0)
 
-	cursor, err := d.productCollection.Find(ctx, bson.M{"product_name": bson.M{"$regex": productname}})
+	sort, ok := productSorts[query.Sort]
+	if !ok {
+		return productList, 0, ErrInvalidSort
+	}
+
+	skip, err := pageSkip(query.Page, query.Limit)
 	if err != nil {
-		return productList, err
+		return productList, 0, err
 	}
 
-	defer func() {
-		err := cursor.Close(ctx)
-		if err != nil {
-			panic(err)
+	opts := options.Find().
+		SetSkip(skip).
+		SetLimit(query.Limit)
+
+	if isTextSearch(filter) {
+		textScore := bson.M{"$meta": "textScore"}
+		opts.SetProjection(bson.D{{Key: "score", Value: textScore}})
+		if query.Sort == "" {
+			sort = bson.D{{Key: "score", Value: textScore}, {Key: "_id", Value: 1}}
 		}
-	}()
+	}
+	opts.SetSort(sort)
+
+	total, err := d.productCollection.CountDocuments(ctx, filter)
+	if err != nil {
+		return productList, 0, err
+	}
+
+	cursor, err := d.productCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return productList, total, err
+	}
 
+	// All closes the cursor, also when decoding fails
 	err = cursor.All(ctx, &productList)
+	if err != nil {
+		return productList, total, err
+	}
+
+	return productList, total, nil
+}
+
+func isTextSearch(filter bson.D) bool {
+	return len(filter) > 0 && filter[0].Key == "$text"
+}
+
+// ListProductNames returns the id and name of every product
+func (d *DBClient) ListProductNames(ctx context.Context) ([]models.Product, error) {
+
+	productList := make([]models.Product, 0)
+
+	opts := options.Find().SetProjection(bson.D{{Key: "_id", Value: 1}, {Key: "product_name", Value: 1}})
+	cursor, err := d.productCollection.Find(ctx, bson.D{}, opts)
 	if err != nil {
 		return productList, err
 	}
 
-	if curserErr := cursor.Err(); curserErr != nil {
+	err = cursor.All(ctx, &productList)
+	if err != nil {
 		return productList, err
 	}
 
-	return productList, err
+	return productList, nil
+}
+
+// CreateUser inserts the new user and records the UserRegistered event with it
+func (d *DBClient) CreateUser(ctx context.Context, user models.User) error {
+
+	registered := events.UserRegistered{User_ID: user.ID}
+	if user.Email != nil {
+		registered.Email = *user.Email
+	}
+	if user.First_Name != nil {
+		registered.First_Name = *user.First_Name
+	}
+	if user.Last_Name != nil {
+		registered.Last_Name = *user.Last_Name
+	}
+
+	err := d.WithTransaction(ctx, func(ctx context.Context) error {
+		_, err := d.userCollection.InsertOne(ctx, user)
+		if err != nil {
+			return err
+		}
+
+		return d.recordEvent(ctx, registered)
+	})
+	if err != nil {
+		return err
+	}
+
+	metrics.SignedUp()
+	return nil
 }
@@ -0,0 +1,347 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/mayuka-c/e-commerce/models"
+)
+
+var (
+	ErrCantFindVariant  = errors.New("can't find the product variant")
+	ErrVariantRequired  = errors.New("product has variants, so a variant must be chosen")
+	ErrOutOfStock       = errors.New("product variant is out of stock")
+	ErrSKUTaken         = errors.New("sku is already used by another variant")
+	ErrDuplicateOptions = errors.New("option names must be unique")
+)
+
+func (d *DBClient) GetProduct(ctx context.Context, product_id primitive.ObjectID) (models.Product, error) {
+
+	var product models.Product
+
+	err := d.productCollection.FindOne(ctx, bson.D{{Key: "_id", Value: product_id}}).Decode(&product)
+	if err != nil {
+		return product, ErrCantFindProduct
+	}
+
+	return product, nil
+}
+
+// SetProductOptions replaces the option axes of the product and regenerates its
+// variants. Variants whose option values survive keep their id, SKU, price,
+// stock and image; new combinations start with no stock.
+func (d *DBClient) SetProductOptions(ctx context.Context, product_id primitive.ObjectID, productOptions []models.ProductOption) (models.Product, error) {
+
+	product, err := d.GetProduct(ctx, product_id)
+	if err != nil {
+		return product, err
+	}
+
+	names := make(map[string]bool, len(productOptions))
+	for _, option := range productOptions {
+		if names[option.Name] {
+			return product, ErrDuplicateOptions
+		}
+		names[option.Name] = true
+	}
+
+	existing := make(map[string]models.ProductVariant, len(product.Variants))
+	taken := make(map[string]bool, len(product.Variants))
+	for _, variant := range product.Variants {
+		existing[optionsKey(variant.Options)] = variant
+		taken[variant.SKU] = true
+	}
+
+	// SKUs of new combinations are checked against other products in one query
+	all := combinations(productOptions)
+	generated := make(map[string]string, len(all))
+	candidates := make(bson.A, 0, len(all))
+	for _, combination := range all {
+		key := optionsKey(combination)
+		if _, ok := existing[key]; !ok {
+			generated[key] = variantSKU(product, productOptions, combination)
+			candidates = append(candidates, generated[key])
+		}
+	}
+	if len(candidates) > 0 {
+		used, err := d.productCollection.Distinct(ctx, "variants.sku", bson.M{"variants.sku": bson.M{"$in": candidates}, "_id": bson.M{"$ne": product_id}})
+		if err != nil {
+			return product, err
+		}
+		for _, sku := range used {
+			if sku, ok := sku.(string); ok {
+				taken[sku] = true
+			}
+		}
+	}
+
+	variants := make([]models.ProductVariant, 0, len(all))
+	for _, combination := range all {
+		key := optionsKey(combination)
+		if variant, ok := existing[key]; ok {
+			variants = append(variants, variant)
+			continue
+		}
+		sku := uniqueSKU(product, generated[key], taken)
+		taken[sku] = true
+		variants = append(variants, models.ProductVariant{
+			Variant_ID: primitive.NewObjectID(),
+			SKU:        sku,
+			Options:    combination,
+		})
+	}
+
+	filter := bson.D{{Key: "_id", Value: product_id}}
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "options", Value: productOptions}, {Key: "variants", Value: variants}}}}
+
+	_, err = d.productCollection.UpdateOne(ctx, filter, update)
+	if mongo.IsDuplicateKeyError(err) {
+		return product, ErrSKUTaken
+	}
+	if err != nil {
+		return product, err
+	}
+
+	product.Options = productOptions
+	product.Variants = variants
+
+	return product, nil
+}
+
+func (d *DBClient) UpdateVariant(ctx context.Context, product_id, variant_id primitive.ObjectID, variantUpdate models.VariantUpdate) error {
+
+	filter := bson.D{{Key: "_id", Value: product_id}, {Key: "variants._id", Value: variant_id}}
+
+	set := bson.D{}
+	if variantUpdate.SKU != nil {
+		// the unique index only spans products, so the other variants of
+		// this product are checked in the filter
+		filter = append(filter, bson.E{Key: "variants", Value: bson.M{"$not": bson.M{"$elemMatch": bson.M{"sku": *variantUpdate.SKU, "_id": bson.M{"$ne": variant_id}}}}})
+		set = append(set, bson.E{Key: "variants.$[v].sku", Value: *variantUpdate.SKU})
+	}
+	if variantUpdate.Price != nil {
+		set = append(set, bson.E{Key: "variants.$[v].price", Value: *variantUpdate.Price})
+	}
+	if variantUpdate.Stock != nil {
+		set = append(set, bson.E{Key: "variants.$[v].stock", Value: *variantUpdate.Stock})
+	}
+	if variantUpdate.Image != nil {
+		set = append(set, bson.E{Key: "variants.$[v].image", Value: *variantUpdate.Image})
+	}
+	if len(set) == 0 {
+		return nil
+	}
+
+	update := bson.D{{Key: "$set", Value: set}}
+	opt := options.Update().SetArrayFilters(options.ArrayFilters{Filters: []interface{}{bson.M{"v._id": variant_id}}})
+
+	result, err := d.productCollection.UpdateOne(ctx, filter, update, opt)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrSKUTaken
+	}
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		if variantUpdate.SKU != nil {
+			count, err := d.productCollection.CountDocuments(ctx, bson.D{{Key: "_id", Value: product_id}, {Key: "variants._id", Value: variant_id}})
+			if err == nil && count > 0 {
+				return ErrSKUTaken
+			}
+		}
+		return ErrCantFindVariant
+	}
+
+	return nil
+}
+
+// cartLine builds the cart/order line for the product, resolving the variant
+// when the product has any and refusing variants that are out of stock
+func cartLine(product models.Product, variant_id *primitive.ObjectID) (models.ProductUser, error) {
+
+	line, variant, err := productLine(product, variant_id)
+	if err != nil {
+		return line, err
+	}
+
+	if variant != nil && variant.Stock <= 0 {
+		return line, ErrOutOfStock
+	}
+
+	return line, nil
+}
+
+// productLine describes the product, or the chosen variant of it, as a line
+// priced at the current catalog price
+func productLine(product models.Product, variant_id *primitive.ObjectID) (models.ProductUser, *models.ProductVariant, error) {
+
+	line := models.ProductUser{
+		Product_ID:   product.Product_ID,
+		Product_Name: product.Product_Name,
+		Image:        product.Image,
+		Weight:       product.Weight,
+	}
+	if product.Price != nil {
+		line.Price = int(*product.Price)
+	}
+
+	if len(product.Variants) == 0 {
+		if variant_id != nil {
+			return line, nil, ErrCantFindVariant
+		}
+		return line, nil, nil
+	}
+
+	if variant_id == nil {
+		return line, nil, ErrVariantRequired
+	}
+
+	for i, variant := range product.Variants {
+		if variant.Variant_ID != *variant_id {
+			continue
+		}
+
+		id, sku := variant.Variant_ID, variant.SKU
+		line.Variant_ID = &id
+		line.SKU = &sku
+		line.Options = variant.Options
+		if variant.Price != nil {
+			line.Price = int(*variant.Price)
+		}
+		if variant.Image != nil {
+			line.Image = variant.Image
+		}
+		return line, &product.Variants[i], nil
+	}
+
+	return line, nil, ErrCantFindVariant
+}
+
+// reserveStock takes one unit of stock for every variant line, giving back what
+// it already took when any of them has run out
+func (d *DBClient) reserveStock(ctx context.Context, lines []models.ProductUser) error {
+
+	reserved := make([]models.ProductUser, 0, len(lines))
+	for _, line := range lines {
+		if line.Variant_ID == nil {
+			continue
+		}
+
+		filter := bson.M{"_id": line.Product_ID, "variants": bson.M{"$elemMatch": bson.M{"_id": *line.Variant_ID, "stock": bson.M{"$gte": 1}}}}
+		update := bson.M{"$inc": bson.M{"variants.$.stock": -1}}
+
+		result, err := d.productCollection.UpdateOne(ctx, filter, update)
+		if err == nil && result.MatchedCount == 0 {
+			err = ErrOutOfStock
+		}
+		if err != nil {
+			d.releaseStock(ctx, reserved)
+			return err
+		}
+		reserved = append(reserved, line)
+	}
+
+	return nil
+}
+
+func (d *DBClient) releaseStock(ctx context.Context, lines []models.ProductUser) {
+
+	for _, line := range lines {
+		if line.Variant_ID == nil {
+			continue
+		}
+
+		filter := bson.M{"_id": line.Product_ID, "variants._id": *line.Variant_ID}
+		update := bson.M{"$inc": bson.M{"variants.$.stock": 1}}
+		_, _ = d.productCollection.UpdateOne(ctx, filter, update)
+	}
+}
+
+// combinations returns the cartesian product of the option values
+func combinations(productOptions []models.ProductOption) []map[string]string {
+
+	if len(productOptions) == 0 {
+		return nil
+	}
+
+	result := []map[string]string{{}}
+	for _, option := range productOptions {
+		next := make([]map[string]string, 0, len(result)*len(option.Values))
+		for _, partial := range result {
+			for _, value := range option.Values {
+				combination := make(map[string]string, len(partial)+1)
+				for k, v := range partial {
+					combination[k] = v
+				}
+				combination[option.Name] = value
+				next = append(next, combination)
+			}
+		}
+		result = next
+	}
+
+	return result
+}
+
+func optionsKey(values map[string]string) string {
+
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(values[k])
+		b.WriteByte(';')
+	}
+
+	return b.String()
+}
+
+// uniqueSKU returns sku, or sku with the product id appended when it is taken.
+// Option values that slugify alike, e.g. "XL" and "xl", get a counter on top.
+func uniqueSKU(product models.Product, sku string, taken map[string]bool) string {
+
+	if !taken[sku] {
+		return sku
+	}
+
+	suffixed := sku + "-" + strings.ToUpper(product.Product_ID.Hex())
+	candidate := suffixed
+	for n := 2; taken[candidate]; n++ {
+		candidate = fmt.Sprintf("%s-%d", suffixed, n)
+	}
+
+	return candidate
+}
+
+// variantSKU builds e.g. "TSHIRT-M-BLUE" from the product SKU (or name) and the
+// option values in option order
+func variantSKU(product models.Product, productOptions []models.ProductOption, combination map[string]string) string {
+
+	base := product.Product_ID.Hex()
+	if product.SKU != nil && *product.SKU != "" {
+		base = *product.SKU
+	} else if product.Product_Name != nil && Slugify(*product.Product_Name) != "" {
+		base = Slugify(*product.Product_Name)
+	}
+
+	parts := []string{base}
+	for _, option := range productOptions {
+		parts = append(parts, Slugify(combination[option.Name]))
+	}
+
+	return strings.ToUpper(strings.Join(parts, "-"))
+}
@@ -0,0 +1,265 @@
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/mayuka-c/e-commerce/constants"
+	"github.com/mayuka-c/e-commerce/models"
+)
+
+var (
+	ErrCantFindWebhook         = errors.New("webhook endpoint not found")
+	ErrCantFindDelivery        = errors.New("webhook delivery not found")
+	ErrDeliveryExists          = errors.New("event was already delivered to this endpoint")
+	ErrDeliveryInProgress      = errors.New("webhook delivery is still being retried")
+	ErrInvalidDeliveryStatus   = errors.New("status must be one of pending, delivered, failed, dead")
+	ErrUnknownWebhookEventType = errors.New("events must be known event types or *")
+)
+
+// maxDeliveryLog caps the attempts kept on a delivery
+const maxDeliveryLog = 20
+
+// ValidDeliveryStatus reports whether the status filter value is a delivery status
+func ValidDeliveryStatus(status string) bool {
+	switch status {
+	case constants.DeliveryPending, constants.DeliveryDelivered, constants.DeliveryFailed, constants.DeliveryDead:
+		return true
+	}
+	return false
+}
+
+// AddWebhookEndpoint stores the endpoint with a new signing secret
+func (d *DBClient) AddWebhookEndpoint(ctx context.Context, endpoint models.WebhookEndpoint) (models.WebhookEndpoint, error) {
+
+	raw := make([]byte, 24)
+	if _, err := rand.Read(raw); err != nil {
+		return endpoint, err
+	}
+
+	now := time.Now()
+	endpoint.Endpoint_ID = primitive.NewObjectID()
+	endpoint.Secret = "whsec_" + hex.EncodeToString(raw)
+	endpoint.Active = true
+	endpoint.Created_At = now
+	endpoint.Updated_At = now
+
+	_, err := d.webhookCollection.InsertOne(ctx, endpoint)
+	if err != nil {
+		return endpoint, err
+	}
+
+	return endpoint, nil
+}
+
+// GetWebhookEndpoints lists the endpoints without their secrets
+func (d *DBClient) GetWebhookEndpoints(ctx context.Context) ([]models.WebhookEndpoint, error) {
+
+	endpoints := make([]models.WebhookEndpoint, 0)
+
+	opts := options.Find().SetProjection(bson.M{"secret": 0}).SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cursor, err := d.webhookCollection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return endpoints, err
+	}
+
+	err = cursor.All(ctx, &endpoints)
+	if err != nil {
+		return endpoints, err
+	}
+
+	return endpoints, nil
+}
+
+// GetWebhookEndpoint returns the endpoint including its secret
+func (d *DBClient) GetWebhookEndpoint(ctx context.Context, endpoint_id primitive.ObjectID) (models.WebhookEndpoint, error) {
+
+	var endpoint models.WebhookEndpoint
+
+	err := d.webhookCollection.FindOne(ctx, bson.M{"_id": endpoint_id}).Decode(&endpoint)
+	if err == mongo.ErrNoDocuments {
+		return endpoint, ErrCantFindWebhook
+	}
+	if err != nil {
+		return endpoint, err
+	}
+
+	return endpoint, nil
+}
+
+func (d *DBClient) UpdateWebhookEndpoint(ctx context.Context, endpoint_id primitive.ObjectID, endpointUpdate models.WebhookEndpointUpdate) error {
+
+	set := bson.M{"updated_at": time.Now()}
+	if endpointUpdate.URL != nil {
+		set["url"] = *endpointUpdate.URL
+	}
+	if endpointUpdate.Events != nil {
+		set["events"] = endpointUpdate.Events
+	}
+	if endpointUpdate.Active != nil {
+		set["active"] = *endpointUpdate.Active
+	}
+
+	result, err := d.webhookCollection.UpdateOne(ctx, bson.M{"_id": endpoint_id}, bson.M{"$set": set})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrCantFindWebhook
+	}
+
+	return nil
+}
+
+// DeleteWebhookEndpoint removes the endpoint. Its delivery log is kept.
+func (d *DBClient) DeleteWebhookEndpoint(ctx context.Context, endpoint_id primitive.ObjectID) error {
+
+	result, err := d.webhookCollection.DeleteOne(ctx, bson.M{"_id": endpoint_id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrCantFindWebhook
+	}
+
+	return nil
+}
+
+// SubscribedWebhookEndpoints returns the active endpoints that want the event type
+func (d *DBClient) SubscribedWebhookEndpoints(ctx context.Context, eventType string) ([]models.WebhookEndpoint, error) {
+
+	endpoints := make([]models.WebhookEndpoint, 0)
+
+	filter := bson.M{"active": true, "events": bson.M{"$in": bson.A{eventType, "*"}}}
+	cursor, err := d.webhookCollection.Find(ctx, filter)
+	if err != nil {
+		return endpoints, err
+	}
+
+	err = cursor.All(ctx, &endpoints)
+	if err != nil {
+		return endpoints, err
+	}
+
+	return endpoints, nil
+}
+
+// CreateWebhookDelivery stores a pending delivery. ErrDeliveryExists means the
+// event already has a delivery for the endpoint.
+func (d *DBClient) CreateWebhookDelivery(ctx context.Context, delivery models.WebhookDelivery) (models.WebhookDelivery, error) {
+
+	delivery.Delivery_ID = primitive.NewObjectID()
+	delivery.Status = constants.DeliveryPending
+	delivery.Log = make([]models.WebhookAttempt, 0)
+	delivery.Created_At = time.Now()
+
+	_, err := d.webhookDeliveryCollection.InsertOne(ctx, delivery)
+	if mongo.IsDuplicateKeyError(err) {
+		return delivery, ErrDeliveryExists
+	}
+	if err != nil {
+		return delivery, err
+	}
+
+	return delivery, nil
+}
+
+func (d *DBClient) GetWebhookDelivery(ctx context.Context, delivery_id primitive.ObjectID) (models.WebhookDelivery, error) {
+
+	var delivery models.WebhookDelivery
+
+	err := d.webhookDeliveryCollection.FindOne(ctx, bson.M{"_id": delivery_id}).Decode(&delivery)
+	if err == mongo.ErrNoDocuments {
+		return delivery, ErrCantFindDelivery
+	}
+	if err != nil {
+		return delivery, err
+	}
+
+	return delivery, nil
+}
+
+// GetWebhookDeliveries lists the newest deliveries, optionally of one endpoint
+// and status. Status dead lists the dead-letter queue.
+func (d *DBClient) GetWebhookDeliveries(ctx context.Context, endpoint_id *primitive.ObjectID, status string, limit int64) ([]models.WebhookDelivery, error) {
+
+	deliveries := make([]models.WebhookDelivery, 0)
+
+	filter := bson.M{}
+	if endpoint_id != nil {
+		filter["endpoint_id"] = *endpoint_id
+	}
+	if status != "" {
+		filter["status"] = status
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(limit)
+
+	cursor, err := d.webhookDeliveryCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return deliveries, err
+	}
+
+	err = cursor.All(ctx, &deliveries)
+	if err != nil {
+		return deliveries, err
+	}
+
+	return deliveries, nil
+}
+
+// RecordWebhookAttempt logs one delivery attempt and moves the delivery to status
+func (d *DBClient) RecordWebhookAttempt(ctx context.Context, delivery_id primitive.ObjectID, attempt models.WebhookAttempt, status string) error {
+
+	set := bson.M{"status": status, "last_status_code": attempt.Status_Code, "last_error": attempt.Error}
+	if status == constants.DeliveryDelivered {
+		set["delivered_at"] = attempt.At
+	}
+
+	update := bson.M{
+		"$set":  set,
+		"$inc":  bson.M{"attempts": 1},
+		"$push": bson.M{"log": bson.M{"$each": bson.A{attempt}, "$slice": -maxDeliveryLog}},
+	}
+
+	result, err := d.webhookDeliveryCollection.UpdateOne(ctx, bson.M{"_id": delivery_id}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrCantFindDelivery
+	}
+
+	return nil
+}
+
+// ResetWebhookDelivery puts a delivered or dead delivery back to pending for a
+// manual redelivery
+func (d *DBClient) ResetWebhookDelivery(ctx context.Context, delivery_id primitive.ObjectID) (models.WebhookDelivery, error) {
+
+	var delivery models.WebhookDelivery
+
+	filter := bson.M{"_id": delivery_id, "status": bson.M{"$in": bson.A{constants.DeliveryDelivered, constants.DeliveryDead}}}
+	update := bson.M{"$set": bson.M{"status": constants.DeliveryPending}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	err := d.webhookDeliveryCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&delivery)
+	if err == mongo.ErrNoDocuments {
+		if _, err := d.GetWebhookDelivery(ctx, delivery_id); err != nil {
+			return delivery, err
+		}
+		return delivery, ErrDeliveryInProgress
+	}
+	if err != nil {
+		return delivery, err
+	}
+
+	return delivery, nil
+}
@@ -0,0 +1,410 @@
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/mayuka-c/e-commerce/constants"
+	"github.com/mayuka-c/e-commerce/events"
+	"github.com/mayuka-c/e-commerce/models"
+)
+
+var (
+	ErrCantFindWishlist     = errors.New("can't find the wishlist")
+	ErrWishlistNameTaken    = errors.New("a wishlist with this name already exists")
+	ErrCantDeleteSavedList  = errors.New("the saved for later list cannot be deleted")
+	ErrCantFindWishlistItem = errors.New("can't find the item in the wishlist")
+	ErrCantFindCartItem     = errors.New("can't find the item in the cart")
+)
+
+const savedForLaterName = "Saved for later"
+
+func (d *DBClient) CreateWishlist(ctx context.Context, user_id primitive.ObjectID, name string) (models.Wishlist, error) {
+
+	wishlist := models.Wishlist{
+		Wishlist_ID: primitive.NewObjectID(),
+		User_ID:     user_id,
+		Name:        &name,
+		Kind:        constants.WishlistKind,
+		Items:       make([]models.WishlistItem, 0),
+		Created_At:  time.Now(),
+	}
+	wishlist.Updated_At = wishlist.Created_At
+
+	// the saved for later list is created on first use and keeps its name
+	if name == savedForLaterName {
+		return wishlist, ErrWishlistNameTaken
+	}
+
+	_, err := d.wishlistCollection.InsertOne(ctx, wishlist)
+	if mongo.IsDuplicateKeyError(err) {
+		return wishlist, ErrWishlistNameTaken
+	}
+	if err != nil {
+		return wishlist, err
+	}
+
+	return wishlist, nil
+}
+
+func (d *DBClient) GetWishlists(ctx context.Context, user_id primitive.ObjectID) ([]models.Wishlist, error) {
+
+	wishlists := make([]models.Wishlist, 0)
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cursor, err := d.wishlistCollection.Find(ctx, bson.M{"user_id": user_id}, opts)
+	if err != nil {
+		return wishlists, err
+	}
+
+	err = cursor.All(ctx, &wishlists)
+	if err != nil {
+		return wishlists, err
+	}
+
+	return wishlists, nil
+}
+
+func (d *DBClient) GetWishlist(ctx context.Context, user_id, wishlist_id primitive.ObjectID) (models.Wishlist, error) {
+
+	var wishlist models.Wishlist
+
+	err := d.wishlistCollection.FindOne(ctx, bson.M{"_id": wishlist_id, "user_id": user_id}).Decode(&wishlist)
+	if err != nil {
+		return wishlist, ErrCantFindWishlist
+	}
+
+	return wishlist, nil
+}
+
+func (d *DBClient) DeleteWishlist(ctx context.Context, user_id, wishlist_id primitive.ObjectID) error {
+
+	wishlist, err := d.GetWishlist(ctx, user_id, wishlist_id)
+	if err != nil {
+		return err
+	}
+	if wishlist.Kind == constants.SavedForLaterKind {
+		return ErrCantDeleteSavedList
+	}
+
+	_, err = d.wishlistCollection.DeleteOne(ctx, bson.M{"_id": wishlist_id, "user_id": user_id})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// AddWishlistItem adds the product, or the chosen variant of it, to the list at
+// its current price. Adding an item that is already on the list does nothing.
+func (d *DBClient) AddWishlistItem(ctx context.Context, user_id, wishlist_id, product_id primitive.ObjectID, variant_id *primitive.ObjectID) error {
+
+	product, err := d.GetProduct(ctx, product_id)
+	if err != nil {
+		return err
+	}
+
+	line, _, err := productLine(product, variant_id)
+	if err != nil {
+		return err
+	}
+
+	if _, err := d.GetWishlist(ctx, user_id, wishlist_id); err != nil {
+		return err
+	}
+
+	return d.pushWishlistItem(ctx, bson.M{"_id": wishlist_id, "user_id": user_id}, wishlistItem(line), false)
+}
+
+func (d *DBClient) RemoveWishlistItem(ctx context.Context, user_id, wishlist_id, product_id primitive.ObjectID, variant_id *primitive.ObjectID) error {
+
+	filter := bson.M{"_id": wishlist_id, "user_id": user_id, "items": bson.M{"$elemMatch": itemMatch(product_id, variant_id)}}
+	update := bson.M{
+		"$pull": bson.M{"items": itemMatch(product_id, variant_id)},
+		"$set":  bson.M{"updated_at": time.Now()},
+	}
+
+	result, err := d.wishlistCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		if _, err := d.GetWishlist(ctx, user_id, wishlist_id); err != nil {
+			return err
+		}
+		return ErrCantFindWishlistItem
+	}
+
+	return nil
+}
+
+// MoveWishlistItemToCart adds the item to the cart at the current price and
+// then takes it off the list
+func (d *DBClient) MoveWishlistItemToCart(ctx context.Context, user_id, wishlist_id, product_id primitive.ObjectID, variant_id *primitive.ObjectID) error {
+
+	count, err := d.wishlistCollection.CountDocuments(ctx, bson.M{"_id": wishlist_id, "user_id": user_id, "items": bson.M{"$elemMatch": itemMatch(product_id, variant_id)}})
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrCantFindWishlistItem
+	}
+
+	err = d.AddProductToCart(ctx, product_id, variant_id, user_id)
+	if err != nil {
+		return err
+	}
+
+	return d.RemoveWishlistItem(ctx, user_id, wishlist_id, product_id, variant_id)
+}
+
+// SaveForLater moves the cart line to the user's saved for later list,
+// creating the list on first use. Only that one line leaves the cart, even when
+// the product is in it more than once or in other variants.
+func (d *DBClient) SaveForLater(ctx context.Context, user_id, product_id primitive.ObjectID, variant_id *primitive.ObjectID) error {
+
+	return d.WithTransaction(ctx, func(ctx context.Context) error {
+
+		var user models.User
+		err := d.userCollection.FindOne(ctx, bson.M{"_id": user_id}).Decode(&user)
+		if err != nil {
+			return err
+		}
+
+		index := -1
+		for i, item := range user.UserCart {
+			if item.Product_ID != product_id {
+				continue
+			}
+			if variant_id != nil && (item.Variant_ID == nil || *item.Variant_ID != *variant_id) {
+				continue
+			}
+			index = i
+			break
+		}
+		if index < 0 {
+			return ErrCantFindCartItem
+		}
+		line := user.UserCart[index]
+
+		filter := bson.M{"user_id": user_id, "kind": constants.SavedForLaterKind}
+		err = d.pushWishlistItem(ctx, filter, wishlistItem(line), true)
+		if err != nil {
+			return err
+		}
+
+		// cut the line out by position, provided it is still there
+		position := fmt.Sprintf("usercart.%d", index)
+		cartFilter := bson.M{"_id": user_id, position + "._id": product_id}
+		if line.Variant_ID != nil {
+			cartFilter[position+".variant_id"] = *line.Variant_ID
+		} else {
+			cartFilter[position+".variant_id"] = bson.M{"$exists": false}
+		}
+		update := bson.A{bson.M{"$set": bson.M{
+			"usercart": bson.M{"$concatArrays": bson.A{
+				bson.M{"$slice": bson.A{"$usercart", index}},
+				bson.M{"$slice": bson.A{"$usercart", index + 1, bson.M{"$size": "$usercart"}}},
+			}},
+			"cart_updated_at": time.Now(),
+		}}}
+
+		result, err := d.userCollection.UpdateOne(ctx, cartFilter, update)
+		if err != nil {
+			return ErrCantRemoveItem
+		}
+		if result.MatchedCount == 0 {
+			return ErrCartChanged
+		}
+
+		return d.recordEvent(ctx, events.CartItemRemoved{User_ID: user_id, Product_ID: product_id, Variant_ID: line.Variant_ID})
+	})
+}
+
+// ShareWishlist gives the list a random share token, keeping an existing one
+func (d *DBClient) ShareWishlist(ctx context.Context, user_id, wishlist_id primitive.ObjectID) (string, error) {
+
+	wishlist, err := d.GetWishlist(ctx, user_id, wishlist_id)
+	if err != nil {
+		return "", err
+	}
+	if wishlist.Share_Token != nil {
+		return *wishlist.Share_Token, nil
+	}
+
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(raw)
+
+	filter := bson.M{"_id": wishlist_id, "user_id": user_id}
+	update := bson.M{"$set": bson.M{"share_token": token, "updated_at": time.Now()}}
+
+	_, err = d.wishlistCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+func (d *DBClient) UnshareWishlist(ctx context.Context, user_id, wishlist_id primitive.ObjectID) error {
+
+	filter := bson.M{"_id": wishlist_id, "user_id": user_id}
+	update := bson.M{"$unset": bson.M{"share_token": ""}, "$set": bson.M{"updated_at": time.Now()}}
+
+	result, err := d.wishlistCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrCantFindWishlist
+	}
+
+	return nil
+}
+
+func (d *DBClient) GetSharedWishlist(ctx context.Context, token string) (models.Wishlist, error) {
+
+	var wishlist models.Wishlist
+
+	err := d.wishlistCollection.FindOne(ctx, bson.M{"share_token": token}).Decode(&wishlist)
+	if err != nil {
+		return wishlist, ErrCantFindWishlist
+	}
+
+	return wishlist, nil
+}
+
+// WishlistPriceDrops compares every item on the user's lists with the current
+// catalog price and returns the ones that got cheaper
+func (d *DBClient) WishlistPriceDrops(ctx context.Context, user_id primitive.ObjectID) ([]models.PriceDrop, error) {
+
+	drops := make([]models.PriceDrop, 0)
+
+	wishlists, err := d.GetWishlists(ctx, user_id)
+	if err != nil {
+		return drops, err
+	}
+
+	ids := make([]primitive.ObjectID, 0)
+	for _, wishlist := range wishlists {
+		for _, item := range wishlist.Items {
+			ids = append(ids, item.Product_ID)
+		}
+	}
+	if len(ids) == 0 {
+		return drops, nil
+	}
+
+	cursor, err := d.productCollection.Find(ctx, bson.M{"_id": bson.M{"$in": uniqueIDs(ids)}})
+	if err != nil {
+		return drops, err
+	}
+
+	var products []models.Product
+	if err = cursor.All(ctx, &products); err != nil {
+		return drops, err
+	}
+
+	byID := make(map[primitive.ObjectID]models.Product, len(products))
+	for _, product := range products {
+		byID[product.Product_ID] = product
+	}
+
+	for _, wishlist := range wishlists {
+		for _, item := range wishlist.Items {
+			product, ok := byID[item.Product_ID]
+			if !ok {
+				continue
+			}
+
+			current, _, err := productLine(product, item.Variant_ID)
+			if err != nil || current.Price >= item.Price_At_Add {
+				continue
+			}
+
+			drop := item.Price_At_Add - current.Price
+			drops = append(drops, models.PriceDrop{
+				Wishlist_ID:   wishlist.Wishlist_ID,
+				Wishlist_Name: *wishlist.Name,
+				Item:          item,
+				Current_Price: current.Price,
+				Drop:          drop,
+				Drop_Percent:  math.Round(float64(drop)*1000/float64(item.Price_At_Add)) / 10,
+			})
+		}
+	}
+
+	return drops, nil
+}
+
+// pushWishlistItem appends the item to the list matched by filter unless the
+// same product and variant is already on it. With upsert the saved for later
+// list is created when the user has none yet.
+func (d *DBClient) pushWishlistItem(ctx context.Context, filter bson.M, item models.WishlistItem, upsert bool) error {
+
+	now := time.Now()
+
+	if upsert {
+		// make sure the list exists before the conditional push below
+		update := bson.M{
+			"$set": bson.M{"updated_at": now},
+			"$setOnInsert": bson.M{
+				"name":       savedForLaterName,
+				"items":      bson.A{},
+				"created_at": now,
+			},
+		}
+		_, err := d.wishlistCollection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+		if err != nil {
+			return err
+		}
+	}
+
+	pushFilter := bson.M{"items": bson.M{"$not": bson.M{"$elemMatch": itemMatch(item.Product_ID, item.Variant_ID)}}}
+	for k, v := range filter {
+		pushFilter[k] = v
+	}
+
+	_, err := d.wishlistCollection.UpdateOne(ctx, pushFilter, bson.M{
+		"$push": bson.M{"items": item},
+		"$set":  bson.M{"updated_at": now},
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func itemMatch(product_id primitive.ObjectID, variant_id *primitive.ObjectID) bson.M {
+	match := bson.M{"product_id": product_id}
+	if variant_id != nil {
+		match["variant_id"] = *variant_id
+	}
+	return match
+}
+
+func wishlistItem(line models.ProductUser) models.WishlistItem {
+	return models.WishlistItem{
+		Product_ID:   line.Product_ID,
+		Variant_ID:   line.Variant_ID,
+		SKU:          line.SKU,
+		Product_Name: line.Product_Name,
+		Image:        line.Image,
+		Price_At_Add: line.Price,
+		Added_At:     time.Now(),
+	}
+}
@@ -0,0 +1,75 @@
+package dto
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/mayuka-c/e-commerce/models"
+)
+
+type SignUp struct {
+	First_Name string `json:"first_name" validate:"required,min=2,max=30"`
+	Last_Name  string `json:"last_name" validate:"required,min=2,max=30"`
+	// bcrypt only reads the first 72 bytes
+	Password string `json:"password" validate:"required,min=6,max=72"`
+	Email    string `json:"email" validate:"required,email,max=254"`
+	Phone    string `json:"phone" validate:"required,phone"`
+	Locale   string `json:"locale" validate:"omitempty,oneof=en es"`
+}
+
+// Model returns the user to create. The caller sets the ids, the hashed
+// password and the tokens.
+func (s SignUp) Model() models.User {
+	return models.User{
+		First_Name: &s.First_Name,
+		Last_Name:  &s.Last_Name,
+		Password:   &s.Password,
+		Email:      &s.Email,
+		Phone:      &s.Phone,
+		Locale:     s.Locale,
+	}
+}
+
+type Login struct {
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
+}
+
+type ForgotPassword struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ResetPassword struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,min=6,max=72"`
+}
+
+type NotificationPrefs struct {
+	Locale            string   `json:"locale" validate:"omitempty,oneof=en es"`
+	Notifications_Off []string `json:"notifications_off" validate:"dive,oneof=order_placed order_shipped order_delivered order_refunded cart_reminder"`
+}
+
+func (n NotificationPrefs) Model() models.NotificationPrefs {
+	return models.NotificationPrefs{Locale: n.Locale, Notifications_Off: n.Notifications_Off}
+}
+
+type Address struct {
+	House   string `json:"house_name" validate:"required,max=100"`
+	Street  string `json:"street_name" validate:"required,max=100"`
+	City    string `json:"city_name" validate:"required,max=60"`
+	Pincode string `json:"pin_code" validate:"required,pincode"`
+}
+
+// Model returns the address with a new id
+func (a Address) Model() models.Address {
+	return models.Address{
+		Address_ID: primitive.NewObjectID(),
+		House:      &a.House,
+		Street:     &a.Street,
+		City:       &a.City,
+		Pincode:    &a.Pincode,
+	}
+}
+
+type Wishlist struct {
+	Name string `json:"name" validate:"required,min=1,max=60"`
+}
@@ -0,0 +1,110 @@
+package dto
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/mayuka-c/e-commerce/models"
+)
+
+// Product adds a product. Options and variants are set afterwards with
+// ProductOptions; ratings only come from reviews.
+type Product struct {
+	Product_Name string               `json:"product_name" validate:"required,min=2,max=200"`
+	Price        *int64               `json:"price" validate:"required,price"`
+	Image        string               `json:"image" validate:"required,max=500"`
+	Weight       *int64               `json:"weight" validate:"omitempty,min=0"`
+	SKU          *string              `json:"sku" validate:"omitempty,min=1,max=64"`
+	Category_IDs []primitive.ObjectID `json:"category_ids"`
+}
+
+// Model returns the product with a new id
+func (p Product) Model() models.Product {
+
+	price := uint64(*p.Price)
+	product := models.Product{
+		Product_ID:   primitive.NewObjectID(),
+		Product_Name: &p.Product_Name,
+		Price:        &price,
+		Image:        &p.Image,
+		SKU:          p.SKU,
+		Category_IDs: p.Category_IDs,
+		Options:      make([]models.ProductOption, 0),
+		Variants:     make([]models.ProductVariant, 0),
+	}
+	if product.Category_IDs == nil {
+		product.Category_IDs = make([]primitive.ObjectID, 0)
+	}
+	if p.Weight != nil {
+		weight := uint64(*p.Weight)
+		product.Weight = &weight
+	}
+
+	return product
+}
+
+type ProductOptions struct {
+	Options []ProductOption `json:"options" validate:"required,min=1,max=3,max_variants=100,dive"`
+}
+
+type ProductOption struct {
+	Name   string   `json:"name" validate:"required,max=30"`
+	Values []string `json:"values" validate:"required,min=1,max=20,unique,dive,required,max=30"`
+}
+
+func (p ProductOptions) Model() []models.ProductOption {
+	options := make([]models.ProductOption, 0, len(p.Options))
+	for _, option := range p.Options {
+		options = append(options, models.ProductOption{Name: option.Name, Values: option.Values})
+	}
+	return options
+}
+
+// VariantUpdate changes a variant; fields left out keep their value
+type VariantUpdate struct {
+	SKU   *string `json:"sku" validate:"omitempty,min=1,max=64"`
+	Price *int64  `json:"price" validate:"omitempty,price"`
+	Stock *int    `json:"stock" validate:"omitempty,min=0"`
+	Image *string `json:"image" validate:"omitempty,max=500"`
+}
+
+func (v VariantUpdate) Model() models.VariantUpdate {
+	update := models.VariantUpdate{SKU: v.SKU, Stock: v.Stock, Image: v.Image}
+	if v.Price != nil {
+		price := uint64(*v.Price)
+		update.Price = &price
+	}
+	return update
+}
+
+// Category adds or edits a category. The slug is made from the name when it
+// is left out.
+type Category struct {
+	Name      string              `json:"name" validate:"required,min=2,max=50"`
+	Slug      string              `json:"slug" validate:"omitempty,max=60"`
+	Parent_ID *primitive.ObjectID `json:"parent_id"`
+}
+
+func (c Category) Model() models.Category {
+	return models.Category{Name: &c.Name, Slug: c.Slug, Parent_ID: c.Parent_ID}
+}
+
+type CategoryAssignment struct {
+	Category_IDs []primitive.ObjectID `json:"category_ids" validate:"required,min=1"`
+}
+
+type Review struct {
+	Stars uint8  `json:"stars" validate:"required,min=1,max=5"`
+	Title string `json:"title" validate:"required,min=2,max=120"`
+	Body  string `json:"body" validate:"required,max=5000"`
+}
+
+// Model returns the review of the product by the user
+func (r Review) Model(product_id, user_id primitive.ObjectID) models.Review {
+	return models.Review{
+		Product_ID: product_id,
+		User_ID:    user_id,
+		Stars:      r.Stars,
+		Title:      &r.Title,
+		Body:       &r.Body,
+	}
+}
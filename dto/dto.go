@@ -0,0 +1,96 @@
+// Package dto holds the request bodies of the write endpoints. They are kept
+// apart from the persisted models so a client can only send the fields an
+// endpoint accepts; ids, tokens, ratings and orders are set by the server.
+// Each request converts to its model with Model().
+package dto
+
+import (
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// Patterns of the custom rules, shared with the OpenAPI document
+const (
+	// PhonePattern is an E.164 number: +, country code and subscriber number
+	PhonePattern = `^\+[1-9][0-9]{7,14}$`
+	// PincodePattern is a six digit Indian postal code
+	PincodePattern = `^[1-9][0-9]{5}$`
+)
+
+var (
+	phoneRegexp   = regexp.MustCompile(PhonePattern)
+	pincodeRegexp = regexp.MustCompile(PincodePattern)
+)
+
+// Validate checks requests against their validate tags. Besides the built in
+// rules it knows phone, pincode, price and max_variants.
+var Validate = newValidator()
+
+func newValidator() *validator.Validate {
+
+	v := validator.New()
+
+	// report errors by the JSON field names the client sent
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			return field.Name
+		}
+		return name
+	})
+
+	mustRegister(v, "phone", func(fl validator.FieldLevel) bool {
+		return phoneRegexp.MatchString(fl.Field().String())
+	})
+	mustRegister(v, "pincode", func(fl validator.FieldLevel) bool {
+		return pincodeRegexp.MatchString(fl.Field().String())
+	})
+	mustRegister(v, "price", validPrice)
+	mustRegister(v, "max_variants", maxVariants)
+
+	return v
+}
+
+func mustRegister(v *validator.Validate, tag string, fn validator.Func) {
+	if err := v.RegisterValidation(tag, fn); err != nil {
+		panic(err)
+	}
+}
+
+// validPrice accepts whole amounts that are not negative
+func validPrice(fl validator.FieldLevel) bool {
+	switch fl.Field().Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return fl.Field().Int() >= 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// maxVariants limits how many variants the option values combine into, the
+// product of their counts
+func maxVariants(fl validator.FieldLevel) bool {
+	limit, err := strconv.Atoi(fl.Param())
+	if err != nil {
+		return false
+	}
+
+	options, ok := fl.Field().Interface().([]ProductOption)
+	if !ok {
+		return false
+	}
+
+	variants := 1
+	for _, option := range options {
+		variants *= len(option.Values)
+		if variants > limit {
+			return false
+		}
+	}
+	return true
+}
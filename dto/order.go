@@ -0,0 +1,87 @@
+package dto
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/mayuka-c/e-commerce/models"
+)
+
+type CancelOrder struct {
+	Reason string `json:"reason" validate:"max=500"`
+}
+
+// ShippingMethod adds a shipping method. Weights are in grams; a zero max
+// weight or max order value means unbounded.
+type ShippingMethod struct {
+	Name                    string         `json:"name" validate:"required,max=60"`
+	Carrier                 string         `json:"carrier" validate:"required"`
+	Free_Shipping_Threshold *int64         `json:"free_shipping_threshold" validate:"omitempty,price"`
+	Zones                   []ShippingZone `json:"zones" validate:"required,min=1,dive"`
+	Rates                   []ShippingRate `json:"rates" validate:"required,min=1,dive"`
+}
+
+type ShippingZone struct {
+	Name             string   `json:"name" validate:"required,max=60"`
+	Pincode_Prefixes []string `json:"pincode_prefixes" validate:"required,min=1,dive,numeric,min=1,max=6"`
+}
+
+type ShippingRate struct {
+	Zone            string `json:"zone" validate:"required"`
+	Min_Weight      int64  `json:"min_weight" validate:"min=0"`
+	Max_Weight      int64  `json:"max_weight" validate:"min=0"`
+	Min_Order_Value int64  `json:"min_order_value" validate:"price"`
+	Max_Order_Value int64  `json:"max_order_value" validate:"price"`
+	Price           int64  `json:"price" validate:"price"`
+}
+
+// Model returns the method with a new id
+func (s ShippingMethod) Model() models.ShippingMethod {
+
+	method := models.ShippingMethod{
+		Method_ID: primitive.NewObjectID(),
+		Name:      &s.Name,
+		Carrier:   &s.Carrier,
+		Zones:     make([]models.ShippingZone, 0, len(s.Zones)),
+		Rates:     make([]models.ShippingRate, 0, len(s.Rates)),
+	}
+	if s.Free_Shipping_Threshold != nil {
+		threshold := uint64(*s.Free_Shipping_Threshold)
+		method.Free_Shipping_Threshold = &threshold
+	}
+
+	for i := range s.Zones {
+		zone := s.Zones[i]
+		method.Zones = append(method.Zones, models.ShippingZone{Name: &zone.Name, Pincode_Prefixes: zone.Pincode_Prefixes})
+	}
+	for i := range s.Rates {
+		rate := s.Rates[i]
+		method.Rates = append(method.Rates, models.ShippingRate{
+			Zone:            &rate.Zone,
+			Min_Weight:      uint64(rate.Min_Weight),
+			Max_Weight:      uint64(rate.Max_Weight),
+			Min_Order_Value: uint64(rate.Min_Order_Value),
+			Max_Order_Value: uint64(rate.Max_Order_Value),
+			Price:           uint64(rate.Price),
+		})
+	}
+
+	return method
+}
+
+type ShipmentEvent struct {
+	Status      string     `json:"status" validate:"required,oneof=label_created in_transit out_for_delivery delivered exception"`
+	Location    string     `json:"location" validate:"max=200"`
+	Description string     `json:"description" validate:"max=500"`
+	Occurred_At *time.Time `json:"occurred_at"`
+}
+
+// Model returns the event, dated now when the carrier sent no time
+func (s ShipmentEvent) Model() models.ShipmentEvent {
+	event := models.ShipmentEvent{Status: s.Status, Location: s.Location, Description: s.Description, Occurred_At: time.Now()}
+	if s.Occurred_At != nil && !s.Occurred_At.IsZero() {
+		event.Occurred_At = *s.Occurred_At
+	}
+	return event
+}
@@ -0,0 +1,27 @@
+package dto
+
+import (
+	"github.com/mayuka-c/e-commerce/models"
+)
+
+// WebhookEndpoint subscribes a URL to event types, "*" for all of them
+type WebhookEndpoint struct {
+	URL         string   `json:"url" validate:"required,http_url,max=2000"`
+	Events      []string `json:"events" validate:"required,min=1,dive,required"`
+	Description string   `json:"description" validate:"max=200"`
+}
+
+func (w WebhookEndpoint) Model() models.WebhookEndpoint {
+	return models.WebhookEndpoint{URL: &w.URL, Events: w.Events, Description: w.Description}
+}
+
+// WebhookEndpointUpdate changes an endpoint; fields left out keep their value
+type WebhookEndpointUpdate struct {
+	URL    *string  `json:"url" validate:"omitempty,http_url,max=2000"`
+	Events []string `json:"events" validate:"omitempty,min=1,dive,required"`
+	Active *bool    `json:"active"`
+}
+
+func (w WebhookEndpointUpdate) Model() models.WebhookEndpointUpdate {
+	return models.WebhookEndpointUpdate{URL: w.URL, Events: w.Events, Active: w.Active}
+}
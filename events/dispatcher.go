@@ -0,0 +1,159 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/mayuka-c/e-commerce/models"
+	"github.com/mayuka-c/e-commerce/telemetry"
+)
+
+var (
+	ErrNoEvent = errors.New("no outbox event is due")
+)
+
+// Store is the outbox the dispatcher reads from. ClaimOutboxEvent returns
+// ErrNoEvent when nothing is due.
+type Store interface {
+	ClaimOutboxEvent(ctx context.Context, lease time.Duration) (models.OutboxEvent, error)
+	MarkEventPublishedTo(ctx context.Context, event_id primitive.ObjectID, sink string) error
+	CompleteOutboxEvent(ctx context.Context, event_id primitive.ObjectID) error
+	FailOutboxEvent(ctx context.Context, event_id primitive.ObjectID, publishErr error, next time.Time) error
+}
+
+// Dispatcher publishes outbox events to the sinks. Several replicas can run
+// one each; an event is leased to one dispatcher at a time.
+type Dispatcher struct {
+	store Store
+	sinks []Sink
+
+	Lease        time.Duration
+	PollInterval time.Duration
+	BaseBackoff  time.Duration
+	MaxBackoff   time.Duration
+}
+
+func NewDispatcher(store Store, sinks ...Sink) *Dispatcher {
+	return &Dispatcher{
+		store:        store,
+		sinks:        sinks,
+		Lease:        time.Minute,
+		PollInterval: time.Second,
+		BaseBackoff:  5 * time.Second,
+		MaxBackoff:   30 * time.Minute,
+	}
+}
+
+// Run publishes events until ctx is done
+func (d *Dispatcher) Run(ctx context.Context) {
+
+	ticker := time.NewTicker(d.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		d.drain(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (d *Dispatcher) drain(ctx context.Context) {
+
+	for ctx.Err() == nil {
+		record, err := d.store.ClaimOutboxEvent(ctx, d.Lease)
+		if err == ErrNoEvent {
+			return
+		}
+		if err != nil {
+			log.Error(err)
+			return
+		}
+
+		d.publish(record)
+	}
+}
+
+// publish hands a claimed event to the sinks and records the outcome. It
+// finishes even when the dispatcher is being stopped; the lease bounds it.
+func (d *Dispatcher) publish(record models.OutboxEvent) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), d.Lease)
+	defer cancel()
+
+	eventCtx, span := telemetry.Start(ctx, "event "+record.Type, trace.WithAttributes(
+		attribute.String("event.id", record.Event_ID.Hex()),
+		attribute.Int("event.attempt", record.Attempts+1),
+	))
+	err := d.dispatch(eventCtx, record)
+	telemetry.End(span, err)
+	if err != nil {
+		log.WithFields(log.Fields{"event": record.Event_ID.Hex(), "type": record.Type, "attempt": record.Attempts + 1}).Error(err)
+		err = d.store.FailOutboxEvent(ctx, record.Event_ID, err, time.Now().Add(d.backoff(record.Attempts+1)))
+	} else {
+		err = d.store.CompleteOutboxEvent(ctx, record.Event_ID)
+	}
+	if err != nil {
+		log.Error(err)
+	}
+}
+
+// dispatch publishes the event to every sink that has not taken it yet
+func (d *Dispatcher) dispatch(ctx context.Context, record models.OutboxEvent) error {
+
+	event, err := FromOutbox(record)
+	if err != nil {
+		return err
+	}
+
+	done := make(map[string]bool, len(record.Published_To))
+	for _, name := range record.Published_To {
+		done[name] = true
+	}
+
+	failures := make([]string, 0)
+	for _, sink := range d.sinks {
+		if done[sink.Name()] {
+			continue
+		}
+
+		if err := sink.Publish(ctx, event); err != nil {
+			failures = append(failures, sink.Name()+": "+err.Error())
+			continue
+		}
+
+		if err := d.store.MarkEventPublishedTo(ctx, record.Event_ID, sink.Name()); err != nil {
+			return err
+		}
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("publishing failed for %s", strings.Join(failures, "; "))
+	}
+
+	return nil
+}
+
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+
+	delay := d.BaseBackoff
+	for i := 1; i < attempts && delay < d.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > d.MaxBackoff {
+		delay = d.MaxBackoff
+	}
+
+	return delay
+}
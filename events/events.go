@@ -0,0 +1,175 @@
+package events
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/mayuka-c/e-commerce/models"
+)
+
+// Event types
+const (
+	UserRegisteredType  = "user.registered"
+	CartItemAddedType   = "cart.item_added"
+	CartItemRemovedType = "cart.item_removed"
+	OrderPlacedType     = "order.placed"
+	OrderCancelledType  = "order.cancelled"
+	ShipmentUpdatedType = "shipment.status_changed"
+)
+
+// Payload is implemented by every domain event. AggregateID is the user or
+// order the event is about, so consumers can keep per-aggregate ordering.
+type Payload interface {
+	EventType() string
+	AggregateID() primitive.ObjectID
+}
+
+type UserRegistered struct {
+	User_ID    primitive.ObjectID `json:"user_id" bson:"user_id"`
+	Email      string             `json:"email" bson:"email"`
+	First_Name string             `json:"first_name" bson:"first_name"`
+	Last_Name  string             `json:"last_name" bson:"last_name"`
+}
+
+type CartItemAdded struct {
+	User_ID    primitive.ObjectID  `json:"user_id" bson:"user_id"`
+	Product_ID primitive.ObjectID  `json:"product_id" bson:"product_id"`
+	Variant_ID *primitive.ObjectID `json:"variant_id,omitempty" bson:"variant_id,omitempty"`
+	Price      int                 `json:"price" bson:"price"`
+}
+
+type CartItemRemoved struct {
+	User_ID    primitive.ObjectID  `json:"user_id" bson:"user_id"`
+	Product_ID primitive.ObjectID  `json:"product_id" bson:"product_id"`
+	Variant_ID *primitive.ObjectID `json:"variant_id,omitempty" bson:"variant_id,omitempty"`
+}
+
+type OrderPlaced struct {
+	User_ID        primitive.ObjectID   `json:"user_id" bson:"user_id"`
+	Order_ID       primitive.ObjectID   `json:"order_id" bson:"order_id"`
+	Items          []models.ProductUser `json:"items" bson:"items"`
+	Total_Price    int                  `json:"total_price" bson:"total_price"`
+	Payment_Method models.Payment       `json:"payment_method" bson:"payment_method"`
+	Ordered_At     time.Time            `json:"ordered_at" bson:"ordered_at"`
+}
+
+type OrderCancelled struct {
+	User_ID      primitive.ObjectID `json:"user_id" bson:"user_id"`
+	Order_ID     primitive.ObjectID `json:"order_id" bson:"order_id"`
+	Total_Price  int                `json:"total_price" bson:"total_price"`
+	Reason       string             `json:"reason" bson:"reason"`
+	Cancelled_At time.Time          `json:"cancelled_at" bson:"cancelled_at"`
+	// set when the order was paid digitally and the money goes back
+	Refund_Amount int `json:"refund_amount" bson:"refund_amount"`
+}
+
+type ShipmentStatusChanged struct {
+	Shipment_ID     primitive.ObjectID `json:"shipment_id" bson:"shipment_id"`
+	Order_ID        primitive.ObjectID `json:"order_id" bson:"order_id"`
+	User_ID         primitive.ObjectID `json:"user_id" bson:"user_id"`
+	Carrier         string             `json:"carrier" bson:"carrier"`
+	Tracking_Number string             `json:"tracking_number" bson:"tracking_number"`
+	Old_Status      string             `json:"old_status" bson:"old_status"`
+	Status          string             `json:"status" bson:"status"`
+	Changed_At      time.Time          `json:"changed_at" bson:"changed_at"`
+}
+
+func (e UserRegistered) EventType() string                      { return UserRegisteredType }
+func (e UserRegistered) AggregateID() primitive.ObjectID        { return e.User_ID }
+func (e CartItemAdded) EventType() string                       { return CartItemAddedType }
+func (e CartItemAdded) AggregateID() primitive.ObjectID         { return e.User_ID }
+func (e CartItemRemoved) EventType() string                     { return CartItemRemovedType }
+func (e CartItemRemoved) AggregateID() primitive.ObjectID       { return e.User_ID }
+func (e OrderPlaced) EventType() string                         { return OrderPlacedType }
+func (e OrderPlaced) AggregateID() primitive.ObjectID           { return e.Order_ID }
+func (e OrderCancelled) EventType() string                      { return OrderCancelledType }
+func (e OrderCancelled) AggregateID() primitive.ObjectID        { return e.Order_ID }
+func (e ShipmentStatusChanged) EventType() string               { return ShipmentUpdatedType }
+func (e ShipmentStatusChanged) AggregateID() primitive.ObjectID { return e.Order_ID }
+
+// payloadTypes turns a stored event type back into its payload struct
+var payloadTypes = map[string]func() Payload{
+	UserRegisteredType:  func() Payload { return &UserRegistered{} },
+	CartItemAddedType:   func() Payload { return &CartItemAdded{} },
+	CartItemRemovedType: func() Payload { return &CartItemRemoved{} },
+	OrderPlacedType:     func() Payload { return &OrderPlaced{} },
+	OrderCancelledType:  func() Payload { return &OrderCancelled{} },
+	ShipmentUpdatedType: func() Payload { return &ShipmentStatusChanged{} },
+}
+
+// Types lists every event type, for subscription validation
+func Types() []string {
+	types := make([]string, 0, len(payloadTypes))
+	for t := range payloadTypes {
+		types = append(types, t)
+	}
+	return types
+}
+
+// KnownType reports whether the event type exists
+func KnownType(eventType string) bool {
+	_, ok := payloadTypes[eventType]
+	return ok
+}
+
+// Event is a published domain event as sinks receive it
+type Event struct {
+	ID           primitive.ObjectID `json:"id"`
+	Type         string             `json:"type"`
+	Subject      string             `json:"subject"`
+	Aggregate_ID primitive.ObjectID `json:"aggregate_id"`
+	Occurred_At  time.Time          `json:"occurred_at"`
+	Data         Payload            `json:"data"`
+}
+
+// NewOutboxEvent builds the outbox record for the payload
+func NewOutboxEvent(payload Payload, now time.Time) (models.OutboxEvent, error) {
+
+	raw, err := bson.Marshal(payload)
+	if err != nil {
+		return models.OutboxEvent{}, err
+	}
+
+	return models.OutboxEvent{
+		Event_ID:        primitive.NewObjectID(),
+		Type:            payload.EventType(),
+		Aggregate_ID:    payload.AggregateID(),
+		Payload:         raw,
+		Occurred_At:     now,
+		Next_Attempt_At: now,
+		Published_To:    make([]string, 0),
+	}, nil
+}
+
+// FromOutbox decodes the outbox record into the event sinks publish
+func FromOutbox(record models.OutboxEvent) (Event, error) {
+
+	newPayload, ok := payloadTypes[record.Type]
+	if !ok {
+		return Event{}, fmt.Errorf("unknown event type %q", record.Type)
+	}
+
+	payload := newPayload()
+	if err := bson.Unmarshal(record.Payload, payload); err != nil {
+		return Event{}, err
+	}
+
+	return Event{
+		ID:           record.Event_ID,
+		Type:         record.Type,
+		Subject:      Subject(record.Type),
+		Aggregate_ID: record.Aggregate_ID,
+		Occurred_At:  record.Occurred_At,
+		Data:         payload,
+	}, nil
+}
+
+// Subject is the NATS-style subject of the event type, e.g.
+// "ecommerce.order.placed"
+func Subject(eventType string) string {
+	return "ecommerce." + strings.ReplaceAll(eventType, "_", "-")
+}
@@ -0,0 +1,143 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/mayuka-c/e-commerce/telemetry"
+)
+
+// Sink receives published events. Delivery is at least once: a sink may see
+// the same event again after a failure, and should use Event.ID to drop
+// duplicates.
+type Sink interface {
+	Name() string
+	Publish(ctx context.Context, event Event) error
+}
+
+// Subscriber handles an event published on the Bus
+type Subscriber func(ctx context.Context, event Event) error
+
+// Bus delivers events to subscribers inside the service
+type Bus struct {
+	mu          sync.RWMutex
+	subscribers map[string][]Subscriber
+}
+
+func NewBus() *Bus {
+	return &Bus{subscribers: make(map[string][]Subscriber)}
+}
+
+// Subscribe registers the subscriber for the event type, or for every event
+// when eventType is "*"
+func (b *Bus) Subscribe(eventType string, subscriber Subscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscribers[eventType] = append(b.subscribers[eventType], subscriber)
+}
+
+func (b *Bus) Name() string {
+	return "bus"
+}
+
+func (b *Bus) Publish(ctx context.Context, event Event) error {
+
+	b.mu.RLock()
+	subscribers := append(append([]Subscriber{}, b.subscribers[event.Type]...), b.subscribers["*"]...)
+	b.mu.RUnlock()
+
+	for _, subscriber := range subscribers {
+		if err := subscriber(ctx, event); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WebhookSink posts every event as JSON to a fixed URL
+type WebhookSink struct {
+	url    string
+	client *http.Client
+}
+
+func NewWebhookSink(url string) *WebhookSink {
+	return &WebhookSink{url: url, client: &http.Client{Timeout: 10 * time.Second, Transport: telemetry.Transport(http.DefaultTransport)}}
+}
+
+func (w *WebhookSink) Name() string {
+	return "webhook:" + w.url
+}
+
+func (w *WebhookSink) Publish(ctx context.Context, event Event) error {
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event-ID", event.ID.Hex())
+	req.Header.Set("X-Event-Type", event.Type)
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook %s answered %s", w.url, resp.Status)
+	}
+
+	return nil
+}
+
+// FileSink appends every event as one JSON line to a file. Lines carry the
+// NATS-style subject, so a local consumer can tail the file the way it would
+// subscribe to a subject.
+type FileSink struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewFileSink(path string) *FileSink {
+	return &FileSink{path: path}
+}
+
+func (f *FileSink) Name() string {
+	return "file:" + f.path
+}
+
+func (f *FileSink) Publish(ctx context.Context, event Event) error {
+
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	file, err := os.OpenFile(f.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(append(line, '\n'))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
@@ -0,0 +1,129 @@
+// Package health answers the liveness and readiness probes. The service is
+// live while it can answer at all; it is ready while every dependency check
+// passes and it is not shutting down.
+package health
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Statuses of a Report and of each check in it
+const (
+	StatusOK       = "ok"
+	StatusFailing  = "failing"
+	StatusDraining = "draining"
+)
+
+// Check reports whether a dependency is usable
+type Check func(ctx context.Context) error
+
+// Report is the body of both probes. Checks maps each check to ok or the
+// error it returned.
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// Checker runs the readiness checks
+type Checker struct {
+	mu       sync.RWMutex
+	checks   map[string]Check
+	draining atomic.Bool
+
+	// Timeout bounds each check
+	Timeout time.Duration
+}
+
+func NewChecker() *Checker {
+	return &Checker{
+		checks:  make(map[string]Check),
+		Timeout: 2 * time.Second,
+	}
+}
+
+// Add registers a readiness check under name
+func (h *Checker) Add(name string, check Check) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checks[name] = check
+}
+
+// Drain fails readiness from now on, so load balancers stop sending requests
+// while the in-flight ones finish
+func (h *Checker) Drain() {
+	h.draining.Store(true)
+}
+
+// Check runs every check at once and reports the result
+func (h *Checker) Check(ctx context.Context) Report {
+
+	h.mu.RLock()
+	names := make([]string, 0, len(h.checks))
+	for name := range h.checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	checks := make([]Check, len(names))
+	for i, name := range names {
+		checks[i] = h.checks[name]
+	}
+	h.mu.RUnlock()
+
+	results := make([]string, len(names))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, h.Timeout)
+			defer cancel()
+			results[i] = StatusOK
+			if err := check(checkCtx); err != nil {
+				results[i] = err.Error()
+			}
+		}(i, check)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]string, len(names))}
+	for i, name := range names {
+		report.Checks[name] = results[i]
+		if results[i] != StatusOK {
+			report.Status = StatusFailing
+		}
+	}
+	if h.draining.Load() {
+		report.Status = StatusDraining
+	}
+
+	return report
+}
+
+// Live answers the liveness probe. It checks no dependency, so an outage of
+// one does not get the process restarted.
+func (h *Checker) Live() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, Report{Status: StatusOK})
+	}
+}
+
+// Ready answers the readiness probe with 200 when the report is ok and 503
+// otherwise
+func (h *Checker) Ready() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		report := h.Check(c.Request.Context())
+
+		status := http.StatusOK
+		if report.Status != StatusOK {
+			status = http.StatusServiceUnavailable
+		}
+		c.JSON(status, report)
+	}
+}
@@ -0,0 +1,149 @@
+package jobs
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidSchedule = errors.New("schedule must have five fields: minute hour day-of-month month day-of-week")
+)
+
+// Schedule is a parsed five field cron expression. Each field accepts *, a
+// number, a range a-b, a list a,b,c and a step */n or a-b/n. Named months and
+// weekdays are not supported. As in cron, when both day-of-month and
+// day-of-week are restricted a day matching either one runs.
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+	domStar, dowStar              bool
+}
+
+var cronFields = []struct {
+	name     string
+	min, max int
+}{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day-of-month", 1, 31},
+	{"month", 1, 12},
+	{"day-of-week", 0, 6},
+}
+
+// ParseSchedule parses a cron expression such as "*/15 * * * *"
+func ParseSchedule(spec string) (Schedule, error) {
+
+	var s Schedule
+
+	fields := strings.Fields(spec)
+	if len(fields) != len(cronFields) {
+		return s, ErrInvalidSchedule
+	}
+
+	bits := make([]uint64, len(fields))
+	for i, field := range fields {
+		b, err := parseCronField(field, cronFields[i].min, cronFields[i].max)
+		if err != nil {
+			return s, fmt.Errorf("%s field %q: %w", cronFields[i].name, field, err)
+		}
+		bits[i] = b
+	}
+
+	s.minute, s.hour, s.dom, s.month, s.dow = bits[0], bits[1], bits[2], bits[3], bits[4]
+	s.domStar = strings.HasPrefix(fields[2], "*")
+	s.dowStar = strings.HasPrefix(fields[4], "*")
+
+	return s, nil
+}
+
+func parseCronField(field string, min, max int) (uint64, error) {
+
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		i := strings.Index(part, "/")
+		if i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, errors.New("step must be a positive number")
+			}
+			rangePart, step = part[:i], n
+		}
+
+		lo, hi := min, max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if lo, err = strconv.Atoi(bounds[0]); err != nil {
+				return 0, errors.New("range start is not a number")
+			}
+			if hi, err = strconv.Atoi(bounds[1]); err != nil {
+				return 0, errors.New("range end is not a number")
+			}
+		default:
+			n, err := strconv.Atoi(rangePart)
+			if err != nil {
+				return 0, errors.New("value is not a number")
+			}
+			lo, hi = n, n
+			if i >= 0 {
+				// "5/15" means from 5 to the end in steps of 15
+				hi = max
+			}
+		}
+
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("values must be between %d and %d", min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+
+	return bits, nil
+}
+
+// Next returns the first minute after t that matches the schedule
+func (s Schedule) Next(t time.Time) time.Time {
+
+	t = t.Truncate(time.Minute).Add(time.Minute)
+
+	// every schedule matches at least once in four years (Feb 29)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+
+	return time.Time{}
+}
+
+func (s Schedule) dayMatches(t time.Time) bool {
+
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+
+	if s.domStar || s.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
@@ -0,0 +1,90 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseScheduleRejects(t *testing.T) {
+
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 7",
+		"*/0 * * * *",
+		"*/x * * * *",
+		"5-1 * * * *",
+		"1-x * * * *",
+		"a * * * *",
+		"1,,2 * * * *",
+	} {
+		if _, err := ParseSchedule(spec); err == nil {
+			t.Errorf("ParseSchedule(%q) succeeded, want an error", spec)
+		}
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+
+	at := func(value string) time.Time {
+		parsed, err := time.Parse("2006-01-02 15:04", value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parsed
+	}
+
+	// 2024-01-01 is a Monday
+	tests := []struct {
+		spec string
+		from string
+		want string
+	}{
+		{"*/15 * * * *", "2024-01-01 10:07", "2024-01-01 10:15"},
+		{"*/15 * * * *", "2024-01-01 10:45", "2024-01-01 11:00"},
+		{"* * * * *", "2024-01-01 10:07", "2024-01-01 10:08"},
+		// strictly after: a matching minute moves on to the next match
+		{"0 3 * * *", "2024-01-01 03:00", "2024-01-02 03:00"},
+		{"0 8,20 * * *", "2024-01-01 09:00", "2024-01-01 20:00"},
+		{"5/20 * * * *", "2024-01-01 10:00", "2024-01-01 10:05"},
+		{"5/20 * * * *", "2024-01-01 10:46", "2024-01-01 11:05"},
+		{"0 9-17/4 * * *", "2024-01-01 14:00", "2024-01-01 17:00"},
+		{"30 9 * * 1-5", "2024-01-06 12:00", "2024-01-08 09:30"},
+		{"0 0 1 * *", "2024-12-15 00:00", "2025-01-01 00:00"},
+		{"0 0 13 * *", "2024-01-01 00:00", "2024-01-13 00:00"},
+		{"0 0 * * 5", "2024-01-01 00:00", "2024-01-05 00:00"},
+		// both days restricted: either one matches, as in cron
+		{"0 0 13 * 5", "2024-01-01 00:00", "2024-01-05 00:00"},
+		{"0 0 13 * 5", "2024-01-05 00:00", "2024-01-12 00:00"},
+		{"0 0 13 * 5", "2024-01-12 00:00", "2024-01-13 00:00"},
+		{"0 0 29 2 *", "2024-03-01 00:00", "2028-02-29 00:00"},
+	}
+
+	for _, test := range tests {
+		schedule, err := ParseSchedule(test.spec)
+		if err != nil {
+			t.Errorf("ParseSchedule(%q): %v", test.spec, err)
+			continue
+		}
+		if got := schedule.Next(at(test.from)); !got.Equal(at(test.want)) {
+			t.Errorf("%q after %s: got %s, want %s", test.spec, test.from, got.Format("2006-01-02 15:04"), test.want)
+		}
+	}
+}
+
+func TestScheduleNextNever(t *testing.T) {
+
+	schedule, err := ParseSchedule("0 0 31 2 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := schedule.Next(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)); !got.IsZero() {
+		t.Errorf("February 31st: got %s, want the zero time", got)
+	}
+}
@@ -0,0 +1,238 @@
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/mayuka-c/e-commerce/database"
+	"github.com/mayuka-c/e-commerce/logging"
+	"github.com/mayuka-c/e-commerce/models"
+	"github.com/mayuka-c/e-commerce/telemetry"
+)
+
+// Handler runs one job. A returned error schedules a retry. Jobs run at least
+// once, so handlers must be safe to run again for the same job.
+type Handler func(ctx context.Context, job models.Job) error
+
+type recurringEntry struct {
+	name     string
+	spec     string
+	kind     string
+	schedule Schedule
+}
+
+// Runner claims due jobs from the Jobs collection and runs their handlers.
+// Every replica runs one; the atomic claims in the database make sure each job
+// and each recurring run is taken by a single replica.
+type Runner struct {
+	dbClient  *database.DBClient
+	worker    string
+	handlers  map[string]Handler
+	recurring []recurringEntry
+
+	Lease        time.Duration
+	PollInterval time.Duration
+	BaseBackoff  time.Duration
+	MaxBackoff   time.Duration
+}
+
+func NewRunner(dbClient *database.DBClient) *Runner {
+	return &Runner{
+		dbClient:     dbClient,
+		worker:       workerID(),
+		handlers:     make(map[string]Handler),
+		Lease:        5 * time.Minute,
+		PollInterval: 5 * time.Second,
+		BaseBackoff:  30 * time.Second,
+		MaxBackoff:   time.Hour,
+	}
+}
+
+// Register sets the handler for jobs of the kind
+func (r *Runner) Register(kind string, handler Handler) {
+	r.handlers[kind] = handler
+}
+
+// Schedule enqueues a job of the kind whenever the cron spec fires. It must be
+// called before Run.
+func (r *Runner) Schedule(name, spec, kind string) error {
+
+	schedule, err := ParseSchedule(spec)
+	if err != nil {
+		return err
+	}
+	if _, ok := r.handlers[kind]; !ok {
+		return fmt.Errorf("no handler registered for job kind %q", kind)
+	}
+
+	r.recurring = append(r.recurring, recurringEntry{name: name, spec: spec, kind: kind, schedule: schedule})
+	return nil
+}
+
+// Run registers the recurring schedules and then polls for work until ctx is
+// done. A job that is running by then is finished before Run returns.
+func (r *Runner) Run(ctx context.Context) error {
+
+	now := time.Now()
+	for _, entry := range r.recurring {
+		err := r.dbClient.RegisterRecurringJob(ctx, entry.name, entry.spec, entry.kind, entry.schedule.Next(now))
+		if err != nil {
+			return err
+		}
+	}
+
+	log.Printf("Job runner %s started", r.worker)
+
+	ticker := time.NewTicker(r.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		r.enqueueRecurring(ctx)
+		r.drain(ctx)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// enqueueRecurring enqueues a job for every recurring schedule that is due
+func (r *Runner) enqueueRecurring(ctx context.Context) {
+
+	if len(r.recurring) == 0 {
+		return
+	}
+
+	now := time.Now()
+	due, err := r.dbClient.DueRecurringJobs(ctx, now)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+
+	for _, recurring := range due {
+		schedule, err := ParseSchedule(recurring.Schedule)
+		if err != nil {
+			log.Error(err)
+			continue
+		}
+
+		_, err = r.dbClient.ClaimRecurringJob(ctx, recurring, now, schedule.Next(now))
+		if err != nil && err != database.ErrRecurringJobClaimed {
+			log.Error(err)
+		}
+	}
+}
+
+// drain runs due jobs until there are none left
+func (r *Runner) drain(ctx context.Context) {
+
+	kinds := make([]string, 0, len(r.handlers))
+	for kind := range r.handlers {
+		kinds = append(kinds, kind)
+	}
+	if len(kinds) == 0 {
+		return
+	}
+
+	buried, err := r.dbClient.BuryAbandonedJobs(ctx)
+	if err != nil {
+		log.Error(err)
+	}
+	if buried > 0 {
+		log.Warnf("Marked %d jobs dead whose lease expired on their last attempt", buried)
+	}
+
+	for ctx.Err() == nil {
+		job, err := r.dbClient.ClaimJob(ctx, r.worker, kinds, r.Lease)
+		if err == database.ErrNoJob {
+			return
+		}
+		if err != nil {
+			log.Error(err)
+			return
+		}
+
+		r.run(job)
+	}
+}
+
+// run runs a claimed job to the end even when the runner is being stopped;
+// the lease bounds it, and recording the outcome saves a needless retry
+func (r *Runner) run(job models.Job) {
+
+	ctx := context.Background()
+	jobCtx, cancel := context.WithTimeout(ctx, r.Lease)
+	defer cancel()
+	jobCtx = logging.WithFields(jobCtx, log.Fields{"job": job.Job_ID.Hex(), "kind": job.Kind, "attempt": job.Attempts})
+
+	jobCtx, span := telemetry.Start(jobCtx, "job "+job.Kind, trace.WithAttributes(
+		attribute.String("job.id", job.Job_ID.Hex()),
+		attribute.Int("job.attempt", job.Attempts),
+	))
+	err := r.call(jobCtx, job)
+	telemetry.End(span, err)
+	if err == nil {
+		err = r.dbClient.CompleteJob(ctx, job, r.worker)
+		if err != nil {
+			log.Error(err)
+		}
+		return
+	}
+
+	logging.From(jobCtx).Error(err)
+
+	err = r.dbClient.FailJob(ctx, job, r.worker, err, time.Now().Add(r.backoff(job.Attempts)))
+	if err != nil {
+		log.Error(err)
+	}
+}
+
+// call runs the handler, turning a panic into a job error
+func (r *Runner) call(ctx context.Context, job models.Job) (err error) {
+
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("job panicked: %v", p)
+		}
+	}()
+
+	return r.handlers[job.Kind](ctx, job)
+}
+
+// backoff doubles the delay with every attempt, up to MaxBackoff
+func (r *Runner) backoff(attempts int) time.Duration {
+
+	delay := r.BaseBackoff
+	for i := 1; i < attempts && delay < r.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > r.MaxBackoff {
+		delay = r.MaxBackoff
+	}
+
+	return delay
+}
+
+func workerID() string {
+
+	host, err := os.Hostname()
+	if err != nil {
+		host = "worker"
+	}
+
+	raw := make([]byte, 4)
+	_, _ = rand.Read(raw)
+
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), hex.EncodeToString(raw))
+}
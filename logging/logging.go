@@ -0,0 +1,60 @@
+// Package logging configures the process wide logrus logger and carries a
+// request scoped entry in context.Context, so every line of a request shares
+// its request id, route and user.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type contextKey struct{}
+
+// Setup sets the level and format of the standard logger and installs the
+// redaction hook. format is json or text.
+func Setup(level, format string) error {
+
+	lvl, err := log.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case "json":
+		log.SetFormatter(&log.JSONFormatter{
+			FieldMap: log.FieldMap{log.FieldKeyTime: "ts", log.FieldKeyMsg: "msg"},
+		})
+	case "text":
+		log.SetFormatter(&log.TextFormatter{FullTimestamp: true})
+	default:
+		return fmt.Errorf("unknown LOG_FORMAT %q, use json or text", format)
+	}
+
+	log.SetOutput(os.Stdout)
+	log.SetLevel(lvl)
+	log.AddHook(RedactHook{})
+
+	return nil
+}
+
+// With returns a copy of ctx carrying entry
+func With(ctx context.Context, entry *log.Entry) context.Context {
+	return context.WithValue(ctx, contextKey{}, entry)
+}
+
+// From returns the entry carried by ctx, or one of the standard logger when
+// there is none
+func From(ctx context.Context) *log.Entry {
+	if entry, ok := ctx.Value(contextKey{}).(*log.Entry); ok {
+		return entry
+	}
+	return log.NewEntry(log.StandardLogger())
+}
+
+// WithFields adds fields to the entry carried by ctx
+func WithFields(ctx context.Context, fields log.Fields) context.Context {
+	return With(ctx, From(ctx).WithFields(fields))
+}
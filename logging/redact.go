@@ -0,0 +1,119 @@
+package logging
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Redacted replaces secrets in log lines
+const Redacted = "[REDACTED]"
+
+// secretKeys are field names whose values are never logged
+var secretKeys = []string{"password", "token", "secret", "authorization", "cookie", "api_key"}
+
+// secretValues match secrets inside messages and string fields: tokens in
+// query strings, JWTs, bearer credentials and webhook signing secrets
+var secretValues = []struct {
+	pattern *regexp.Regexp
+	replace string
+}{
+	{regexp.MustCompile(`(?i)((?:token|secret|password)=)[^&\s"]+`), "${1}" + Redacted},
+	{regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`), Redacted},
+	{regexp.MustCompile(`(?i)(bearer\s+)\S+`), "${1}" + Redacted},
+	{regexp.MustCompile(`whsec_[0-9a-f]+`), Redacted},
+}
+
+// RedactHook blanks secret fields and scrubs secrets from messages before an
+// entry is written
+type RedactHook struct{}
+
+func (RedactHook) Levels() []log.Level {
+	return log.AllLevels
+}
+
+func (RedactHook) Fire(entry *log.Entry) error {
+
+	entry.Message = RedactString(entry.Message)
+
+	// entries share their Data map with the entry they were derived from
+	data := make(log.Fields, len(entry.Data))
+	for key, value := range entry.Data {
+		switch {
+		case secretKey(key):
+			data[key] = Redacted
+		default:
+			switch v := value.(type) {
+			case string:
+				value = RedactString(v)
+			case error:
+				value = RedactString(v.Error())
+			}
+			data[key] = value
+		}
+	}
+	entry.Data = data
+
+	return nil
+}
+
+// RedactString scrubs the secrets secretValues recognise from s
+func RedactString(s string) string {
+	for _, secret := range secretValues {
+		s = secret.pattern.ReplaceAllString(s, secret.replace)
+	}
+	return s
+}
+
+// RedactMap returns a copy of values with secret keys blanked and secrets
+// scrubbed from strings, descending into nested maps and lists
+func RedactMap(values map[string]interface{}) map[string]interface{} {
+
+	redacted := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		if secretKey(key) {
+			redacted[key] = Redacted
+			continue
+		}
+		redacted[key] = redactValue(value)
+	}
+	return redacted
+}
+
+func redactValue(value interface{}) interface{} {
+
+	if s, ok := value.(string); ok {
+		return RedactString(s)
+	}
+
+	// decoded documents use named types such as primitive.M and primitive.A
+	v := reflect.ValueOf(value)
+	switch {
+	case v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String:
+		values := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			values[iter.Key().String()] = iter.Value().Interface()
+		}
+		return RedactMap(values)
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Interface:
+		list := make([]interface{}, v.Len())
+		for i := range list {
+			list[i] = redactValue(v.Index(i).Interface())
+		}
+		return list
+	}
+	return value
+}
+
+func secretKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, secret := range secretKeys {
+		if strings.Contains(key, secret) {
+			return true
+		}
+	}
+	return false
+}
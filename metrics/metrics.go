@@ -0,0 +1,107 @@
+// Package metrics exposes the Prometheus metrics of the service: HTTP traffic
+// per route, MongoDB command timings and business counters. Everything is
+// registered on the default registry, which also carries the Go runtime and
+// process collectors.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "ecommerce"
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "HTTP requests by method, route and status.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Time taken to answer HTTP requests by method, route and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	mongoDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "mongodb",
+		Name:      "command_duration_seconds",
+		Help:      "Time taken by MongoDB commands by command, collection and outcome.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"command", "collection", "outcome"})
+
+	signups = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "signups_total",
+		Help:      "Users that signed up.",
+	})
+
+	cartAdds = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cart_adds_total",
+		Help:      "Products added to a cart, by user or guest cart.",
+	}, []string{"cart"})
+
+	ordersPlaced = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "orders_placed_total",
+		Help:      "Orders placed, by checkout of the cart or instant buy.",
+	}, []string{"source"})
+
+	orderValue = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "order_value",
+		Help:      "Total price of placed orders; the sum is the revenue.",
+		Buckets:   []float64{100, 250, 500, 1000, 2500, 5000, 10000, 25000, 50000, 100000},
+	}, []string{"source"})
+)
+
+// Cart labels of AddedToCart
+const (
+	UserCart  = "user"
+	GuestCart = "guest"
+)
+
+// Source labels of OrderPlaced
+const (
+	CartCheckout = "cart"
+	InstantBuy   = "instant"
+)
+
+// Handler serves the metrics in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// ObserveRequest records an answered HTTP request. route is the route
+// pattern, never the raw path, to keep the number of series bounded.
+func ObserveRequest(method, route string, status int, took time.Duration) {
+	code := strconv.Itoa(status)
+	httpRequests.WithLabelValues(method, route, code).Inc()
+	httpDuration.WithLabelValues(method, route, code).Observe(took.Seconds())
+}
+
+func SignedUp() {
+	signups.Inc()
+}
+
+// AddedToCart counts a product added to a UserCart or GuestCart
+func AddedToCart(cart string) {
+	cartAdds.WithLabelValues(cart).Inc()
+}
+
+// OrderPlaced counts an order placed from source and records its value
+func OrderPlaced(source string, value int) {
+	ordersPlaced.WithLabelValues(source).Inc()
+	orderValue.WithLabelValues(source).Observe(float64(value))
+}
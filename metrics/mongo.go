@@ -0,0 +1,57 @@
+package metrics
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+// MongoMonitor times every command the driver sends, so all DBClient helpers
+// are covered without wrapping each of them. Install it with
+// options.Client().SetMonitor.
+func MongoMonitor() *event.CommandMonitor {
+
+	// the finished events only carry the request id, so remember the
+	// collection each command was sent to
+	var collections sync.Map
+
+	observe := func(finished event.CommandFinishedEvent, outcome string) {
+		collection := ""
+		if value, ok := collections.LoadAndDelete(finished.RequestID); ok {
+			collection = value.(string)
+		}
+		took := time.Duration(finished.DurationNanos)
+		mongoDuration.WithLabelValues(finished.CommandName, collection, outcome).Observe(took.Seconds())
+	}
+
+	return &event.CommandMonitor{
+		Started: func(_ context.Context, started *event.CommandStartedEvent) {
+			collections.Store(started.RequestID, commandCollection(started.Command))
+		},
+		Succeeded: func(_ context.Context, succeeded *event.CommandSucceededEvent) {
+			observe(succeeded.CommandFinishedEvent, "success")
+		},
+		Failed: func(_ context.Context, failed *event.CommandFailedEvent) {
+			observe(failed.CommandFinishedEvent, "failure")
+		},
+	}
+}
+
+// commandCollection returns the collection a command acts on. Most commands
+// name it as the value of their first element; getMore names it separately.
+func commandCollection(command bson.Raw) string {
+
+	if collection, ok := command.Lookup("collection").StringValueOK(); ok {
+		return collection
+	}
+
+	elements, err := command.Elements()
+	if err != nil || len(elements) == 0 {
+		return ""
+	}
+	collection, _ := elements[0].Value().StringValueOK()
+	return collection
+}
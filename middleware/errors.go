@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/mayuka-c/e-commerce/apperror"
+	"github.com/mayuka-c/e-commerce/logging"
+)
+
+// Errors writes the error a handler passed to c.Error as a problem+json
+// response, and turns panics into internal errors. Register it after
+// RequestID, Tracing, Logger and Metrics, so the problem carries the request id
+// and the log line and metrics see the status it writes, and before the
+// routes so it wraps every handler.
+func Errors() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			if p := recover(); p != nil {
+				logging.From(c.Request.Context()).Errorf("panic: %v", p)
+				_ = c.Error(fmt.Errorf("panic: %v", p))
+				writeProblem(c)
+			}
+		}()
+
+		c.Next()
+
+		writeProblem(c)
+	}
+}
+
+// NoRoute answers unknown paths with a problem document
+func NoRoute() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		_ = c.Error(apperror.New(http.StatusNotFound, apperror.CodeNotFound, "no route for "+c.Request.URL.Path))
+	}
+}
+
+// NoMethod answers known paths called with the wrong method
+func NoMethod() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		_ = c.Error(apperror.New(http.StatusMethodNotAllowed, apperror.CodeMethodNotAllowed, c.Request.Method+" is not allowed on "+c.Request.URL.Path))
+	}
+}
+
+func writeProblem(c *gin.Context) {
+
+	if len(c.Errors) == 0 || c.Writer.Written() {
+		return
+	}
+
+	err := c.Errors.Last().Err
+	problem := *apperror.From(err)
+	problem.Instance = c.Request.URL.Path
+	problem.Request_ID = c.GetString("request_id")
+
+	entry := logging.From(c.Request.Context()).WithFields(log.Fields{"status": problem.Status, "code": problem.Code})
+	if problem.Status >= http.StatusInternalServerError {
+		entry.Error(err)
+	} else {
+		entry.Info(err)
+	}
+
+	body, marshalErr := json.MarshalIndent(&problem, "", "    ")
+	if marshalErr != nil {
+		entry.Error(marshalErr)
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	c.Abort()
+	c.Data(problem.Status, "application/problem+json", body)
+}
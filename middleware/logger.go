@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/mayuka-c/e-commerce/logging"
+	"github.com/mayuka-c/e-commerce/telemetry"
+)
+
+// Logger puts a logger carrying the request id, trace id, method and route in
+// the request context and writes one line per request once it is answered, with
+// the status, latency and the user the token belongs to. Register it after
+// RequestID and Tracing.
+func Logger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		entry := log.WithFields(log.Fields{
+			"request_id": c.GetString("request_id"),
+			"method":     c.Request.Method,
+			"route":      c.FullPath(),
+		})
+		if traceID := telemetry.TraceID(c.Request.Context()); traceID != "" {
+			entry = entry.WithField("trace_id", traceID)
+		}
+		c.Request = c.Request.WithContext(logging.With(c.Request.Context(), entry))
+
+		c.Next()
+
+		entry = logging.From(c.Request.Context()).WithFields(log.Fields{
+			"path":       c.Request.URL.Path,
+			"status":     c.Writer.Status(),
+			"latency_ms": float64(time.Since(start).Microseconds()) / 1000,
+			"bytes":      c.Writer.Size(),
+			"client_ip":  c.ClientIP(),
+		})
+
+		switch status := c.Writer.Status(); {
+		case status >= http.StatusInternalServerError:
+			entry.Error("request")
+		case status >= http.StatusBadRequest:
+			entry.Warn("request")
+		default:
+			entry.Info("request")
+		}
+	}
+}
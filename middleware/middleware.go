@@ -6,33 +6,45 @@ import (
 	"github.com/gin-gonic/gin"
 	log "github.com/sirupsen/logrus"
 
+	"github.com/mayuka-c/e-commerce/apperror"
+	"github.com/mayuka-c/e-commerce/constants"
+	"github.com/mayuka-c/e-commerce/logging"
 	"github.com/mayuka-c/e-commerce/tokens"
 )
 
 func Authentication(tokenGenerator *tokens.TokenGenrator) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		log.Println("Running Authentication Middleware")
 		ClientToken := c.Request.Header.Get("token")
 		if ClientToken == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Authorization Header (`token`) not provided"})
+			_ = c.Error(apperror.New(http.StatusUnauthorized, apperror.CodeUnauthorized, "Authorization Header (`token`) not provided"))
 			c.Abort()
 			return
 		}
 
 		claims, err := tokenGenerator.ValidateToken(ClientToken)
 		if err != "" {
-			log.Error(err)
-			if err == "unauthorized access" {
-				c.JSON(http.StatusUnauthorized, gin.H{"error": err})
-			} else {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": err})
-			}
+			_ = c.Error(apperror.New(http.StatusUnauthorized, apperror.CodeUnauthorized, err))
 			c.Abort()
 			return
 		}
 
 		c.Set("email", claims.Email)
 		c.Set("uuid", claims.UUID)
+		c.Set("role", claims.Role)
+		c.Request = c.Request.WithContext(logging.WithFields(c.Request.Context(), log.Fields{"user_id": claims.UUID}))
+		c.Next()
+	}
+}
+
+// Admin lets only tokens issued to admins through. Register it after
+// Authentication.
+func Admin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("role") != constants.RoleAdmin {
+			_ = c.Error(apperror.New(http.StatusForbidden, apperror.CodeForbidden, "this route is only for admins"))
+			c.Abort()
+			return
+		}
 		c.Next()
 	}
 }
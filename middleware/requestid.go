@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader carries the request id in requests and responses
+const RequestIDHeader = "X-Request-ID"
+
+// an id the client sends ends up in every log line of the request, so only
+// short ids of safe characters are kept
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,64}$`)
+
+// RequestID tags the request with the id the client sent, or a new one, and
+// echoes it in the response
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID.MatchString(id) {
+			raw := make([]byte, 8)
+			_, _ = rand.Read(raw)
+			id = hex.EncodeToString(raw)
+		}
+
+		c.Set("request_id", id)
+		c.Header(RequestIDHeader, id)
+		c.Next()
+	}
+}
@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+
+	"github.com/mayuka-c/e-commerce/telemetry"
+)
+
+// Tracing starts a span for every request, named after its route, that
+// continues the trace of a traceparent header. Register it before Logger so
+// log lines carry the trace id. Metrics scrapes and probes are not traced.
+func Tracing() gin.HandlerFunc {
+	return otelgin.Middleware(telemetry.ServiceName, otelgin.WithFilter(func(r *http.Request) bool {
+		switch r.URL.Path {
+		case "/metrics", "/healthz", "/readyz":
+			return false
+		}
+		return true
+	}))
+}
@@ -3,17 +3,18 @@ package models
 import (
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // User collection
 type User struct {
 	ID              primitive.ObjectID `json:"_id" bson:"_id"`
-	First_Name      *string            `json:"first_name" validate:"required,min=2,max=30"`
-	Last_Name       *string            `json:"last_name" validate:"required,min=2,max=30"`
-	Password        *string            `json:"password" validate:"required,min=6"`
-	Email           *string            `json:"email" validate:"email,required"`
-	Phone           *string            `json:"phone" validate:"required"`
+	First_Name      *string            `json:"first_name"`
+	Last_Name       *string            `json:"last_name"`
+	Password        *string            `json:"password"`
+	Email           *string            `json:"email"`
+	Phone           *string            `json:"phone"`
 	Token           *string            `json:"token"`
 	Refresh_Token   *string            `json:"refresh_token"`
 	User_ID         *string            `json:"user_id"`
@@ -22,24 +23,286 @@ type User struct {
 	Order_Status    []Order            `json:"orders" bson:"orders"`
 	Created_At      time.Time          `json:"created_at"`
 	Updated_At      time.Time          `json:"updated_at"`
+	// Role is customer or admin; only admins may call the /admin routes
+	Role string `json:"role" bson:"role"`
+
+	// cart activity, used to find abandoned carts
+	Cart_Updated_At    *time.Time `json:"cart_updated_at,omitempty" bson:"cart_updated_at,omitempty"`
+	Cart_Reminded_At   *time.Time `json:"-" bson:"cart_reminded_at,omitempty"`
+	Cart_Reminders_Off bool       `json:"cart_reminders_off" bson:"cart_reminders_off"`
+	Unsubscribe_Token  *string    `json:"-" bson:"unsubscribe_token,omitempty"`
+
+	// email preferences; Notifications_Off lists the email kinds the user
+	// opted out of
+	Locale            string         `json:"locale" bson:"locale"`
+	Notifications_Off []string       `json:"notifications_off" bson:"notifications_off"`
+	Password_Reset    *PasswordReset `json:"-" bson:"password_reset,omitempty"`
+}
+
+type PasswordReset struct {
+	Token_Hash string    `bson:"token_hash"`
+	Expires_At time.Time `bson:"expires_at"`
+}
+
+type NotificationPrefs struct {
+	Locale            string   `json:"locale"`
+	Notifications_Off []string `json:"notifications_off"`
 }
 
 // Product collection
 type Product struct {
-	Product_ID   primitive.ObjectID `bson:"_id"`
-	Product_Name *string            `json:"product_name" validate:"required"`
-	Price        *uint64            `json:"price" validate:"required"`
-	Rating       *uint8             `json:"rating" validate:"required"`
-	Image        *string            `json:"image" validate:"required"`
+	Product_ID   primitive.ObjectID   `bson:"_id"`
+	Product_Name *string              `json:"product_name"`
+	Price        *uint64              `json:"price"`
+	Rating       float64              `json:"rating" bson:"rating"`
+	Rating_Count int64                `json:"rating_count" bson:"rating_count"`
+	Image        *string              `json:"image"`
+	Weight       *uint64              `json:"weight"`
+	Category_IDs []primitive.ObjectID `json:"category_ids" bson:"category_ids"`
+	SKU          *string              `json:"sku" bson:"sku"`
+	Options      []ProductOption      `json:"options" bson:"options"`
+	Variants     []ProductVariant     `json:"variants" bson:"variants"`
+	Score        float64              `json:"score,omitempty" bson:"score,omitempty"`
+}
+
+// ProductOption is one axis a product varies on, e.g. Size: S, M, L
+type ProductOption struct {
+	Name   string   `json:"name" bson:"name"`
+	Values []string `json:"values" bson:"values"`
+}
+
+// ProductVariant is one purchasable combination of option values. Price, when
+// set, overrides the product price.
+type ProductVariant struct {
+	Variant_ID primitive.ObjectID `json:"_id" bson:"_id"`
+	SKU        string             `json:"sku" bson:"sku"`
+	Options    map[string]string  `json:"options" bson:"options"`
+	Price      *uint64            `json:"price" bson:"price"`
+	Stock      int                `json:"stock" bson:"stock"`
+	Image      *string            `json:"image" bson:"image"`
+}
+
+// VariantUpdate carries the variant fields an admin may change; nil fields are left as they are
+type VariantUpdate struct {
+	SKU   *string `json:"sku"`
+	Price *uint64 `json:"price"`
+	Stock *int    `json:"stock"`
+	Image *string `json:"image"`
+}
+
+// Category collection. Ancestors lists every parent from the root down to the
+// direct parent so a whole subtree can be matched with one query.
+type Category struct {
+	Category_ID primitive.ObjectID   `json:"_id" bson:"_id"`
+	Name        *string              `json:"name" bson:"name"`
+	Slug        string               `json:"slug" bson:"slug"`
+	Parent_ID   *primitive.ObjectID  `json:"parent_id" bson:"parent_id"`
+	Ancestors   []primitive.ObjectID `json:"ancestors" bson:"ancestors"`
+	Created_At  time.Time            `json:"created_at" bson:"created_at"`
+	Updated_At  time.Time            `json:"updated_at" bson:"updated_at"`
+}
+
+// Review collection. Only approved reviews are shown and counted towards the
+// product rating.
+type Review struct {
+	Review_ID     primitive.ObjectID   `json:"_id" bson:"_id"`
+	Product_ID    primitive.ObjectID   `json:"product_id" bson:"product_id"`
+	User_ID       primitive.ObjectID   `json:"user_id" bson:"user_id"`
+	Order_ID      primitive.ObjectID   `json:"order_id" bson:"order_id"`
+	Author        string               `json:"author" bson:"author"`
+	Stars         uint8                `json:"stars" bson:"stars"`
+	Title         *string              `json:"title" bson:"title"`
+	Body          *string              `json:"body" bson:"body"`
+	Status        string               `json:"status" bson:"status"`
+	Helpful_Votes int64                `json:"helpful_votes" bson:"helpful_votes"`
+	Voters        []primitive.ObjectID `json:"-" bson:"voters"`
+	Created_At    time.Time            `json:"created_at" bson:"created_at"`
+	Updated_At    time.Time            `json:"updated_at" bson:"updated_at"`
 }
 
 // Used for usercart
 type ProductUser struct {
-	Product_ID   primitive.ObjectID `bson:"_id"`
-	Product_Name *string            `json:"product_name" bson:"product_name"`
-	Price        int                `json:"price" bson:"price"`
-	Rating       *uint              `json:"rating" bson:"rating"`
-	Image        *string            `json:"image" bson:"image"`
+	Product_ID   primitive.ObjectID  `bson:"_id"`
+	Product_Name *string             `json:"product_name" bson:"product_name"`
+	Price        int                 `json:"price" bson:"price"`
+	Image        *string             `json:"image" bson:"image"`
+	Weight       *uint64             `json:"weight" bson:"weight"`
+	Variant_ID   *primitive.ObjectID `json:"variant_id,omitempty" bson:"variant_id,omitempty"`
+	SKU          *string             `json:"sku,omitempty" bson:"sku,omitempty"`
+	Options      map[string]string   `json:"options,omitempty" bson:"options,omitempty"`
+}
+
+// CartIssue flags a cart line whose product changed since it was added. Old and
+// new price are set for price changes only.
+type CartIssue struct {
+	Product_ID   primitive.ObjectID  `json:"product_id"`
+	Variant_ID   *primitive.ObjectID `json:"variant_id,omitempty"`
+	Product_Name *string             `json:"product_name"`
+	Kind         string              `json:"kind"`
+	Old_Price    int                 `json:"old_price,omitempty"`
+	New_Price    int                 `json:"new_price,omitempty"`
+}
+
+// GuestCart collection. Carts of visitors who are not logged in, found by the
+// SHA-256 of their opaque cart token and dropped by a TTL index once
+// Expires_At passes without activity.
+type GuestCart struct {
+	Cart_ID    primitive.ObjectID `json:"_id" bson:"_id"`
+	Token_Hash string             `json:"-" bson:"token_hash"`
+	Items      []ProductUser      `json:"items" bson:"items"`
+	Created_At time.Time          `json:"created_at" bson:"created_at"`
+	Updated_At time.Time          `json:"updated_at" bson:"updated_at"`
+	Expires_At time.Time          `json:"expires_at" bson:"expires_at"`
+}
+
+// CartReminder collection. One document per abandoned cart reminder sent,
+// marked converted when the user checks out within the conversion window.
+type CartReminder struct {
+	Reminder_ID     primitive.ObjectID  `json:"_id" bson:"_id"`
+	User_ID         primitive.ObjectID  `json:"user_id" bson:"user_id"`
+	Sent_At         time.Time           `json:"sent_at" bson:"sent_at"`
+	Cart_Updated_At time.Time           `json:"cart_updated_at" bson:"cart_updated_at"`
+	Items           int                 `json:"items" bson:"items"`
+	Cart_Value      int                 `json:"cart_value" bson:"cart_value"`
+	Converted_At    *time.Time          `json:"converted_at" bson:"converted_at"`
+	Order_ID        *primitive.ObjectID `json:"order_id,omitempty" bson:"order_id,omitempty"`
+	Order_Value     int                 `json:"order_value" bson:"order_value"`
+}
+
+type CartReminderStats struct {
+	Since           time.Time `json:"since"`
+	Sent            int64     `json:"sent"`
+	Converted       int64     `json:"converted"`
+	Conversion_Rate float64   `json:"conversion_rate"`
+	Converted_Value int64     `json:"converted_value"`
+}
+
+// Job collection. A unit of background work, claimed by one worker at a time
+// under a lease. A worker that dies mid-job lets the lease run out and the job
+// is picked up again, so handlers must tolerate running more than once.
+type Job struct {
+	Job_ID       primitive.ObjectID     `json:"_id" bson:"_id"`
+	Kind         string                 `json:"kind" bson:"kind"`
+	Payload      map[string]interface{} `json:"payload" bson:"payload"`
+	Status       string                 `json:"status" bson:"status"`
+	Attempts     int                    `json:"attempts" bson:"attempts"`
+	Max_Attempts int                    `json:"max_attempts" bson:"max_attempts"`
+	Run_At       time.Time              `json:"run_at" bson:"run_at"`
+	Locked_By    string                 `json:"locked_by,omitempty" bson:"locked_by,omitempty"`
+	Locked_Until *time.Time             `json:"locked_until,omitempty" bson:"locked_until,omitempty"`
+	Last_Error   string                 `json:"last_error,omitempty" bson:"last_error,omitempty"`
+	Recurring    string                 `json:"recurring,omitempty" bson:"recurring,omitempty"`
+	// Dedupe_Key is unique among the kept jobs, so work triggered by an event
+	// that is delivered again is not queued twice
+	Dedupe_Key  string     `json:"dedupe_key,omitempty" bson:"dedupe_key,omitempty"`
+	Created_At  time.Time  `json:"created_at" bson:"created_at"`
+	Updated_At  time.Time  `json:"updated_at" bson:"updated_at"`
+	Finished_At *time.Time `json:"finished_at,omitempty" bson:"finished_at,omitempty"`
+}
+
+// OutboxEvent collection. Domain events written in the same transaction as
+// the change they describe and published to the sinks afterwards.
+// Published_To lists the sinks that already took the event, so a retry only
+// goes to the ones that failed.
+type OutboxEvent struct {
+	Event_ID        primitive.ObjectID `json:"_id" bson:"_id"`
+	Type            string             `json:"type" bson:"type"`
+	Aggregate_ID    primitive.ObjectID `json:"aggregate_id" bson:"aggregate_id"`
+	Payload         bson.Raw           `json:"-" bson:"payload"`
+	Occurred_At     time.Time          `json:"occurred_at" bson:"occurred_at"`
+	Published_To    []string           `json:"published_to" bson:"published_to"`
+	Published_At    *time.Time         `json:"published_at,omitempty" bson:"published_at,omitempty"`
+	Attempts        int                `json:"attempts" bson:"attempts"`
+	Next_Attempt_At time.Time          `json:"next_attempt_at" bson:"next_attempt_at"`
+	Locked_Until    *time.Time         `json:"-" bson:"locked_until,omitempty"`
+	Last_Error      string             `json:"last_error,omitempty" bson:"last_error,omitempty"`
+}
+
+// Webhook collection. A merchant endpoint subscribed to event types, "*" for
+// all of them. The secret signs every delivery and is only shown when the
+// endpoint is created.
+type WebhookEndpoint struct {
+	Endpoint_ID primitive.ObjectID `json:"_id" bson:"_id"`
+	URL         *string            `json:"url" bson:"url"`
+	Events      []string           `json:"events" bson:"events"`
+	Description string             `json:"description" bson:"description"`
+	Secret      string             `json:"secret,omitempty" bson:"secret"`
+	Active      bool               `json:"active" bson:"active"`
+	Created_At  time.Time          `json:"created_at" bson:"created_at"`
+	Updated_At  time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+type WebhookEndpointUpdate struct {
+	URL    *string  `json:"url"`
+	Events []string `json:"events"`
+	Active *bool    `json:"active"`
+}
+
+// WebhookDelivery collection. One event sent to one endpoint, with the body
+// kept so a redelivery sends the same bytes.
+type WebhookDelivery struct {
+	Delivery_ID      primitive.ObjectID `json:"_id" bson:"_id"`
+	Endpoint_ID      primitive.ObjectID `json:"endpoint_id" bson:"endpoint_id"`
+	Event_ID         primitive.ObjectID `json:"event_id" bson:"event_id"`
+	Event_Type       string             `json:"event_type" bson:"event_type"`
+	Body             string             `json:"body" bson:"body"`
+	Status           string             `json:"status" bson:"status"`
+	Attempts         int                `json:"attempts" bson:"attempts"`
+	Last_Status_Code int                `json:"last_status_code,omitempty" bson:"last_status_code,omitempty"`
+	Last_Error       string             `json:"last_error,omitempty" bson:"last_error,omitempty"`
+	Log              []WebhookAttempt   `json:"log" bson:"log"`
+	Created_At       time.Time          `json:"created_at" bson:"created_at"`
+	Delivered_At     *time.Time         `json:"delivered_at,omitempty" bson:"delivered_at,omitempty"`
+}
+
+type WebhookAttempt struct {
+	At          time.Time `json:"at" bson:"at"`
+	Status_Code int       `json:"status_code,omitempty" bson:"status_code,omitempty"`
+	Error       string    `json:"error,omitempty" bson:"error,omitempty"`
+	Duration_Ms int64     `json:"duration_ms" bson:"duration_ms"`
+}
+
+// RecurringJob collection. A cron schedule that enqueues a job of Kind each
+// time Next_Run_At passes.
+type RecurringJob struct {
+	Name        string     `json:"name" bson:"_id"`
+	Schedule    string     `json:"schedule" bson:"schedule"`
+	Kind        string     `json:"kind" bson:"kind"`
+	Next_Run_At time.Time  `json:"next_run_at" bson:"next_run_at"`
+	Last_Run_At *time.Time `json:"last_run_at,omitempty" bson:"last_run_at,omitempty"`
+}
+
+// Wishlist collection. Besides the named lists every user gets one list of
+// kind saved_for_later that holds cart lines put aside for later.
+type Wishlist struct {
+	Wishlist_ID primitive.ObjectID `json:"_id" bson:"_id"`
+	User_ID     primitive.ObjectID `json:"user_id" bson:"user_id"`
+	Name        *string            `json:"name" bson:"name"`
+	Kind        string             `json:"kind" bson:"kind"`
+	Items       []WishlistItem     `json:"items" bson:"items"`
+	Share_Token *string            `json:"share_token,omitempty" bson:"share_token,omitempty"`
+	Created_At  time.Time          `json:"created_at" bson:"created_at"`
+	Updated_At  time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+type WishlistItem struct {
+	Product_ID   primitive.ObjectID  `json:"product_id" bson:"product_id"`
+	Variant_ID   *primitive.ObjectID `json:"variant_id,omitempty" bson:"variant_id,omitempty"`
+	SKU          *string             `json:"sku,omitempty" bson:"sku,omitempty"`
+	Product_Name *string             `json:"product_name" bson:"product_name"`
+	Image        *string             `json:"image" bson:"image"`
+	Price_At_Add int                 `json:"price_at_add" bson:"price_at_add"`
+	Added_At     time.Time           `json:"added_at" bson:"added_at"`
+}
+
+// PriceDrop is a wishlisted item that now costs less than when it was added
+type PriceDrop struct {
+	Wishlist_ID   primitive.ObjectID `json:"wishlist_id"`
+	Wishlist_Name string             `json:"wishlist_name"`
+	Item          WishlistItem       `json:"item"`
+	Current_Price int                `json:"current_price"`
+	Drop          int                `json:"drop"`
+	Drop_Percent  float64            `json:"drop_percent"`
 }
 
 type Address struct {
@@ -57,9 +320,132 @@ type Order struct {
 	Price          int                `json:"total_price" bson:"total_price"`
 	Discount       *int               `json:"discount" bson:"discount"`
 	Payment_Method Payment            `json:"payment_method" bson:"payment_method"`
+	Status         string             `json:"status" bson:"status"`
+	Cancelled_At   *time.Time         `json:"cancelled_at,omitempty" bson:"cancelled_at,omitempty"`
 }
 
 type Payment struct {
 	Digital        bool
 	CashOnDelivery bool
 }
+
+// ShippingMethod collection
+type ShippingMethod struct {
+	Method_ID               primitive.ObjectID `json:"_id" bson:"_id"`
+	Name                    *string            `json:"name" bson:"name"`
+	Carrier                 *string            `json:"carrier" bson:"carrier"`
+	Free_Shipping_Threshold *uint64            `json:"free_shipping_threshold" bson:"free_shipping_threshold"`
+	Zones                   []ShippingZone     `json:"zones" bson:"zones"`
+	Rates                   []ShippingRate     `json:"rates" bson:"rates"`
+}
+
+// ShippingZone groups delivery pincodes by prefix
+type ShippingZone struct {
+	Name             *string  `json:"name" bson:"name"`
+	Pincode_Prefixes []string `json:"pincode_prefixes" bson:"pincode_prefixes"`
+}
+
+// ShippingRate is one row of a rate table. Min values are inclusive, Max values
+// are exclusive and a zero Max means unbounded. Weight is in grams.
+type ShippingRate struct {
+	Zone            *string `json:"zone" bson:"zone"`
+	Min_Weight      uint64  `json:"min_weight" bson:"min_weight"`
+	Max_Weight      uint64  `json:"max_weight" bson:"max_weight"`
+	Min_Order_Value uint64  `json:"min_order_value" bson:"min_order_value"`
+	Max_Order_Value uint64  `json:"max_order_value" bson:"max_order_value"`
+	Price           uint64  `json:"price" bson:"price"`
+}
+
+type ShippingQuote struct {
+	Method_ID     primitive.ObjectID `json:"method_id"`
+	Name          string             `json:"name"`
+	Carrier       string             `json:"carrier"`
+	Price         uint64             `json:"price"`
+	Free_Shipping bool               `json:"free_shipping"`
+}
+
+// Shipment collection
+type Shipment struct {
+	Shipment_ID     primitive.ObjectID `json:"_id" bson:"_id"`
+	Order_ID        primitive.ObjectID `json:"order_id" bson:"order_id"`
+	User_ID         primitive.ObjectID `json:"user_id" bson:"user_id"`
+	Method_ID       primitive.ObjectID `json:"method_id" bson:"method_id"`
+	Carrier         string             `json:"carrier" bson:"carrier"`
+	Tracking_Number string             `json:"tracking_number" bson:"tracking_number"`
+	Status          string             `json:"status" bson:"status"`
+	Events          []ShipmentEvent    `json:"events" bson:"events"`
+	Created_At      time.Time          `json:"created_at" bson:"created_at"`
+	Updated_At      time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+type ShipmentEvent struct {
+	Status      string    `json:"status" bson:"status"`
+	Location    string    `json:"location" bson:"location"`
+	Description string    `json:"description" bson:"description"`
+	Occurred_At time.Time `json:"occurred_at" bson:"occurred_at"`
+}
+
+// ProductQuery narrows, orders and pages a product listing
+type ProductQuery struct {
+	Search       string
+	Search_Mode  string
+	Category_IDs []primitive.ObjectID
+	// facet selections; values within one facet are OR-ed, facets are AND-ed
+	Selected_Categories []primitive.ObjectID
+	Price_Bands         []string
+	Ratings             []uint8
+	Facets              bool
+	Min_Price           *uint64
+	Max_Price           *uint64
+	Min_Rating          *uint8
+	Max_Rating          *uint8
+	Sort                string
+	Page                int64
+	Limit               int64
+}
+
+// ProductPage is the envelope returned by the product listing endpoints
+type ProductPage struct {
+	Items       []Product      `json:"items"`
+	Total       int64          `json:"total"`
+	Page        int64          `json:"page"`
+	Limit       int64          `json:"limit"`
+	Total_Pages int64          `json:"total_pages"`
+	Next        *string        `json:"next"`
+	Prev        *string        `json:"prev"`
+	Facets      *ProductFacets `json:"facets,omitempty"`
+}
+
+// ProductFacets holds the sidebar filter counts of a product listing. The
+// counts of each facet ignore the selections made in that same facet, so the
+// other options of a multi-select facet keep their counts.
+type ProductFacets struct {
+	Categories []CategoryFacet `json:"categories"`
+	Price      []PriceFacet    `json:"price"`
+	Rating     []RatingFacet   `json:"rating"`
+}
+
+// CategoryFacet counts the matching products in the category or any of its subcategories
+type CategoryFacet struct {
+	Category_ID primitive.ObjectID  `json:"_id"`
+	Name        string              `json:"name"`
+	Slug        string              `json:"slug"`
+	Parent_ID   *primitive.ObjectID `json:"parent_id"`
+	Count       int64               `json:"count"`
+	Selected    bool                `json:"selected"`
+}
+
+type PriceFacet struct {
+	Band     string  `json:"band"`
+	Min      uint64  `json:"min"`
+	Max      *uint64 `json:"max"`
+	Count    int64   `json:"count"`
+	Selected bool    `json:"selected"`
+}
+
+// RatingFacet counts the matching products rated Min_Rating stars and up
+type RatingFacet struct {
+	Min_Rating uint8 `json:"min_rating"`
+	Count      int64 `json:"count"`
+	Selected   bool  `json:"selected"`
+}
@@ -0,0 +1,193 @@
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/mayuka-c/e-commerce/constants"
+	"github.com/mayuka-c/e-commerce/database"
+	"github.com/mayuka-c/e-commerce/events"
+	"github.com/mayuka-c/e-commerce/models"
+)
+
+// EmailJobKind is the background job that sends one email
+const EmailJobKind = "send_email"
+
+// EmailAttempts is how often sending an email is tried
+const EmailAttempts = 6
+
+// Emails queues the transactional emails as jobs, so a mail server outage
+// delays emails instead of losing them. A job holds the user, the kind and
+// the template data; the email is rendered when it is sent, so secrets such as
+// the password reset link never sit in the jobs collection.
+type Emails struct {
+	dbClient  *database.DBClient
+	mailer    Mailer
+	templates *Templates
+	// resetURL is the frontend page password reset links point at
+	resetURL string
+}
+
+func NewEmails(dbClient *database.DBClient, mailer Mailer, templates *Templates, resetURL string) *Emails {
+	return &Emails{dbClient: dbClient, mailer: mailer, templates: templates, resetURL: resetURL}
+}
+
+// Subscribe is the event bus subscriber for the order emails
+func (e *Emails) Subscribe(ctx context.Context, event events.Event) error {
+
+	var (
+		user_id = event.Aggregate_ID
+		kind    string
+		data    EmailData
+	)
+
+	switch payload := event.Data.(type) {
+	case *events.OrderPlaced:
+		user_id, kind = payload.User_ID, OrderPlacedKind
+		data = EmailData{Order_ID: payload.Order_ID.Hex(), Items: payload.Items, Total: payload.Total_Price}
+	case *events.ShipmentStatusChanged:
+		switch {
+		case payload.Status == constants.ShipmentInTransit && payload.Old_Status == constants.ShipmentLabelCreated:
+			// only the first pickup, not a return to transit after an exception
+			kind = OrderShippedKind
+		case payload.Status == constants.ShipmentDelivered:
+			kind = OrderDeliveredKind
+		default:
+			return nil
+		}
+		user_id = payload.User_ID
+		data = EmailData{Order_ID: payload.Order_ID.Hex(), Carrier: payload.Carrier, Tracking_Number: payload.Tracking_Number}
+	case *events.OrderCancelled:
+		if payload.Refund_Amount <= 0 {
+			return nil
+		}
+		user_id, kind = payload.User_ID, OrderRefundedKind
+		data = EmailData{Order_ID: payload.Order_ID.Hex(), Reason: payload.Reason, Refund_Amount: payload.Refund_Amount}
+	default:
+		return nil
+	}
+
+	user, err := e.dbClient.GetUser(ctx, user_id)
+	if err == database.ErrCantFindUser {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, off := range user.Notifications_Off {
+		if off == kind {
+			return nil
+		}
+	}
+
+	// the bus is published again when a later subscriber fails, so the event
+	// may already have queued this email
+	return e.queue(ctx, user, kind, event.ID.Hex()+":"+kind, data)
+}
+
+// SendPasswordReset queues the password reset email. The reset token is
+// issued when the email is sent. It ignores the user's notification
+// preferences.
+func (e *Emails) SendPasswordReset(ctx context.Context, user models.User) error {
+	return e.queue(ctx, user, PasswordResetKind, "", EmailData{})
+}
+
+// Handle is the job handler that renders and sends a queued email
+func (e *Emails) Handle(ctx context.Context, job models.Job) error {
+
+	kind, _ := job.Payload["kind"].(string)
+	user_id, _ := job.Payload["user_id"].(primitive.ObjectID)
+	if kind == "" || user_id.IsZero() {
+		return fmt.Errorf("email job has no kind or user")
+	}
+
+	var data EmailData
+	if raw, ok := job.Payload["data"]; ok {
+		doc, err := bson.Marshal(raw)
+		if err != nil {
+			return err
+		}
+		if err := bson.Unmarshal(doc, &data); err != nil {
+			return err
+		}
+	}
+
+	user, err := e.dbClient.GetUser(ctx, user_id)
+	if err == database.ErrCantFindUser {
+		// the account was deleted since
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if user.Email == nil || *user.Email == "" {
+		return nil
+	}
+	if user.First_Name != nil {
+		data.Name = *user.First_Name
+	}
+
+	if kind == PasswordResetKind {
+		_, token, err := e.dbClient.CreatePasswordReset(ctx, user_id)
+		if err != nil {
+			return err
+		}
+		data.Reset_URL = resetLink(e.resetURL, token)
+		data.Expires_In = database.PasswordResetTTL.String()
+	}
+
+	locale := user.Locale
+	if locale == "" {
+		locale = DefaultLocale
+	}
+
+	email, err := e.templates.Render(locale, kind, data)
+	if err != nil {
+		return err
+	}
+	email.To = *user.Email
+
+	return e.mailer.Send(ctx, email)
+}
+
+// queue enqueues the email unless an email with the dedupe key was queued
+// before
+func (e *Emails) queue(ctx context.Context, user models.User, kind, dedupeKey string, data EmailData) error {
+
+	if user.Email == nil || *user.Email == "" {
+		return nil
+	}
+
+	payload := map[string]interface{}{
+		"kind":    kind,
+		"user_id": user.ID,
+		"data":    data,
+	}
+
+	_, err := e.dbClient.EnqueueUniqueJob(ctx, EmailJobKind, dedupeKey, payload, time.Now(), EmailAttempts)
+	if err == database.ErrJobExists {
+		return nil
+	}
+	return err
+}
+
+// resetLink adds the token to the reset page URL
+func resetLink(page, token string) string {
+
+	link, err := url.Parse(page)
+	if err != nil {
+		return page + "?token=" + url.QueryEscape(token)
+	}
+
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+
+	return link.String()
+}
@@ -0,0 +1,41 @@
+package notifications
+
+import (
+	"context"
+)
+
+// Message kinds. The email kinds double as the values users put in their
+// notifications_off preference.
+const (
+	CartReminderKind   = "cart_reminder"
+	OrderPlacedKind    = "order_placed"
+	OrderShippedKind   = "order_shipped"
+	OrderDeliveredKind = "order_delivered"
+	OrderRefundedKind  = "order_refunded"
+	PasswordResetKind  = "password_reset"
+)
+
+type Message struct {
+	Kind    string
+	To      string
+	Subject string
+	Body    string
+}
+
+// Notifier delivers a message to a user
+type Notifier interface {
+	Notify(ctx context.Context, message Message) error
+}
+
+// MailNotifier sends messages as plain text emails
+type MailNotifier struct {
+	mailer Mailer
+}
+
+func NewMailNotifier(mailer Mailer) *MailNotifier {
+	return &MailNotifier{mailer: mailer}
+}
+
+func (n *MailNotifier) Notify(ctx context.Context, message Message) error {
+	return n.mailer.Send(ctx, Email{To: message.To, Subject: message.Subject, Text: message.Body})
+}
@@ -0,0 +1,125 @@
+package notifications
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"path"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/mayuka-c/e-commerce/models"
+)
+
+//go:embed templates
+var templateFiles embed.FS
+
+// DefaultLocale is used for users without a locale and for kinds that have no
+// variant in the user's locale
+const DefaultLocale = "en"
+
+// EmailData is what the email templates render. Each kind uses the fields that
+// apply to it.
+type EmailData struct {
+	Name            string
+	Order_ID        string
+	Items           []models.ProductUser
+	Total           int
+	Carrier         string
+	Tracking_Number string
+	Reason          string
+	Refund_Amount   int
+	Reset_URL       string
+	Expires_In      string
+}
+
+// Templates holds the parsed email templates by "<locale>/<kind>". The text
+// template also defines the subject line.
+type Templates struct {
+	text map[string]*texttemplate.Template
+	html map[string]*htmltemplate.Template
+}
+
+// LoadTemplates parses the embedded templates/<locale>/<kind>.txt and .html files
+func LoadTemplates() (*Templates, error) {
+
+	t := &Templates{
+		text: make(map[string]*texttemplate.Template),
+		html: make(map[string]*htmltemplate.Template),
+	}
+
+	err := fs.WalkDir(templateFiles, "templates", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+
+		locale := path.Base(path.Dir(name))
+		ext := path.Ext(name)
+		key := locale + "/" + strings.TrimSuffix(path.Base(name), ext)
+
+		switch ext {
+		case ".txt":
+			tmpl, err := texttemplate.ParseFS(templateFiles, name)
+			if err != nil {
+				return err
+			}
+			if tmpl.Lookup("subject") == nil {
+				return fmt.Errorf("%s does not define a subject", name)
+			}
+			t.text[key] = tmpl
+		case ".html":
+			tmpl, err := htmltemplate.ParseFS(templateFiles, name)
+			if err != nil {
+				return err
+			}
+			t.html[key] = tmpl
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// Render builds the email of the kind in the locale, falling back to
+// DefaultLocale
+func (t *Templates) Render(locale, kind string, data EmailData) (Email, error) {
+
+	var email Email
+
+	key := locale + "/" + kind
+	if _, ok := t.text[key]; !ok {
+		key = DefaultLocale + "/" + kind
+	}
+
+	text, ok := t.text[key]
+	if !ok {
+		return email, fmt.Errorf("no email template for %q", kind)
+	}
+
+	var b bytes.Buffer
+	if err := text.ExecuteTemplate(&b, "subject", data); err != nil {
+		return email, err
+	}
+	email.Subject = strings.TrimSpace(b.String())
+
+	b.Reset()
+	if err := text.Execute(&b, data); err != nil {
+		return email, err
+	}
+	email.Text = b.String()
+
+	if html, ok := t.html[key]; ok {
+		b.Reset()
+		if err := html.Execute(&b, data); err != nil {
+			return email, err
+		}
+		email.HTML = b.String()
+	}
+
+	return email, nil
+}
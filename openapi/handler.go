@@ -0,0 +1,51 @@
+package openapi
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed swagger.html
+var swaggerPage string
+
+// Handler serves the document. It is built once, on the first request.
+func Handler(s *Spec) gin.HandlerFunc {
+
+	var (
+		once sync.Once
+		body []byte
+		err  error
+	)
+	return func(c *gin.Context) {
+		once.Do(func() {
+			body, err = json.MarshalIndent(s.Document(), "", "    ")
+		})
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+	}
+}
+
+// UI serves Swagger UI for the document at specURL. The page is embedded;
+// the Swagger UI scripts load from unpkg.
+func UI(specURL string) gin.HandlerFunc {
+
+	page := template.Must(template.New("swagger").Parse(swaggerPage))
+	var buf bytes.Buffer
+	if err := page.Execute(&buf, struct{ SpecURL string }{specURL}); err != nil {
+		panic(err)
+	}
+	body := buf.Bytes()
+
+	return func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", body)
+	}
+}
@@ -0,0 +1,256 @@
+// Package openapi builds the OpenAPI 3 document of the API from the routes
+// and the request and response types the handlers use. Schemas are generated
+// from the Go types: json tags give the property names and validate tags
+// give required fields, enums and bounds.
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mayuka-c/e-commerce/apperror"
+)
+
+// Version of the OpenAPI specification the document follows
+const Version = "3.0.3"
+
+// Operation describes one route. Body and Response are zero values of the
+// request and response types; leave Body nil for routes without a body.
+type Operation struct {
+	Summary     string
+	Description string
+	Tags        []string
+	Params      []Param
+	Body        interface{}
+	Response    interface{}
+	// OptionalBody marks a Body the client may leave out
+	OptionalBody bool
+	// Status of a successful response, 200 when not set
+	Status     int
+	Auth       bool
+	Deprecated bool
+}
+
+// Param is a path, query, header or cookie parameter
+type Param struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+func PathParam(name, description string) Param {
+	return Param{Name: name, In: "path", Description: description, Required: true, Schema: &Schema{Type: "string"}}
+}
+
+// IDParam is a path parameter holding a hex object id
+func IDParam(name, description string) Param {
+	return Param{Name: name, In: "path", Description: description, Required: true, Schema: &Schema{Type: "string", Pattern: "^[0-9a-f]{24}$"}}
+}
+
+func QueryParam(name, description string) Param {
+	return Param{Name: name, In: "query", Description: description, Schema: &Schema{Type: "string"}}
+}
+
+// EnumQueryParam is a query parameter that takes one of values
+func EnumQueryParam(name, description string, values ...string) Param {
+	param := QueryParam(name, description)
+	for _, value := range values {
+		param.Schema.Enum = append(param.Schema.Enum, value)
+	}
+	return param
+}
+
+func IntQueryParam(name, description string) Param {
+	return Param{Name: name, In: "query", Description: description, Schema: &Schema{Type: "integer", Minimum: float(1)}}
+}
+
+func HeaderParam(name, description string) Param {
+	return Param{Name: name, In: "header", Description: description, Schema: &Schema{Type: "string"}}
+}
+
+// Spec collects the operations of the API
+type Spec struct {
+	title      string
+	version    string
+	operations map[string]map[string]Operation
+	schemas    map[string]*Schema
+	rules      map[string]Schema
+}
+
+func New(title, version string) *Spec {
+	return &Spec{
+		title:      title,
+		version:    version,
+		operations: map[string]map[string]Operation{},
+		schemas:    map[string]*Schema{},
+		rules:      map[string]Schema{},
+	}
+}
+
+// Rule documents a custom validate tag by the constraints it puts on a value
+func (s *Spec) Rule(tag string, schema Schema) {
+	s.rules[tag] = schema
+}
+
+var ginParam = regexp.MustCompile(`[:*]([A-Za-z0-9_]+)`)
+
+// OpenAPIPath turns gin path parameters (:id, *path) into {id}
+func OpenAPIPath(path string) string {
+	return ginParam.ReplaceAllString(path, "{$1}")
+}
+
+// Add documents the route. path may use gin or OpenAPI parameter syntax.
+func (s *Spec) Add(method, path string, op Operation) {
+
+	path = OpenAPIPath(path)
+	if s.operations[path] == nil {
+		s.operations[path] = map[string]Operation{}
+	}
+	s.operations[path][strings.ToLower(method)] = op
+}
+
+// Operation returns the operation documented for the route
+func (s *Spec) Operation(method, path string) (Operation, bool) {
+	op, ok := s.operations[OpenAPIPath(path)][strings.ToLower(method)]
+	return op, ok
+}
+
+// Missing lists the registered routes that have no operation in the spec,
+// as "METHOD path"
+func (s *Spec) Missing(routes gin.RoutesInfo) []string {
+
+	var missing []string
+	for _, route := range routes {
+		if _, ok := s.Operation(route.Method, route.Path); !ok {
+			missing = append(missing, route.Method+" "+route.Path)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// Document builds the OpenAPI document
+func (s *Spec) Document() map[string]interface{} {
+
+	paths := map[string]interface{}{}
+	for path, methods := range s.operations {
+		item := map[string]interface{}{}
+		for method, op := range methods {
+			item[method] = s.operation(method, path, op)
+		}
+		paths[path] = item
+	}
+
+	s.schemaFor(reflect.TypeOf(apperror.Error{}))
+
+	return map[string]interface{}{
+		"openapi": Version,
+		"info": map[string]interface{}{
+			"title":   s.title,
+			"version": s.version,
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": s.schemas,
+			"securitySchemes": map[string]interface{}{
+				"token": map[string]interface{}{
+					"type":        "apiKey",
+					"in":          "header",
+					"name":        "token",
+					"description": "The token returned by login",
+				},
+			},
+			"responses": map[string]interface{}{
+				"Problem": map[string]interface{}{
+					"description": "The request failed, see code and detail",
+					"content": map[string]interface{}{
+						"application/problem+json": map[string]interface{}{
+							"schema": &Schema{Ref: "#/components/schemas/apperror.Error"},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (s *Spec) operation(method, path string, op Operation) map[string]interface{} {
+
+	out := map[string]interface{}{
+		"operationId": operationID(method, path),
+		"summary":     op.Summary,
+	}
+	if op.Description != "" {
+		out["description"] = op.Description
+	}
+	if len(op.Tags) > 0 {
+		out["tags"] = op.Tags
+	}
+	if op.Deprecated {
+		out["deprecated"] = true
+	}
+	if op.Auth {
+		out["security"] = []map[string][]string{{"token": {}}}
+	}
+
+	params := op.Params
+	for _, match := range regexp.MustCompile(`{([^}]+)}`).FindAllStringSubmatch(path, -1) {
+		if !hasParam(params, match[1], "path") {
+			params = append(params, PathParam(match[1], ""))
+		}
+	}
+	if len(params) > 0 {
+		out["parameters"] = params
+	}
+
+	if op.Body != nil {
+		out["requestBody"] = map[string]interface{}{
+			"required": !op.OptionalBody,
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{"schema": s.schemaFor(reflect.TypeOf(op.Body))},
+			},
+		}
+	}
+
+	status := op.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := map[string]interface{}{"description": http.StatusText(status)}
+	if op.Response != nil {
+		success["content"] = map[string]interface{}{
+			"application/json": map[string]interface{}{"schema": s.schemaFor(reflect.TypeOf(op.Response))},
+		}
+	}
+	out["responses"] = map[string]interface{}{
+		strconv.Itoa(status): success,
+		"default":            map[string]interface{}{"$ref": "#/components/responses/Problem"},
+	}
+
+	return out
+}
+
+func hasParam(params []Param, name, in string) bool {
+	for _, param := range params {
+		if param.Name == name && param.In == in {
+			return true
+		}
+	}
+	return false
+}
+
+// operationID derives a stable id from the route, e.g. post_api_v1_orders_id_cancel
+func operationID(method, path string) string {
+	id := strings.NewReplacer("{", "", "}", "", "-", "_").Replace(path)
+	id = strings.Trim(strings.ReplaceAll(id, "/", "_"), "_")
+	return fmt.Sprintf("%s_%s", method, id)
+}
@@ -0,0 +1,286 @@
+package openapi
+
+import (
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Schema is a JSON Schema object as used by OpenAPI 3.0
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	ExclusiveMinimum     bool               `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum     bool               `json:"exclusiveMaximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	UniqueItems          bool               `json:"uniqueItems,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	objectIDType = reflect.TypeOf(primitive.ObjectID{})
+)
+
+// schemaFor returns the schema of t. Named structs are added to the
+// components once and referenced from then on, which also ends recursion.
+func (s *Spec) schemaFor(t reflect.Type) *Schema {
+
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case objectIDType:
+		return &Schema{Type: "string", Pattern: "^[0-9a-f]{24}$"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Minimum: float(0)}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: s.schemaFor(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: s.schemaFor(t.Elem())}
+	case reflect.Interface:
+		return &Schema{}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.structSchema(t)
+		}
+		name := componentName(t)
+		if _, ok := s.schemas[name]; !ok {
+			// reserve the name before walking the fields of a recursive type
+			s.schemas[name] = nil
+			s.schemas[name] = s.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+
+	return &Schema{}
+}
+
+// structSchema lists the JSON fields of t, with the rules of their validate
+// tags. Embedded structs are flattened like encoding/json does.
+func (s *Spec) structSchema(t reflect.Type) *Schema {
+
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	s.addFields(schema, t)
+	return schema
+}
+
+func (s *Spec) addFields(schema *Schema, t reflect.Type) {
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		name, skip := jsonName(field)
+		if skip {
+			continue
+		}
+
+		if field.Anonymous && field.Tag.Get("json") == "" {
+			embedded := field.Type
+			for embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				s.addFields(schema, embedded)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		property := s.schemaFor(field.Type)
+		if property.Ref != "" && hasRules(field) {
+			// $ref siblings are ignored, so rules on a referenced type are dropped
+			schema.Properties[name] = property
+			continue
+		}
+		if s.applyRules(property, field) {
+			schema.Required = append(schema.Required, name)
+		}
+		schema.Properties[name] = property
+	}
+}
+
+// jsonName returns the key encoding/json uses for the field
+func jsonName(field reflect.StructField) (string, bool) {
+
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", true
+	}
+
+	name, _, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = field.Name
+	}
+	return name, false
+}
+
+func hasRules(field reflect.StructField) bool {
+	return field.Tag.Get("validate") != ""
+}
+
+// applyRules maps the validate tag of the field onto the schema and reports
+// whether the field is required. Rules after dive apply to the items.
+func (s *Spec) applyRules(schema *Schema, field reflect.StructField) bool {
+
+	required := false
+	target := schema
+	kind := indirect(field.Type).Kind()
+
+	for _, rule := range strings.Split(field.Tag.Get("validate"), ",") {
+		tag, param, _ := strings.Cut(rule, "=")
+
+		switch tag {
+		case "":
+		case "dive":
+			if target.Items == nil {
+				return required
+			}
+			target = target.Items
+			kind = indirect(indirect(field.Type).Elem()).Kind()
+		case "required":
+			if target == schema {
+				required = true
+			} else if kind == reflect.String {
+				target.MinLength = intPtr(1)
+			}
+		case "email":
+			target.Format = "email"
+		case "url", "http_url":
+			target.Format = "uri"
+		case "unique":
+			target.UniqueItems = true
+		case "numeric":
+			target.Pattern = "^[0-9]+$"
+		case "oneof":
+			for _, value := range strings.Fields(param) {
+				target.Enum = append(target.Enum, value)
+			}
+		case "min", "gte", "gt":
+			bound(target, kind, param, true, tag == "gt")
+		case "max", "lte", "lt":
+			bound(target, kind, param, false, tag == "lt")
+		case "len":
+			bound(target, kind, param, true, false)
+			bound(target, kind, param, false, false)
+		default:
+			if rule, ok := s.rules[tag]; ok {
+				merge(target, rule)
+			}
+		}
+	}
+
+	return required
+}
+
+// bound sets the lower or upper limit a rule puts on a string length, an
+// array size or a number
+func bound(schema *Schema, kind reflect.Kind, param string, lower, exclusive bool) {
+
+	value, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return
+	}
+
+	switch kind {
+	case reflect.String:
+		if lower {
+			schema.MinLength = intPtr(int(value))
+		} else {
+			schema.MaxLength = intPtr(int(value))
+		}
+	case reflect.Slice, reflect.Array, reflect.Map:
+		if lower {
+			schema.MinItems = intPtr(int(value))
+		} else {
+			schema.MaxItems = intPtr(int(value))
+		}
+	default:
+		if lower {
+			schema.Minimum = float(value)
+			schema.ExclusiveMinimum = exclusive
+		} else {
+			schema.Maximum = float(value)
+			schema.ExclusiveMaximum = exclusive
+		}
+	}
+}
+
+func indirect(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+// merge copies the constraints set in rule onto schema
+func merge(schema *Schema, rule Schema) {
+	if rule.Pattern != "" {
+		schema.Pattern = rule.Pattern
+	}
+	if rule.Format != "" {
+		schema.Format = rule.Format
+	}
+	if rule.Minimum != nil {
+		schema.Minimum = rule.Minimum
+	}
+	if rule.Maximum != nil {
+		schema.Maximum = rule.Maximum
+	}
+	if rule.Description != "" {
+		schema.Description = rule.Description
+	}
+}
+
+// componentName qualifies the type name with its package, since request and
+// model types share names. Unexported documentation types get an upper case
+// first letter so they read like the rest.
+func componentName(t reflect.Type) string {
+	name := []rune(t.Name())
+	name[0] = unicode.ToUpper(name[0])
+	return path.Base(t.PkgPath()) + "." + string(name)
+}
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func float(v float64) *float64 {
+	return &v
+}
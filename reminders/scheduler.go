@@ -0,0 +1,131 @@
+package reminders
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mayuka-c/e-commerce/database"
+	"github.com/mayuka-c/e-commerce/logging"
+	"github.com/mayuka-c/e-commerce/models"
+	"github.com/mayuka-c/e-commerce/notifications"
+	"github.com/mayuka-c/e-commerce/routes"
+)
+
+// JobKind is the background job that scans for abandoned carts
+const JobKind = "cart_reminders"
+
+// Scheduler looks for carts left untouched for Idle and sends their owners one
+// reminder, at most once per Throttle
+type Scheduler struct {
+	dbClient       *database.DBClient
+	notifier       notifications.Notifier
+	unsubscribeURL string
+
+	Idle      time.Duration
+	Throttle  time.Duration
+	BatchSize int64
+}
+
+// NewScheduler creates a scheduler that links reminders to the versioned
+// unsubscribe endpoint under publicURL. Sent emails keep the link, so it must
+// not point at a legacy route.
+func NewScheduler(dbClient *database.DBClient, notifier notifications.Notifier, publicURL string) *Scheduler {
+	return &Scheduler{
+		dbClient:       dbClient,
+		notifier:       notifier,
+		unsubscribeURL: strings.TrimRight(publicURL, "/") + routes.APIPrefix + "/unsubscribe",
+		Idle:           24 * time.Hour,
+		Throttle:       3 * 24 * time.Hour,
+		BatchSize:      100,
+	}
+}
+
+// Handle runs one scan as a background job of kind JobKind
+func (s *Scheduler) Handle(ctx context.Context, job models.Job) error {
+
+	sent, err := s.RunOnce(ctx)
+	if sent > 0 {
+		logging.From(ctx).WithField("sent", sent).Info("sent abandoned cart reminders")
+	}
+
+	return err
+}
+
+// RunOnce sends reminders for one batch of abandoned carts and returns how
+// many were sent
+func (s *Scheduler) RunOnce(ctx context.Context) (int, error) {
+
+	now := time.Now()
+
+	users, err := s.dbClient.AbandonedCarts(ctx, now.Add(-s.Idle), now.Add(-s.Throttle), s.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, user := range users {
+		if user.Email == nil {
+			continue
+		}
+
+		user, claimed, err := s.dbClient.ClaimCartReminder(ctx, user, now)
+		if err != nil {
+			return sent, err
+		}
+		if !claimed {
+			continue
+		}
+
+		err = s.notifier.Notify(ctx, s.message(user))
+		if err != nil {
+			logging.From(ctx).WithField("user_id", user.ID.Hex()).Errorf("cart reminder not sent: %v", err)
+			continue
+		}
+
+		total := 0
+		for _, line := range user.UserCart {
+			total += line.Price
+		}
+
+		err = s.dbClient.RecordCartReminder(ctx, models.CartReminder{
+			User_ID:         user.ID,
+			Sent_At:         now,
+			Cart_Updated_At: *user.Cart_Updated_At,
+			Items:           len(user.UserCart),
+			Cart_Value:      total,
+		})
+		if err != nil {
+			return sent, err
+		}
+		sent++
+	}
+
+	return sent, nil
+}
+
+func (s *Scheduler) message(user models.User) notifications.Message {
+
+	var b strings.Builder
+	if user.First_Name != nil {
+		fmt.Fprintf(&b, "Hi %s,\n\n", *user.First_Name)
+	}
+	b.WriteString("You left these items in your cart:\n")
+	for _, line := range user.UserCart {
+		name := "item"
+		if line.Product_Name != nil {
+			name = *line.Product_Name
+		}
+		fmt.Fprintf(&b, "  - %s\n", name)
+	}
+	fmt.Fprintf(&b, "\nTo stop these reminders: %s?token=%s\n", s.unsubscribeURL, url.QueryEscape(*user.Unsubscribe_Token))
+
+	return notifications.Message{
+		Kind:    notifications.CartReminderKind,
+		To:      *user.Email,
+		Subject: "You left something in your cart",
+		Body:    b.String(),
+	}
+}
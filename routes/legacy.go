@@ -0,0 +1,105 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// The unversioned routes were deprecated when /api/v1 was added and are
+// removed after LegacySunset
+var (
+	LegacyDeprecatedAt = time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+	LegacySunset       = time.Date(2027, time.April, 30, 0, 0, 0, 0, time.UTC)
+)
+
+// legacySuccessors maps each legacy route to the /api/v1 route replacing it
+var legacySuccessors = map[string]string{
+	"POST /users/signup":             "/auth/signup",
+	"POST /users/login":              "/auth/login",
+	"POST /users/forgotpassword":     "/auth/forgot-password",
+	"POST /users/resetpassword":      "/auth/reset-password",
+	"GET /users/unsubscribe":         "/unsubscribe",
+	"GET /users/productview":         "/products",
+	"GET /users/search":              "/products/search",
+	"GET /users/search/suggest":      "/products/suggestions",
+	"GET /users/reviews":             "/products/{id}/reviews",
+	"GET /users/categories":          "/categories",
+	"GET /users/sharedwishlist":      "/shared-wishlists/{token}",
+	"GET /guest/listcart":            "/guest/cart",
+	"POST /guest/addtocart":          "/guest/cart/items/{id}",
+	"DELETE /guest/removeitem":       "/guest/cart/items/{id}",
+	"GET /listcart":                  "/cart",
+	"POST /addtocart":                "/cart/items/{id}",
+	"DELETE /removeitem":             "/cart/items/{id}",
+	"POST /saveforlater":             "/cart/items/{id}/save-for-later",
+	"POST /cartcheckout":             "/orders",
+	"POST /instantbuy":               "/products/{id}/purchase",
+	"POST /cancelorder":              "/orders/{id}/cancel",
+	"GET /trackshipment":             "/orders/{id}/shipment",
+	"GET /shippingquote":             "/shipping/quote",
+	"POST /addreview":                "/products/{id}/reviews",
+	"POST /votereview":               "/reviews/{id}/votes",
+	"POST /addaddress":               "/me/addresses",
+	"PUT /edithomeaddress":           "/me/addresses/home",
+	"PUT /editworkaddress":           "/me/addresses/work",
+	"DELETE /deleteaddresses":        "/me/addresses",
+	"GET /notificationprefs":         "/me/notification-preferences",
+	"PUT /notificationprefs":         "/me/notification-preferences",
+	"GET /listwishlists":             "/me/wishlists",
+	"POST /createwishlist":           "/me/wishlists",
+	"GET /wishlistpricedrops":        "/me/wishlists/price-drops",
+	"DELETE /deletewishlist":         "/me/wishlists/{id}",
+	"POST /sharewishlist":            "/me/wishlists/{id}/share",
+	"DELETE /sharewishlist":          "/me/wishlists/{id}/share",
+	"POST /addtowishlist":            "/me/wishlists/{id}/items/{productID}",
+	"DELETE /removefromwishlist":     "/me/wishlists/{id}/items/{productID}",
+	"POST /wishlisttocart":           "/me/wishlists/{id}/items/{productID}/move-to-cart",
+	"POST /admin/addproduct":         "/admin/products",
+	"PUT /admin/setproductoptions":   "/admin/products/{id}/options",
+	"PUT /admin/editvariant":         "/admin/products/{id}/variants/{variantID}",
+	"POST /admin/assigncategories":   "/admin/products/{id}/categories",
+	"DELETE /admin/unassigncategory": "/admin/products/{id}/categories/{categoryID}",
+	"POST /admin/addcategory":        "/admin/categories",
+	"PUT /admin/editcategory":        "/admin/categories/{id}",
+	"DELETE /admin/deletecategory":   "/admin/categories/{id}",
+	"POST /admin/addshippingmethod":  "/admin/shipping-methods",
+	"POST /admin/createshipment":     "/admin/orders/{id}/shipments",
+	"POST /admin/updateshipment":     "/admin/shipments/{id}/events",
+	"GET /admin/reviews":             "/admin/reviews",
+	"PUT /admin/moderatereview":      "/admin/reviews/{id}",
+	"GET /admin/cartreminders":       "/admin/cart-reminders",
+	"GET /admin/jobs":                "/admin/jobs",
+	"GET /admin/job":                 "/admin/jobs/{id}",
+	"POST /admin/retryjob":           "/admin/jobs/{id}/retry",
+	"GET /admin/recurringjobs":       "/admin/recurring-jobs",
+	"POST /admin/runrecurringjob":    "/admin/recurring-jobs/{name}/run",
+	"POST /admin/addwebhook":         "/admin/webhooks",
+	"GET /admin/webhooks":            "/admin/webhooks",
+	"PUT /admin/editwebhook":         "/admin/webhooks/{id}",
+	"DELETE /admin/deletewebhook":    "/admin/webhooks/{id}",
+	"GET /admin/webhookdeliveries":   "/admin/webhook-deliveries",
+	"GET /admin/webhookdelivery":     "/admin/webhook-deliveries/{id}",
+	"POST /admin/redeliverwebhook":   "/admin/webhook-deliveries/{id}/redeliver",
+}
+
+// Deprecation marks responses of the legacy routes with the Deprecation,
+// Sunset and Link headers (RFC 9745, RFC 8594) pointing at the /api/v1 route.
+// Register it before the routes.
+func Deprecation() gin.HandlerFunc {
+
+	deprecation := fmt.Sprintf("@%d", LegacyDeprecatedAt.Unix())
+	sunset := LegacySunset.Format(http.TimeFormat)
+
+	return func(c *gin.Context) {
+		successor, ok := legacySuccessors[c.Request.Method+" "+c.FullPath()]
+		if ok {
+			c.Header("Deprecation", deprecation)
+			c.Header("Sunset", sunset)
+			c.Header("Link", fmt.Sprintf("<%s%s>; rel=\"successor-version\"", APIPrefix, successor))
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,308 @@
+package routes
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mayuka-c/e-commerce/dto"
+	"github.com/mayuka-c/e-commerce/health"
+	"github.com/mayuka-c/e-commerce/models"
+	"github.com/mayuka-c/e-commerce/openapi"
+	"github.com/mayuka-c/e-commerce/search"
+)
+
+// Documentation types for the responses the handlers build with gin.H
+
+type message struct {
+	Msg string `json:"msg"`
+}
+
+type cartView struct {
+	Result      []models.ProductUser `json:"result"`
+	Total_Price int                  `json:"totalPrice"`
+	Issues      []models.CartIssue   `json:"issues"`
+	// Version is sent back on checkout to confirm the reviewed cart
+	Version string `json:"version"`
+}
+
+type guestCartView struct {
+	Result      []models.ProductUser `json:"result"`
+	Total_Price int                  `json:"totalPrice"`
+	Issues      []models.CartIssue   `json:"issues,omitempty"`
+	Expires_At  time.Time            `json:"expires_at"`
+}
+
+type checkoutResult struct {
+	Msg    string             `json:"msg"`
+	Issues []models.CartIssue `json:"issues"`
+}
+
+type categoryTree struct {
+	models.Category
+	Children []categoryTree `json:"children"`
+}
+
+type suggestions struct {
+	Query        string         `json:"query"`
+	Completions  []search.Entry `json:"completions"`
+	Did_You_Mean []string       `json:"did_you_mean"`
+}
+
+type reviewPage struct {
+	Rating       float64         `json:"rating,omitempty"`
+	Rating_Count int64           `json:"rating_count,omitempty"`
+	Items        []models.Review `json:"items"`
+	Total        int64           `json:"total"`
+	Page         int64           `json:"page"`
+	Limit        int64           `json:"limit"`
+}
+
+type shippingQuotes struct {
+	Weight      uint64                 `json:"weight"`
+	Order_Value uint64                 `json:"order_value"`
+	Quotes      []models.ShippingQuote `json:"quotes"`
+}
+
+type shareLink struct {
+	Share_Token string `json:"share_token"`
+	Share_Link  string `json:"share_link"`
+}
+
+type sharedWishlist struct {
+	Name       *string               `json:"name"`
+	Items      []models.WishlistItem `json:"items"`
+	Updated_At time.Time             `json:"updated_at"`
+}
+
+// DocsRoutes serves the OpenAPI document and Swagger UI. They need no token.
+func DocsRoutes(incomingRoutes *gin.Engine, spec *openapi.Spec) {
+	incomingRoutes.GET("/openapi.json", openapi.Handler(spec))
+	incomingRoutes.GET("/docs", openapi.UI("/openapi.json"))
+}
+
+// Spec documents every route of the API. A route without an entry here fails
+// the startup check, so add the entry together with the route.
+func Spec() *openapi.Spec {
+
+	spec := openapi.New("E-commerce API", "1.0.0")
+	spec.Rule("phone", openapi.Schema{Pattern: dto.PhonePattern, Description: "E.164 phone number"})
+	spec.Rule("pincode", openapi.Schema{Pattern: dto.PincodePattern})
+	spec.Rule("price", openapi.Schema{Minimum: new(float64)})
+
+	spec.Add(http.MethodGet, "/openapi.json", openapi.Operation{Summary: "This OpenAPI document", Tags: []string{"docs"}})
+	spec.Add(http.MethodGet, "/docs", openapi.Operation{Summary: "Swagger UI for this document", Tags: []string{"docs"}})
+	spec.Add(http.MethodGet, "/metrics", openapi.Operation{
+		Summary:     "Prometheus metrics",
+		Description: "HTTP, MongoDB and business metrics in the Prometheus text format",
+		Tags:        []string{"ops"},
+	})
+	spec.Add(http.MethodGet, "/healthz", openapi.Operation{
+		Summary:     "Liveness probe",
+		Description: "Answers 200 while the process can serve requests; no dependency is checked",
+		Tags:        []string{"ops"},
+		Response:    health.Report{},
+	})
+	spec.Add(http.MethodGet, "/readyz", openapi.Operation{
+		Summary:     "Readiness probe",
+		Description: "Runs the dependency checks, such as a MongoDB ping. Answers 503 with the same report when a check fails or the service is shutting down.",
+		Tags:        []string{"ops"},
+		Response:    health.Report{},
+	})
+
+	v1Spec(spec)
+	legacySpec(spec)
+
+	return spec
+}
+
+var (
+	productID  = openapi.IDParam("id", "product id")
+	variantID  = openapi.QueryParam("variantID", "variant id, for products with options")
+	pageParams = []openapi.Param{
+		openapi.IntQueryParam("page", "page number, 1 by default and at most 10000"),
+		openapi.IntQueryParam("limit", "page size, 20 by default and at most 100"),
+	}
+	productListParams = append([]openapi.Param{
+		openapi.EnumQueryParam("sort", "order, prefix with - for descending; by id when not set", "price", "-price", "rating", "-rating", "name", "-name", "newest"),
+		openapi.QueryParam("min_price", "lowest price"),
+		openapi.QueryParam("max_price", "highest price"),
+		openapi.QueryParam("min_rating", "lowest rating"),
+		openapi.QueryParam("max_rating", "highest rating"),
+		openapi.QueryParam("category", "category slug, repeat to select several"),
+		openapi.EnumQueryParam("price_band", "price band, repeat to select several", "0-500", "500-1000", "1000-5000", "5000-"),
+		openapi.QueryParam("rating", "minimum stars 1 to 5, repeat to select several"),
+		openapi.EnumQueryParam("facets", "true to include the facet counts", "true", "false"),
+	}, pageParams...)
+	guestCartParams = []openapi.Param{
+		openapi.HeaderParam("X-Cart-Token", "guest cart token; the cart_token cookie works as well"),
+	}
+)
+
+func v1Spec(spec *openapi.Spec) {
+
+	public := func(method, path string, op openapi.Operation) {
+		spec.Add(method, APIPrefix+path, op)
+	}
+	private := func(method, path string, op openapi.Operation) {
+		op.Auth = true
+		spec.Add(method, APIPrefix+path, op)
+	}
+
+	public(http.MethodPost, "/auth/signup", openapi.Operation{Summary: "Sign up", Tags: []string{"auth"}, Body: dto.SignUp{}, Response: message{}, Status: http.StatusCreated})
+	public(http.MethodPost, "/auth/login", openapi.Operation{Summary: "Log in and get a token", Tags: []string{"auth"}, Body: dto.Login{}, Response: models.User{}})
+	public(http.MethodPost, "/auth/forgot-password", openapi.Operation{Summary: "Email a password reset link", Description: "Answers the same whether or not the email is known.", Tags: []string{"auth"}, Body: dto.ForgotPassword{}, Response: message{}})
+	public(http.MethodPost, "/auth/reset-password", openapi.Operation{Summary: "Set a new password with a reset token", Tags: []string{"auth"}, Body: dto.ResetPassword{}, Response: message{}})
+	public(http.MethodGet, "/unsubscribe", openapi.Operation{Summary: "Stop cart reminder emails", Tags: []string{"account"}, Params: []openapi.Param{required(openapi.QueryParam("token", "unsubscribe token from the email"))}, Response: message{}})
+
+	public(http.MethodGet, "/products", openapi.Operation{Summary: "List products", Tags: []string{"products"}, Params: productListParams, Response: models.ProductPage{}})
+	public(http.MethodGet, "/products/search", openapi.Operation{Summary: "Search products by name", Tags: []string{"products"}, Params: append([]openapi.Param{
+		required(openapi.QueryParam("name", "search terms")),
+		openapi.EnumQueryParam("mode", "text ranks by relevance, contains matches part of the name", "text", "contains"),
+	}, productListParams...), Response: models.ProductPage{}})
+	public(http.MethodGet, "/products/suggestions", openapi.Operation{Summary: "Autocomplete product and category names", Tags: []string{"products"}, Params: []openapi.Param{
+		required(openapi.QueryParam("q", "what was typed so far")),
+		openapi.IntQueryParam("limit", "number of completions"),
+	}, Response: suggestions{}})
+	public(http.MethodGet, "/products/:id/reviews", openapi.Operation{Summary: "List the approved reviews of a product", Tags: []string{"reviews"}, Params: append([]openapi.Param{
+		productID,
+		openapi.EnumQueryParam("sort", "helpful by default", "helpful", "newest"),
+	}, pageParams...), Response: reviewPage{}})
+	public(http.MethodGet, "/categories", openapi.Operation{Summary: "Category tree", Tags: []string{"categories"}, Response: []categoryTree{}})
+	public(http.MethodGet, "/shared-wishlists/:token", openapi.Operation{Summary: "View a shared wishlist", Tags: []string{"wishlists"}, Response: sharedWishlist{}})
+
+	public(http.MethodGet, "/guest/cart", openapi.Operation{Summary: "Guest cart", Tags: []string{"guest cart"}, Params: guestCartParams, Response: guestCartView{}})
+	public(http.MethodPost, "/guest/cart/items/:id", openapi.Operation{Summary: "Add to the guest cart", Description: "Creates the cart and sets the cart_token cookie when there is none.", Tags: []string{"guest cart"}, Params: append([]openapi.Param{productID, variantID}, guestCartParams...), Response: guestCartView{}})
+	public(http.MethodDelete, "/guest/cart/items/:id", openapi.Operation{Summary: "Remove from the guest cart", Tags: []string{"guest cart"}, Params: append([]openapi.Param{productID, variantID}, guestCartParams...), Response: guestCartView{}})
+
+	private(http.MethodGet, "/cart", openapi.Operation{Summary: "Cart of the user", Tags: []string{"cart"}, Response: cartView{}})
+	private(http.MethodPost, "/cart/items/:id", openapi.Operation{Summary: "Add to the cart", Tags: []string{"cart"}, Params: []openapi.Param{productID, variantID}, Response: message{}})
+	private(http.MethodDelete, "/cart/items/:id", openapi.Operation{Summary: "Remove from the cart", Tags: []string{"cart"}, Params: []openapi.Param{productID, variantID}, Response: message{}})
+	private(http.MethodPost, "/cart/items/:id/save-for-later", openapi.Operation{Summary: "Move a cart line to the saved for later list", Tags: []string{"cart", "wishlists"}, Params: []openapi.Param{productID, variantID}, Response: message{}})
+
+	private(http.MethodPost, "/orders", openapi.Operation{Summary: "Check out the cart", Description: "Fails with cart_changed, listing the issues and the version of the cart as it would be ordered, when prices or stock changed or the cart no longer matches version; retry with that version once the user has reviewed it.", Tags: []string{"orders"}, Params: []openapi.Param{openapi.QueryParam("version", "version of the cart the user reviewed, from the cart view or the cart_changed error")}, Response: checkoutResult{}})
+	private(http.MethodPost, "/products/:id/purchase", openapi.Operation{Summary: "Buy one product now", Tags: []string{"orders"}, Params: []openapi.Param{productID, variantID}, Response: message{}})
+	private(http.MethodPost, "/orders/:id/cancel", openapi.Operation{Summary: "Cancel an order that has not shipped", Tags: []string{"orders"}, Params: []openapi.Param{openapi.IDParam("id", "order id")}, Body: dto.CancelOrder{}, OptionalBody: true, Response: models.Order{}})
+	private(http.MethodGet, "/orders/:id/shipment", openapi.Operation{Summary: "Track the shipments of an order", Tags: []string{"shipping"}, Params: []openapi.Param{openapi.IDParam("id", "order id")}, Response: []models.Shipment{}})
+	private(http.MethodGet, "/shipping/quote", openapi.Operation{Summary: "Shipping quotes for the cart", Tags: []string{"shipping"}, Params: []openapi.Param{openapi.EnumQueryParam("address", "home by default", "home", "work")}, Response: shippingQuotes{}})
+
+	private(http.MethodPost, "/products/:id/reviews", openapi.Operation{Summary: "Review a product you ordered", Tags: []string{"reviews"}, Params: []openapi.Param{productID}, Body: dto.Review{}, Response: models.Review{}, Status: http.StatusCreated})
+	private(http.MethodPost, "/reviews/:id/votes", openapi.Operation{Summary: "Mark a review as helpful", Tags: []string{"reviews"}, Params: []openapi.Param{openapi.IDParam("id", "review id")}, Response: message{}})
+
+	private(http.MethodPost, "/me/addresses", openapi.Operation{Summary: "Add an address", Tags: []string{"account"}, Body: dto.Address{}, Response: message{}})
+	private(http.MethodPut, "/me/addresses/home", openapi.Operation{Summary: "Replace the home address", Tags: []string{"account"}, Body: dto.Address{}, Response: message{}})
+	private(http.MethodPut, "/me/addresses/work", openapi.Operation{Summary: "Replace the work address", Tags: []string{"account"}, Body: dto.Address{}, Response: message{}})
+	private(http.MethodDelete, "/me/addresses", openapi.Operation{Summary: "Delete all addresses", Tags: []string{"account"}, Response: message{}})
+	private(http.MethodGet, "/me/notification-preferences", openapi.Operation{Summary: "Email preferences", Tags: []string{"account"}, Response: models.NotificationPrefs{}})
+	private(http.MethodPut, "/me/notification-preferences", openapi.Operation{Summary: "Change the email preferences", Tags: []string{"account"}, Body: dto.NotificationPrefs{}, Response: message{}})
+
+	wishlistID := openapi.IDParam("id", "wishlist id")
+	wishlistItem := []openapi.Param{wishlistID, openapi.IDParam("productID", "product id"), variantID}
+	private(http.MethodGet, "/me/wishlists", openapi.Operation{Summary: "List wishlists", Tags: []string{"wishlists"}, Response: []models.Wishlist{}})
+	private(http.MethodPost, "/me/wishlists", openapi.Operation{Summary: "Create a wishlist", Tags: []string{"wishlists"}, Body: dto.Wishlist{}, Response: models.Wishlist{}, Status: http.StatusCreated})
+	private(http.MethodGet, "/me/wishlists/price-drops", openapi.Operation{Summary: "Wishlisted items that got cheaper", Tags: []string{"wishlists"}, Response: []models.PriceDrop{}})
+	private(http.MethodDelete, "/me/wishlists/:id", openapi.Operation{Summary: "Delete a wishlist", Tags: []string{"wishlists"}, Params: []openapi.Param{wishlistID}, Response: message{}})
+	private(http.MethodPut, "/me/wishlists/:id/share", openapi.Operation{Summary: "Create a share link", Tags: []string{"wishlists"}, Params: []openapi.Param{wishlistID}, Response: shareLink{}})
+	private(http.MethodDelete, "/me/wishlists/:id/share", openapi.Operation{Summary: "Revoke the share link", Tags: []string{"wishlists"}, Params: []openapi.Param{wishlistID}, Response: message{}})
+	private(http.MethodPost, "/me/wishlists/:id/items/:productID", openapi.Operation{Summary: "Add to a wishlist", Tags: []string{"wishlists"}, Params: wishlistItem, Response: message{}})
+	private(http.MethodDelete, "/me/wishlists/:id/items/:productID", openapi.Operation{Summary: "Remove from a wishlist", Tags: []string{"wishlists"}, Params: wishlistItem, Response: message{}})
+	private(http.MethodPost, "/me/wishlists/:id/items/:productID/move-to-cart", openapi.Operation{Summary: "Move a wishlist item to the cart", Tags: []string{"wishlists", "cart"}, Params: wishlistItem, Response: message{}})
+
+	admin := func(method, path string, op openapi.Operation) {
+		op.Tags = append(op.Tags, "admin")
+		op.Description = strings.TrimSpace(op.Description + " Needs a token issued to an admin.")
+		private(method, "/admin"+path, op)
+	}
+	categoryID := openapi.IDParam("id", "category id")
+	admin(http.MethodPost, "/products", openapi.Operation{Summary: "Add a product", Body: dto.Product{}, Response: message{}})
+	admin(http.MethodPut, "/products/:id/options", openapi.Operation{Summary: "Set the options of a product and regenerate its variants", Description: "The option values may combine into at most 100 variants.", Params: []openapi.Param{productID}, Body: dto.ProductOptions{}, Response: models.Product{}})
+	admin(http.MethodPut, "/products/:id/variants/:variantID", openapi.Operation{Summary: "Change a variant", Params: []openapi.Param{productID, openapi.IDParam("variantID", "variant id")}, Body: dto.VariantUpdate{}, Response: message{}})
+	admin(http.MethodPost, "/products/:id/categories", openapi.Operation{Summary: "Add a product to categories", Params: []openapi.Param{productID}, Body: dto.CategoryAssignment{}, Response: message{}})
+	admin(http.MethodDelete, "/products/:id/categories/:categoryID", openapi.Operation{Summary: "Remove a product from a category", Params: []openapi.Param{productID, openapi.IDParam("categoryID", "category id")}, Response: message{}})
+	admin(http.MethodPost, "/categories", openapi.Operation{Summary: "Add a category", Body: dto.Category{}, Response: models.Category{}, Status: http.StatusCreated})
+	admin(http.MethodPut, "/categories/:id", openapi.Operation{Summary: "Rename or move a category", Params: []openapi.Param{categoryID}, Body: dto.Category{}, Response: models.Category{}})
+	admin(http.MethodDelete, "/categories/:id", openapi.Operation{Summary: "Delete a category without subcategories", Params: []openapi.Param{categoryID}, Response: message{}})
+
+	admin(http.MethodPost, "/shipping-methods", openapi.Operation{Summary: "Add a shipping method", Body: dto.ShippingMethod{}, Response: models.ShippingMethod{}, Status: http.StatusCreated})
+	admin(http.MethodPost, "/orders/:id/shipments", openapi.Operation{Summary: "Ship an order", Params: []openapi.Param{
+		openapi.IDParam("id", "order id"),
+		required(openapi.QueryParam("userID", "user who placed the order")),
+		required(openapi.QueryParam("methodID", "shipping method id")),
+	}, Response: models.Shipment{}, Status: http.StatusCreated})
+	admin(http.MethodPost, "/shipments/:id/events", openapi.Operation{Summary: "Record a tracking event", Params: []openapi.Param{openapi.IDParam("id", "shipment id")}, Body: dto.ShipmentEvent{}, Response: message{}})
+
+	admin(http.MethodGet, "/reviews", openapi.Operation{Summary: "Reviews waiting for moderation", Params: append([]openapi.Param{openapi.EnumQueryParam("status", "pending by default", "pending", "approved", "rejected")}, pageParams...), Response: reviewPage{}})
+	admin(http.MethodPut, "/reviews/:id", openapi.Operation{Summary: "Approve or reject a review", Params: []openapi.Param{
+		openapi.IDParam("id", "review id"),
+		required(openapi.EnumQueryParam("status", "", "approved", "rejected")),
+	}, Response: models.Review{}})
+	admin(http.MethodGet, "/cart-reminders", openapi.Operation{Summary: "Cart reminder conversion", Params: []openapi.Param{openapi.IntQueryParam("days", "period in days, 30 by default")}, Response: models.CartReminderStats{}})
+
+	jobID := openapi.IDParam("id", "job id")
+	admin(http.MethodGet, "/jobs", openapi.Operation{Summary: "List background jobs", Params: []openapi.Param{
+		openapi.EnumQueryParam("status", "", "pending", "running", "succeeded", "failed", "dead"),
+		openapi.QueryParam("kind", "job kind"),
+		openapi.IntQueryParam("limit", "number of jobs, 50 by default"),
+	}, Response: []models.Job{}})
+	admin(http.MethodGet, "/jobs/:id", openapi.Operation{Summary: "Get a job", Params: []openapi.Param{jobID}, Response: models.Job{}})
+	admin(http.MethodPost, "/jobs/:id/retry", openapi.Operation{Summary: "Retry a failed job", Params: []openapi.Param{jobID}, Response: message{}})
+	admin(http.MethodGet, "/recurring-jobs", openapi.Operation{Summary: "List recurring jobs", Response: []models.RecurringJob{}})
+	admin(http.MethodPost, "/recurring-jobs/:name/run", openapi.Operation{Summary: "Run a recurring job now", Params: []openapi.Param{openapi.PathParam("name", "recurring job name")}, Response: message{}})
+
+	webhookID := openapi.IDParam("id", "webhook endpoint id")
+	deliveryID := openapi.IDParam("id", "delivery id")
+	admin(http.MethodPost, "/webhooks", openapi.Operation{Summary: "Add a webhook endpoint", Description: "The response is the only place the signing secret is shown.", Body: dto.WebhookEndpoint{}, Response: models.WebhookEndpoint{}, Status: http.StatusCreated})
+	admin(http.MethodGet, "/webhooks", openapi.Operation{Summary: "List webhook endpoints", Response: []models.WebhookEndpoint{}})
+	admin(http.MethodPut, "/webhooks/:id", openapi.Operation{Summary: "Change a webhook endpoint", Params: []openapi.Param{webhookID}, Body: dto.WebhookEndpointUpdate{}, Response: message{}})
+	admin(http.MethodDelete, "/webhooks/:id", openapi.Operation{Summary: "Delete a webhook endpoint", Params: []openapi.Param{webhookID}, Response: message{}})
+	admin(http.MethodGet, "/webhook-deliveries", openapi.Operation{Summary: "List webhook deliveries", Params: []openapi.Param{
+		openapi.QueryParam("endpointID", "webhook endpoint id"),
+		openapi.EnumQueryParam("status", "dead lists the dead-letter queue", "pending", "delivered", "failed", "dead"),
+		openapi.IntQueryParam("limit", "number of deliveries, 50 by default"),
+	}, Response: []models.WebhookDelivery{}})
+	admin(http.MethodGet, "/webhook-deliveries/:id", openapi.Operation{Summary: "Get a webhook delivery", Params: []openapi.Param{deliveryID}, Response: models.WebhookDelivery{}})
+	admin(http.MethodPost, "/webhook-deliveries/:id/redeliver", openapi.Operation{Summary: "Send a delivered or dead delivery again", Params: []openapi.Param{deliveryID}, Response: message{}, Status: http.StatusAccepted})
+
+	admin(http.MethodGet, "/config", openapi.Operation{
+		Summary:     "Show the settings the service runs with",
+		Description: "Grouped by section as in the settings file. Secrets and the password of the database URL are redacted.",
+		Response:    map[string]map[string]interface{}{},
+	})
+}
+
+// legacySpec documents each legacy route as a deprecated copy of its
+// successor. The legacy routes take the ids as query parameters.
+func legacySpec(spec *openapi.Spec) {
+
+	for route, successor := range legacySuccessors {
+		method, path, _ := strings.Cut(route, " ")
+
+		op, ok := spec.Operation(method, APIPrefix+successor)
+		for _, other := range []string{http.MethodPut, http.MethodPost, http.MethodDelete, http.MethodGet} {
+			if ok {
+				break
+			}
+			op, ok = spec.Operation(other, APIPrefix+successor)
+		}
+
+		var params []openapi.Param
+		for _, param := range op.Params {
+			if param.In != "path" {
+				params = append(params, param)
+			}
+		}
+
+		op.Params = params
+		op.Deprecated = true
+		op.Description = "Use " + APIPrefix + successor + ". Removed after " + LegacySunset.Format("2006-01-02") + "."
+		op.Tags = []string{"legacy"}
+		spec.Add(method, path, op)
+	}
+}
+
+func required(param openapi.Param) openapi.Param {
+	param.Required = true
+	return param
+}
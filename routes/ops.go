@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/mayuka-c/e-commerce/health"
+	"github.com/mayuka-c/e-commerce/metrics"
+)
+
+// OpsRoutes serves the endpoints operators and their tooling poll. They need
+// no token.
+func OpsRoutes(incomingRoutes *gin.Engine, checker *health.Checker) {
+	incomingRoutes.GET("/metrics", gin.WrapH(metrics.Handler()))
+	incomingRoutes.GET("/healthz", checker.Live())
+	incomingRoutes.GET("/readyz", checker.Ready())
+}
@@ -0,0 +1,35 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/mayuka-c/e-commerce/controllers"
+	"github.com/mayuka-c/e-commerce/health"
+	"github.com/mayuka-c/e-commerce/openapi"
+)
+
+// Register adds every route of the service to the router and returns the
+// OpenAPI spec documenting them. Routes added after authentication need a
+// token.
+func Register(router *gin.Engine, handler *controllers.Application, checker *health.Checker, authentication gin.HandlerFunc) *openapi.Spec {
+
+	spec := Spec()
+	DocsRoutes(router, spec)
+	OpsRoutes(router, checker)
+
+	UserRoutes(router, handler)
+	GuestRoutes(router, handler)
+	V1PublicRoutes(router, handler)
+	router.Use(authentication)
+
+	V1Routes(router, handler)
+
+	ProductRoutes(router, handler)
+	AddressRoutes(router, handler)
+	AccountRoutes(router, handler)
+	WishlistRoutes(router, handler)
+	ShippingRoutes(router, handler)
+	AdminRoutes(router, handler)
+
+	return spec
+}
@@ -4,14 +4,27 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"github.com/mayuka-c/e-commerce/controllers"
+	"github.com/mayuka-c/e-commerce/middleware"
 )
 
 func UserRoutes(incomingRoutes *gin.Engine, handler *controllers.Application) {
 	incomingRoutes.POST("/users/signup", handler.SignUp())
 	incomingRoutes.POST("/users/login", handler.Login())
-	incomingRoutes.POST("/admin/addproduct", handler.ProductViewerAdmin())
 	incomingRoutes.GET("/users/productview", handler.SearchProducts())
 	incomingRoutes.GET("/users/search", handler.SearchProductsByQuery())
+	incomingRoutes.GET("/users/search/suggest", handler.SuggestProducts())
+	incomingRoutes.GET("/users/categories", handler.ListCategories())
+	incomingRoutes.GET("/users/reviews", handler.GetProductReviews())
+	incomingRoutes.GET("/users/sharedwishlist", handler.SharedWishlist())
+	incomingRoutes.GET("/users/unsubscribe", handler.Unsubscribe())
+	incomingRoutes.POST("/users/forgotpassword", handler.ForgotPassword())
+	incomingRoutes.POST("/users/resetpassword", handler.ResetPassword())
+}
+
+func GuestRoutes(incomingRoutes *gin.Engine, handler *controllers.Application) {
+	incomingRoutes.POST("/guest/addtocart", handler.GuestAddToCart())
+	incomingRoutes.DELETE("/guest/removeitem", handler.GuestRemoveItem())
+	incomingRoutes.GET("/guest/listcart", handler.GuestListCart())
 }
 
 func ProductRoutes(incomingRoutes *gin.Engine, handler *controllers.Application) {
@@ -20,6 +33,9 @@ func ProductRoutes(incomingRoutes *gin.Engine, handler *controllers.Application)
 	incomingRoutes.GET("/listcart", handler.GetItemFromCart())
 	incomingRoutes.POST("/cartcheckout", handler.BuyFromCart())
 	incomingRoutes.POST("/instantbuy", handler.InstantBuy())
+	incomingRoutes.POST("/cancelorder", handler.CancelOrder())
+	incomingRoutes.POST("/addreview", handler.AddReview())
+	incomingRoutes.POST("/votereview", handler.VoteReview())
 }
 
 func AddressRoutes(incomingRoutes *gin.Engine, handler *controllers.Application) {
@@ -28,3 +44,59 @@ func AddressRoutes(incomingRoutes *gin.Engine, handler *controllers.Application)
 	incomingRoutes.PUT("/editworkaddress", handler.EditWorkAddress())
 	incomingRoutes.DELETE("/deleteaddresses", handler.DeleteAddress())
 }
+
+func AccountRoutes(incomingRoutes *gin.Engine, handler *controllers.Application) {
+	incomingRoutes.GET("/notificationprefs", handler.GetNotificationPrefs())
+	incomingRoutes.PUT("/notificationprefs", handler.UpdateNotificationPrefs())
+}
+
+func WishlistRoutes(incomingRoutes *gin.Engine, handler *controllers.Application) {
+	incomingRoutes.POST("/createwishlist", handler.CreateWishlist())
+	incomingRoutes.GET("/listwishlists", handler.ListWishlists())
+	incomingRoutes.DELETE("/deletewishlist", handler.DeleteWishlist())
+	incomingRoutes.POST("/addtowishlist", handler.AddToWishlist())
+	incomingRoutes.DELETE("/removefromwishlist", handler.RemoveFromWishlist())
+	incomingRoutes.POST("/wishlisttocart", handler.MoveWishlistItemToCart())
+	incomingRoutes.POST("/saveforlater", handler.SaveForLater())
+	incomingRoutes.POST("/sharewishlist", handler.ShareWishlist())
+	incomingRoutes.DELETE("/sharewishlist", handler.UnshareWishlist())
+	incomingRoutes.GET("/wishlistpricedrops", handler.WishlistPriceDrops())
+}
+
+func ShippingRoutes(incomingRoutes *gin.Engine, handler *controllers.Application) {
+	incomingRoutes.GET("/shippingquote", handler.ShippingQuote())
+	incomingRoutes.GET("/trackshipment", handler.TrackShipment())
+}
+
+// AdminRoutes are only open to admins. Register them after the
+// authentication middleware.
+func AdminRoutes(incomingRoutes *gin.Engine, handler *controllers.Application) {
+	admin := incomingRoutes.Group("/admin", middleware.Admin())
+
+	admin.POST("/addproduct", handler.ProductViewerAdmin())
+	admin.POST("/addshippingmethod", handler.AddShippingMethod())
+	admin.POST("/createshipment", handler.CreateShipment())
+	admin.POST("/updateshipment", handler.UpdateShipment())
+	admin.POST("/addcategory", handler.AddCategory())
+	admin.PUT("/editcategory", handler.EditCategory())
+	admin.DELETE("/deletecategory", handler.DeleteCategory())
+	admin.POST("/assigncategories", handler.AssignCategories())
+	admin.DELETE("/unassigncategory", handler.UnassignCategory())
+	admin.PUT("/setproductoptions", handler.SetProductOptions())
+	admin.PUT("/editvariant", handler.EditVariant())
+	admin.GET("/reviews", handler.ListReviewsForModeration())
+	admin.PUT("/moderatereview", handler.ModerateReview())
+	admin.GET("/cartreminders", handler.CartReminderStats())
+	admin.GET("/jobs", handler.ListJobs())
+	admin.GET("/job", handler.GetJob())
+	admin.POST("/retryjob", handler.RetryJob())
+	admin.GET("/recurringjobs", handler.ListRecurringJobs())
+	admin.POST("/runrecurringjob", handler.RunRecurringJob())
+	admin.POST("/addwebhook", handler.AddWebhook())
+	admin.GET("/webhooks", handler.ListWebhooks())
+	admin.PUT("/editwebhook", handler.EditWebhook())
+	admin.DELETE("/deletewebhook", handler.DeleteWebhook())
+	admin.GET("/webhookdeliveries", handler.ListWebhookDeliveries())
+	admin.GET("/webhookdelivery", handler.GetWebhookDelivery())
+	admin.POST("/redeliverwebhook", handler.RedeliverWebhook())
+}
@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mayuka-c/e-commerce/controllers"
+	"github.com/mayuka-c/e-commerce/health"
+)
+
+func TestEveryRouteIsDocumented(t *testing.T) {
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	// handlers are only built here, never called, so an empty app will do
+	spec := Register(router, &controllers.Application{}, health.NewChecker(), func(c *gin.Context) { c.Next() })
+
+	if missing := spec.Missing(router.Routes()); len(missing) > 0 {
+		t.Errorf("routes without an OpenAPI entry in routes/openapi.go: %s", strings.Join(missing, ", "))
+	}
+}
@@ -0,0 +1,120 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/mayuka-c/e-commerce/controllers"
+	"github.com/mayuka-c/e-commerce/middleware"
+)
+
+// APIPrefix is where the versioned API lives
+const APIPrefix = "/api/v1"
+
+// V1PublicRoutes are the /api/v1 routes that work without a token. Register
+// them before the authentication middleware.
+func V1PublicRoutes(incomingRoutes *gin.Engine, handler *controllers.Application) {
+	v1 := incomingRoutes.Group(APIPrefix)
+
+	v1.POST("/auth/signup", handler.SignUp())
+	v1.POST("/auth/login", handler.Login())
+	v1.POST("/auth/forgot-password", handler.ForgotPassword())
+	v1.POST("/auth/reset-password", handler.ResetPassword())
+	v1.GET("/unsubscribe", handler.Unsubscribe())
+
+	v1.GET("/products", handler.SearchProducts())
+	v1.GET("/products/search", handler.SearchProductsByQuery())
+	v1.GET("/products/suggestions", handler.SuggestProducts())
+	v1.GET("/products/:id/reviews", query("id", "id"), handler.GetProductReviews())
+	v1.GET("/categories", handler.ListCategories())
+	v1.GET("/shared-wishlists/:token", query("token", "token"), handler.SharedWishlist())
+
+	v1.GET("/guest/cart", handler.GuestListCart())
+	v1.POST("/guest/cart/items/:id", query("id", "id"), handler.GuestAddToCart())
+	v1.DELETE("/guest/cart/items/:id", query("id", "id"), handler.GuestRemoveItem())
+}
+
+// V1Routes are the /api/v1 routes that need a token. Routes under /me, and
+// the cart and order routes, act on the user the token was issued to. Routes
+// under /admin need a token issued to an admin.
+func V1Routes(incomingRoutes *gin.Engine, handler *controllers.Application) {
+	v1 := incomingRoutes.Group(APIPrefix)
+
+	v1.GET("/cart", handler.GetItemFromCart())
+	v1.POST("/cart/items/:id", query("id", "id"), handler.AddToCart())
+	v1.DELETE("/cart/items/:id", query("id", "id"), handler.RemoveItemFromCart())
+	v1.POST("/cart/items/:id/save-for-later", query("id", "id"), handler.SaveForLater())
+
+	v1.POST("/orders", handler.BuyFromCart())
+	v1.POST("/products/:id/purchase", query("id", "id"), handler.InstantBuy())
+	v1.POST("/orders/:id/cancel", query("id", "orderID"), handler.CancelOrder())
+	v1.GET("/orders/:id/shipment", query("id", "orderID"), handler.TrackShipment())
+	v1.GET("/shipping/quote", handler.ShippingQuote())
+
+	v1.POST("/products/:id/reviews", query("id", "id"), handler.AddReview())
+	v1.POST("/reviews/:id/votes", query("id", "id"), handler.VoteReview())
+
+	v1.POST("/me/addresses", handler.AddAddress())
+	v1.PUT("/me/addresses/home", handler.EditHomeAddress())
+	v1.PUT("/me/addresses/work", handler.EditWorkAddress())
+	v1.DELETE("/me/addresses", handler.DeleteAddress())
+	v1.GET("/me/notification-preferences", handler.GetNotificationPrefs())
+	v1.PUT("/me/notification-preferences", handler.UpdateNotificationPrefs())
+
+	v1.GET("/me/wishlists", handler.ListWishlists())
+	v1.POST("/me/wishlists", handler.CreateWishlist())
+	v1.GET("/me/wishlists/price-drops", handler.WishlistPriceDrops())
+	v1.DELETE("/me/wishlists/:id", query("id", "wishlistID"), handler.DeleteWishlist())
+	v1.PUT("/me/wishlists/:id/share", query("id", "wishlistID"), handler.ShareWishlist())
+	v1.DELETE("/me/wishlists/:id/share", query("id", "wishlistID"), handler.UnshareWishlist())
+	v1.POST("/me/wishlists/:id/items/:productID", query("id", "wishlistID", "productID", "id"), handler.AddToWishlist())
+	v1.DELETE("/me/wishlists/:id/items/:productID", query("id", "wishlistID", "productID", "id"), handler.RemoveFromWishlist())
+	v1.POST("/me/wishlists/:id/items/:productID/move-to-cart", query("id", "wishlistID", "productID", "id"), handler.MoveWishlistItemToCart())
+
+	admin := v1.Group("/admin", middleware.Admin())
+
+	admin.POST("/products", handler.ProductViewerAdmin())
+	admin.PUT("/products/:id/options", query("id", "id"), handler.SetProductOptions())
+	admin.PUT("/products/:id/variants/:variantID", query("id", "id", "variantID", "variantID"), handler.EditVariant())
+	admin.POST("/products/:id/categories", query("id", "id"), handler.AssignCategories())
+	admin.DELETE("/products/:id/categories/:categoryID", query("id", "id", "categoryID", "categoryID"), handler.UnassignCategory())
+	admin.POST("/categories", handler.AddCategory())
+	admin.PUT("/categories/:id", query("id", "id"), handler.EditCategory())
+	admin.DELETE("/categories/:id", query("id", "id"), handler.DeleteCategory())
+
+	admin.POST("/shipping-methods", handler.AddShippingMethod())
+	admin.POST("/orders/:id/shipments", query("id", "orderID"), handler.CreateShipment())
+	admin.POST("/shipments/:id/events", query("id", "id"), handler.UpdateShipment())
+
+	admin.GET("/reviews", handler.ListReviewsForModeration())
+	admin.PUT("/reviews/:id", query("id", "id"), handler.ModerateReview())
+	admin.GET("/cart-reminders", handler.CartReminderStats())
+
+	admin.GET("/jobs", handler.ListJobs())
+	admin.GET("/jobs/:id", query("id", "id"), handler.GetJob())
+	admin.POST("/jobs/:id/retry", query("id", "id"), handler.RetryJob())
+	admin.GET("/recurring-jobs", handler.ListRecurringJobs())
+	admin.POST("/recurring-jobs/:name/run", query("name", "name"), handler.RunRecurringJob())
+
+	admin.POST("/webhooks", handler.AddWebhook())
+	admin.GET("/webhooks", handler.ListWebhooks())
+	admin.PUT("/webhooks/:id", query("id", "id"), handler.EditWebhook())
+	admin.DELETE("/webhooks/:id", query("id", "id"), handler.DeleteWebhook())
+	admin.GET("/webhook-deliveries", handler.ListWebhookDeliveries())
+	admin.GET("/webhook-deliveries/:id", query("id", "id"), handler.GetWebhookDelivery())
+	admin.POST("/webhook-deliveries/:id/redeliver", query("id", "id"), handler.RedeliverWebhook())
+
+	admin.GET("/config", handler.GetConfig())
+}
+
+// query copies path parameters into the query string under the names the
+// handlers read. pairs are (path parameter, query key) pairs.
+func query(pairs ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		values := c.Request.URL.Query()
+		for i := 0; i+1 < len(pairs); i += 2 {
+			values.Set(pairs[i+1], c.Param(pairs[i]))
+		}
+		c.Request.URL.RawQuery = values.Encode()
+		c.Next()
+	}
+}
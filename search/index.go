@@ -0,0 +1,332 @@
+package search
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Kinds of entries held by the index
+const (
+	ProductKind  = "product"
+	CategoryKind = "category"
+)
+
+// Entry is one indexed product or category name
+type Entry struct {
+	ID   string `json:"id"`
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	Slug string `json:"slug,omitempty"`
+
+	normalized string
+	tokens     []string
+}
+
+// Index is an in-process index of product and category names used for
+// autocomplete and "did you mean" suggestions. It is safe for concurrent use.
+type Index struct {
+	mu      sync.RWMutex
+	entries map[string]*Entry
+	terms   map[string]int
+}
+
+func NewIndex() *Index {
+	return &Index{
+		entries: make(map[string]*Entry),
+		terms:   make(map[string]int),
+	}
+}
+
+// Upsert adds the entry or replaces the one with the same kind and id
+func (i *Index) Upsert(entry Entry) {
+	entry.normalized = Normalize(entry.Name)
+	entry.tokens = strings.Fields(entry.normalized)
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.remove(entry.Kind, entry.ID)
+	i.entries[entryKey(entry.Kind, entry.ID)] = &entry
+	for _, token := range entry.tokens {
+		i.terms[token]++
+	}
+}
+
+func (i *Index) Remove(kind, id string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.remove(kind, id)
+}
+
+// Replace swaps the whole content of the index for the given entries
+func (i *Index) Replace(entries []Entry) {
+	fresh := NewIndex()
+	for _, entry := range entries {
+		fresh.Upsert(entry)
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.entries = fresh.entries
+	i.terms = fresh.terms
+}
+
+func (i *Index) remove(kind, id string) {
+	old, ok := i.entries[entryKey(kind, id)]
+	if !ok {
+		return
+	}
+
+	for _, token := range old.tokens {
+		i.terms[token]--
+		if i.terms[token] <= 0 {
+			delete(i.terms, token)
+		}
+	}
+	delete(i.entries, entryKey(kind, id))
+}
+
+// Complete returns up to limit entries matching the prefix. Names starting with
+// the whole prefix rank above names where every typed word only starts some
+// word of the name; shorter names rank first within each group.
+func (i *Index) Complete(prefix string, limit int) []Entry {
+	query := Normalize(prefix)
+	words := strings.Fields(query)
+	if len(words) == 0 || limit <= 0 {
+		return make([]Entry, 0)
+	}
+
+	type match struct {
+		entry *Entry
+		score int
+	}
+
+	i.mu.RLock()
+	matches := make([]match, 0)
+	for _, entry := range i.entries {
+		switch {
+		case strings.HasPrefix(entry.normalized, query):
+			matches = append(matches, match{entry, 2})
+		case wordsPrefixTokens(words, entry.tokens):
+			matches = append(matches, match{entry, 1})
+		}
+	}
+	i.mu.RUnlock()
+
+	sort.Slice(matches, func(a, b int) bool {
+		if matches[a].score != matches[b].score {
+			return matches[a].score > matches[b].score
+		}
+		if len(matches[a].entry.normalized) != len(matches[b].entry.normalized) {
+			return len(matches[a].entry.normalized) < len(matches[b].entry.normalized)
+		}
+		return matches[a].entry.normalized < matches[b].entry.normalized
+	})
+
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+
+	completions := make([]Entry, 0, len(matches))
+	for _, m := range matches {
+		completions = append(completions, *m.entry)
+	}
+
+	return completions
+}
+
+// DidYouMean returns up to limit corrected versions of the query, replacing
+// words that are not in the index with the closest indexed words. It returns
+// nothing when every word is already known.
+func (i *Index) DidYouMean(query string, limit int) []string {
+	words := strings.Fields(Normalize(query))
+	if len(words) == 0 || limit <= 0 {
+		return make([]string, 0)
+	}
+
+	i.mu.RLock()
+	candidates := make([][]string, len(words))
+	corrected := false
+	for n, word := range words {
+		if _, known := i.terms[word]; known {
+			candidates[n] = []string{word}
+			continue
+		}
+
+		candidates[n] = i.closestTerms(word, limit)
+		if len(candidates[n]) == 0 {
+			candidates[n] = []string{word}
+			continue
+		}
+		corrected = true
+	}
+	i.mu.RUnlock()
+
+	if !corrected {
+		return make([]string, 0)
+	}
+
+	best := make([]string, len(words))
+	for n := range words {
+		best[n] = candidates[n][0]
+	}
+
+	suggestions := []string{strings.Join(best, " ")}
+	for n := range words {
+		for _, alternative := range candidates[n][1:] {
+			if len(suggestions) >= limit {
+				return suggestions
+			}
+			variant := append([]string(nil), best...)
+			variant[n] = alternative
+			suggestions = append(suggestions, strings.Join(variant, " "))
+		}
+	}
+
+	return suggestions
+}
+
+// closestTerms returns the indexed terms within the edit distance allowed for
+// the word, nearest and then most frequent first. Callers hold the read lock.
+func (i *Index) closestTerms(word string, limit int) []string {
+	maxDistance := 1
+	if len([]rune(word)) > 4 {
+		maxDistance = 2
+	}
+
+	type candidate struct {
+		term     string
+		distance int
+		count    int
+	}
+
+	found := make([]candidate, 0)
+	for term, count := range i.terms {
+		if d := distance(word, term, maxDistance); d <= maxDistance {
+			found = append(found, candidate{term, d, count})
+		}
+	}
+
+	sort.Slice(found, func(a, b int) bool {
+		if found[a].distance != found[b].distance {
+			return found[a].distance < found[b].distance
+		}
+		if found[a].count != found[b].count {
+			return found[a].count > found[b].count
+		}
+		return found[a].term < found[b].term
+	})
+
+	if len(found) > limit {
+		found = found[:limit]
+	}
+
+	terms := make([]string, 0, len(found))
+	for _, c := range found {
+		terms = append(terms, c.term)
+	}
+
+	return terms
+}
+
+// Normalize lowercases the text, strips diacritics and turns everything that
+// is not a letter or digit into single spaces
+func Normalize(text string) string {
+	stripMarks := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	plain, _, err := transform.String(stripMarks, text)
+	if err != nil {
+		plain = text
+	}
+
+	fields := strings.FieldsFunc(strings.ToLower(plain), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	return strings.Join(fields, " ")
+}
+
+// distance is the optimal string alignment distance between a and b, so a
+// swap of two neighbouring letters ("iphnoe") counts as one edit. It gives up
+// early and returns max+1 once the distance is known to exceed max.
+func distance(a, b string, max int) int {
+	ra, rb := []rune(a), []rune(b)
+	if abs(len(ra)-len(rb)) > max {
+		return max + 1
+	}
+
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for x := 1; x <= len(ra); x++ {
+		curr[0] = x
+		rowMin := curr[0]
+		for y := 1; y <= len(rb); y++ {
+			cost := 1
+			if ra[x-1] == rb[y-1] {
+				cost = 0
+			}
+			curr[y] = minInt(prev[y]+1, curr[y-1]+1, prev[y-1]+cost)
+			if x > 1 && y > 1 && ra[x-1] == rb[y-2] && ra[x-2] == rb[y-1] {
+				curr[y] = minInt(curr[y], prev2[y-2]+1)
+			}
+			if curr[y] < rowMin {
+				rowMin = curr[y]
+			}
+		}
+		if rowMin > max {
+			return max + 1
+		}
+		prev2, prev, curr = prev, curr, prev2
+	}
+
+	return prev[len(rb)]
+}
+
+func wordsPrefixTokens(words, tokens []string) bool {
+	for _, word := range words {
+		found := false
+		for _, token := range tokens {
+			if strings.HasPrefix(token, word) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func entryKey(kind, id string) string {
+	return kind + ":" + id
+}
+
+func minInt(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
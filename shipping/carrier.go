@@ -0,0 +1,42 @@
+package shipping
+
+import (
+	"context"
+	"errors"
+
+	"github.com/mayuka-c/e-commerce/models"
+)
+
+var (
+	ErrUnknownCarrier = errors.New("carrier is not configured")
+	ErrUnknownParcel  = errors.New("carrier has no parcel with this tracking number")
+)
+
+// Carrier books parcels with a delivery company and reports their progress
+type Carrier interface {
+	// Name is the value stored in ShippingMethod.Carrier and Shipment.Carrier
+	Name() string
+	// CreateShipment books the parcel and returns its tracking number
+	CreateShipment(ctx context.Context, shipment models.Shipment, address models.Address) (string, error)
+	// Track returns every tracking event the carrier knows for the parcel
+	Track(ctx context.Context, trackingNumber string) ([]models.ShipmentEvent, error)
+}
+
+// Carriers is the set of carriers the service can book shipments with
+type Carriers map[string]Carrier
+
+func NewCarriers(carriers ...Carrier) Carriers {
+	registry := make(Carriers, len(carriers))
+	for _, carrier := range carriers {
+		registry[carrier.Name()] = carrier
+	}
+	return registry
+}
+
+func (c Carriers) Get(name string) (Carrier, error) {
+	carrier, ok := c[name]
+	if !ok {
+		return nil, ErrUnknownCarrier
+	}
+	return carrier, nil
+}
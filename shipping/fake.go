@@ -0,0 +1,83 @@
+package shipping
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mayuka-c/e-commerce/constants"
+	"github.com/mayuka-c/e-commerce/models"
+)
+
+// FakeCarrier is an in-memory Carrier for local runs and tests. Parcels only
+// move when Advance is called.
+type FakeCarrier struct {
+	name    string
+	mu      sync.Mutex
+	seq     int
+	parcels map[string][]models.ShipmentEvent
+}
+
+func NewFakeCarrier(name string) *FakeCarrier {
+	return &FakeCarrier{
+		name:    name,
+		parcels: make(map[string][]models.ShipmentEvent),
+	}
+}
+
+func (f *FakeCarrier) Name() string {
+	return f.name
+}
+
+func (f *FakeCarrier) CreateShipment(ctx context.Context, shipment models.Shipment, address models.Address) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.seq++
+	trackingNumber := fmt.Sprintf("%s-%06d", strings.ToUpper(f.name), f.seq)
+
+	location := ""
+	if address.City != nil {
+		location = *address.City
+	}
+
+	f.parcels[trackingNumber] = []models.ShipmentEvent{{
+		Status:      constants.ShipmentLabelCreated,
+		Location:    location,
+		Description: "Shipping label created",
+		Occurred_At: time.Now(),
+	}}
+
+	return trackingNumber, nil
+}
+
+func (f *FakeCarrier) Track(ctx context.Context, trackingNumber string) ([]models.ShipmentEvent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	events, ok := f.parcels[trackingNumber]
+	if !ok {
+		return nil, ErrUnknownParcel
+	}
+
+	return append([]models.ShipmentEvent(nil), events...), nil
+}
+
+// Advance records a new tracking event for the parcel
+func (f *FakeCarrier) Advance(trackingNumber string, event models.ShipmentEvent) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.parcels[trackingNumber]; !ok {
+		return ErrUnknownParcel
+	}
+
+	if event.Occurred_At.IsZero() {
+		event.Occurred_At = time.Now()
+	}
+	f.parcels[trackingNumber] = append(f.parcels[trackingNumber], event)
+
+	return nil
+}
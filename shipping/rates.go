@@ -0,0 +1,104 @@
+package shipping
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/mayuka-c/e-commerce/models"
+)
+
+var (
+	ErrNoZone = errors.New("shipping method does not deliver to this pincode")
+	ErrNoRate = errors.New("shipping method has no rate for this order")
+)
+
+// ResolveZone returns the zone of the method whose pincode prefix is the
+// longest match for the given pincode
+func ResolveZone(method models.ShippingMethod, pincode string) (string, error) {
+
+	zone := ""
+	matched := -1
+
+	for _, z := range method.Zones {
+		if z.Name == nil {
+			continue
+		}
+		for _, prefix := range z.Pincode_Prefixes {
+			if strings.HasPrefix(pincode, prefix) && len(prefix) > matched {
+				zone = *z.Name
+				matched = len(prefix)
+			}
+		}
+	}
+
+	if matched < 0 {
+		return "", ErrNoZone
+	}
+
+	return zone, nil
+}
+
+// Quote prices an order of the given weight (grams) and value for the method.
+// Orders at or above the free shipping threshold ship for free, otherwise the
+// cheapest matching rate wins.
+func Quote(method models.ShippingMethod, pincode string, weight, orderValue uint64) (models.ShippingQuote, error) {
+
+	quote := models.ShippingQuote{
+		Method_ID: method.Method_ID,
+	}
+	if method.Name != nil {
+		quote.Name = *method.Name
+	}
+	if method.Carrier != nil {
+		quote.Carrier = *method.Carrier
+	}
+
+	zone, err := ResolveZone(method, pincode)
+	if err != nil {
+		return quote, err
+	}
+
+	if method.Free_Shipping_Threshold != nil && orderValue >= *method.Free_Shipping_Threshold {
+		quote.Free_Shipping = true
+		return quote, nil
+	}
+
+	found := false
+	for _, rate := range method.Rates {
+		if rate.Zone == nil || *rate.Zone != zone {
+			continue
+		}
+		if !inRange(weight, rate.Min_Weight, rate.Max_Weight) || !inRange(orderValue, rate.Min_Order_Value, rate.Max_Order_Value) {
+			continue
+		}
+		if !found || rate.Price < quote.Price {
+			quote.Price = rate.Price
+			found = true
+		}
+	}
+
+	if !found {
+		return quote, ErrNoRate
+	}
+
+	return quote, nil
+}
+
+// CartTotals sums the weight and value of the cart lines
+func CartTotals(cart []models.ProductUser) (weight uint64, value uint64) {
+
+	for _, item := range cart {
+		if item.Weight != nil {
+			weight += *item.Weight
+		}
+		if item.Price > 0 {
+			value += uint64(item.Price)
+		}
+	}
+
+	return weight, value
+}
+
+func inRange(value, min, max uint64) bool {
+	return value >= min && (max == 0 || value < max)
+}
@@ -0,0 +1,85 @@
+package shipping
+
+import (
+	"testing"
+
+	"github.com/mayuka-c/e-commerce/models"
+)
+
+func str(s string) *string { return &s }
+
+func TestQuote(t *testing.T) {
+
+	threshold := uint64(1000)
+	method := models.ShippingMethod{
+		Name:                    str("Standard"),
+		Carrier:                 str("fake"),
+		Free_Shipping_Threshold: &threshold,
+		Zones: []models.ShippingZone{
+			{Name: str("india"), Pincode_Prefixes: []string{"1", "2", "5"}},
+			{Name: str("metro"), Pincode_Prefixes: []string{"11", "56"}},
+		},
+		Rates: []models.ShippingRate{
+			{Zone: str("india"), Max_Weight: 1000, Price: 80},
+			{Zone: str("india"), Min_Weight: 1000, Price: 150},
+			{Zone: str("india"), Min_Weight: 500, Max_Weight: 2000, Price: 120},
+			{Zone: str("metro"), Max_Weight: 1000, Price: 40},
+			{Zone: str("metro"), Min_Order_Value: 500, Price: 20},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		pincode string
+		weight  uint64
+		value   uint64
+		want    uint64
+		free    bool
+		err     error
+	}{
+		{"single matching rate", "100010", 200, 100, 80, false, nil},
+		{"cheapest of overlapping rates", "200001", 1500, 100, 120, false, nil},
+		{"max weight is exclusive", "200001", 1000, 100, 120, false, nil},
+		{"unbounded max", "200001", 5000, 100, 150, false, nil},
+		{"longest prefix picks the zone", "110001", 200, 100, 40, false, nil},
+		{"cheapest by order value", "560001", 200, 600, 20, false, nil},
+		{"free at the threshold", "200001", 5000, 1000, 0, true, nil},
+		{"free above the threshold", "560001", 200, 1500, 0, true, nil},
+		{"just below the threshold", "200001", 200, 999, 80, false, nil},
+		{"no zone for the pincode", "400001", 200, 100, 0, false, ErrNoZone},
+		{"no rate for the order", "110001", 1500, 100, 0, false, ErrNoRate},
+	}
+
+	for _, test := range tests {
+		quote, err := Quote(method, test.pincode, test.weight, test.value)
+		if err != test.err {
+			t.Errorf("%s: got error %v, want %v", test.name, err, test.err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if quote.Price != test.want || quote.Free_Shipping != test.free {
+			t.Errorf("%s: got price %d free %t, want price %d free %t", test.name, quote.Price, quote.Free_Shipping, test.want, test.free)
+		}
+		if quote.Name != "Standard" || quote.Carrier != "fake" {
+			t.Errorf("%s: quote names %q by %q", test.name, quote.Name, quote.Carrier)
+		}
+	}
+}
+
+func TestQuoteWithoutThreshold(t *testing.T) {
+
+	method := models.ShippingMethod{
+		Zones: []models.ShippingZone{{Name: str("india"), Pincode_Prefixes: []string{""}}},
+		Rates: []models.ShippingRate{{Zone: str("india"), Price: 60}},
+	}
+
+	quote, err := Quote(method, "400001", 200, 1000000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if quote.Free_Shipping || quote.Price != 60 {
+		t.Errorf("got price %d free %t, want price 60 and no free shipping", quote.Price, quote.Free_Shipping)
+	}
+}
@@ -0,0 +1,124 @@
+// Package telemetry sets up OpenTelemetry tracing. HTTP requests, MongoDB
+// commands, jobs and outgoing webhook calls get spans, and the W3C trace
+// context is taken from incoming requests and passed on to outgoing ones.
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+
+	"go.mongodb.org/mongo-driver/event"
+	"go.opentelemetry.io/contrib/instrumentation/go.mongodb.org/mongo-driver/mongo/otelmongo"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ServiceName is the service.name spans are reported under
+const ServiceName = "e-commerce"
+
+const instrumentation = "github.com/mayuka-c/e-commerce"
+
+// Exporters Setup accepts
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterFile   = "file"
+	ExporterOTLP   = "otlp"
+)
+
+// Setup installs the tracer provider and the trace context and baggage
+// propagators. exporter is one of the Exporter constants; file is the path
+// spans are appended to for ExporterFile. The OTLP exporter is configured
+// through the standard OTEL_EXPORTER_OTLP_* variables. sampleRatio is the
+// share of new traces kept, a sampled parent is always followed.
+//
+// The returned function flushes buffered spans and must be called before the
+// process exits.
+func Setup(ctx context.Context, exporter, file string, sampleRatio float64) (func(context.Context) error, error) {
+
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var spanExporter sdktrace.SpanExporter
+	var err error
+
+	switch exporter {
+	case ExporterNone:
+		// spans are not recorded, but incoming trace context is still passed on
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		spanExporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case ExporterFile:
+		var out *os.File
+		out, err = os.OpenFile(file, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err == nil {
+			spanExporter, err = stdouttrace.New(stdouttrace.WithWriter(out))
+		}
+	case ExporterOTLP:
+		spanExporter, err = otlptracehttp.New(ctx)
+	default:
+		return nil, fmt.Errorf("unknown TRACE_EXPORTER %q, use none, stdout, file or otlp", exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(ServiceName)))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(spanExporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// Start starts a span for work the service does on its own, such as a job
+func Start(ctx context.Context, name string, options ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentation).Start(ctx, name, options...)
+}
+
+// Transport wraps base so every outgoing request gets a client span and
+// carries the trace context in its headers
+func Transport(base http.RoundTripper) http.RoundTripper {
+	return otelhttp.NewTransport(base)
+}
+
+// MongoMonitor gives every MongoDB command a span, named after the collection
+// and command, under the span of the context the command was sent with. The
+// command itself is left out since it holds user data and secrets.
+func MongoMonitor() *event.CommandMonitor {
+	return otelmongo.NewMonitor(otelmongo.WithCommandAttributeDisabled(true))
+}
+
+// TraceID returns the id of the trace ctx belongs to, or "" when there is none
+func TraceID(ctx context.Context) string {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.HasTraceID() {
+		return ""
+	}
+	return spanContext.TraceID().String()
+}
+
+// End ends span, marking it failed when err is not nil
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
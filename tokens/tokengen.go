@@ -9,8 +9,8 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 
 	jwt "github.com/dgrijalva/jwt-go"
-	log "github.com/sirupsen/logrus"
 
+	"github.com/mayuka-c/e-commerce/config"
 	"github.com/mayuka-c/e-commerce/database"
 )
 
@@ -19,45 +19,52 @@ type SignedDetails struct {
 	FirstName string
 	LastName  string
 	UUID      string
+	Role      string
 	jwt.StandardClaims
 }
 
-var JWT_Key = "my_secret_value"
-
 type TokenGenrator struct {
 	dbClient *database.DBClient
+	// key signs the tokens
+	key             []byte
+	tokenTTL        time.Duration
+	refreshTokenTTL time.Duration
 }
 
-func NewTokenGenerator(dbClient *database.DBClient) *TokenGenrator {
+func NewTokenGenerator(dbClient *database.DBClient, authConfig config.AuthConfig) *TokenGenrator {
 	return &TokenGenrator{
-		dbClient: dbClient,
+		dbClient:        dbClient,
+		key:             []byte(authConfig.JWTSecret),
+		tokenTTL:        authConfig.TokenTTL,
+		refreshTokenTTL: authConfig.RefreshTokenTTL,
 	}
 }
 
-func (t *TokenGenrator) TokenGenerator(email, firstName, lastName, uuid string) (signedToken string, signedRefreshToken string, err error) {
+func (t *TokenGenrator) TokenGenerator(email, firstName, lastName, uuid, role string) (signedToken string, signedRefreshToken string, err error) {
 
 	claims := &SignedDetails{
 		Email:     email,
 		FirstName: firstName,
 		LastName:  lastName,
 		UUID:      uuid,
+		Role:      role,
 		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: time.Now().Local().Add(time.Hour * time.Duration(24)).Unix(),
+			ExpiresAt: time.Now().Local().Add(t.tokenTTL).Unix(),
 		},
 	}
 
 	refreshClaims := &SignedDetails{
 		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: time.Now().Local().Add(time.Hour * time.Duration(168)).Unix(),
+			ExpiresAt: time.Now().Local().Add(t.refreshTokenTTL).Unix(),
 		},
 	}
 
-	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(JWT_Key))
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(t.key)
 	if err != nil {
 		return "", "", err
 	}
 
-	refreshToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshClaims).SignedString([]byte(JWT_Key))
+	refreshToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshClaims).SignedString(t.key)
 	if err != nil {
 		return "", "", err
 	}
@@ -70,7 +77,7 @@ func (t *TokenGenrator) ValidateToken(signedtoken string) (claims *SignedDetails
 	claims = &SignedDetails{}
 
 	token, err := jwt.ParseWithClaims(signedtoken, claims, func(token *jwt.Token) (interface{}, error) {
-		return []byte(JWT_Key), nil
+		return t.key, nil
 	})
 	if err != nil {
 		msg = err.Error()
@@ -90,10 +97,8 @@ func (t *TokenGenrator) ValidateToken(signedtoken string) (claims *SignedDetails
 	return claims, msg
 }
 
-func (t *TokenGenrator) UpdateAllTokens(signedtoken, signedrefreshtoken, user_id string) {
-
-	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
-	defer cancel()
+// UpdateAllTokens stores the user's new tokens, within the caller's deadline
+func (t *TokenGenrator) UpdateAllTokens(ctx context.Context, signedtoken, signedrefreshtoken, user_id string) error {
 
 	var updateobj primitive.D
 	updateobj = append(updateobj, bson.E{Key: "token", Value: signedtoken})
@@ -106,8 +111,5 @@ func (t *TokenGenrator) UpdateAllTokens(signedtoken, signedrefreshtoken, user_id
 		Upsert: &upsert,
 	}
 
-	err := t.dbClient.UpdateOne(ctx, t.dbClient.GetUserCollection(), filter, bson.D{{Key: "$set", Value: updateobj}}, opt)
-	if err != nil {
-		log.Panic(err)
-	}
+	return t.dbClient.UpdateOne(ctx, t.dbClient.GetUserCollection(), filter, bson.D{{Key: "$set", Value: updateobj}}, opt)
 }
@@ -0,0 +1,178 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/mayuka-c/e-commerce/constants"
+	"github.com/mayuka-c/e-commerce/database"
+	"github.com/mayuka-c/e-commerce/events"
+	"github.com/mayuka-c/e-commerce/models"
+	"github.com/mayuka-c/e-commerce/telemetry"
+)
+
+// JobKind is the background job that sends one delivery
+const JobKind = "webhook_delivery"
+
+// MaxAttempts is how often a delivery is tried before it goes to the
+// dead-letter queue. With the job runner's backoff that spans about two hours.
+const MaxAttempts = 8
+
+var (
+	ErrEndpointDisabled = errors.New("webhook endpoint is disabled")
+)
+
+// Deliverer turns events into signed HTTP deliveries to the merchant endpoints
+// subscribed to them
+type Deliverer struct {
+	dbClient *database.DBClient
+	client   *http.Client
+}
+
+// NewDeliverer builds the deliverer. Unless allowPrivate is set, deliveries
+// to loopback, link-local and private addresses are refused.
+func NewDeliverer(dbClient *database.DBClient, allowPrivate bool) *Deliverer {
+	return &Deliverer{
+		dbClient: dbClient,
+		client:   &http.Client{Timeout: 10 * time.Second, Transport: telemetry.Transport(transport(allowPrivate))},
+	}
+}
+
+// DeliveryJob is the job payload for sending the delivery
+func DeliveryJob(delivery_id primitive.ObjectID) map[string]interface{} {
+	return map[string]interface{}{"delivery_id": delivery_id.Hex()}
+}
+
+// Enqueue schedules a send of the delivery
+func Enqueue(ctx context.Context, dbClient *database.DBClient, delivery_id primitive.ObjectID) error {
+	_, err := dbClient.EnqueueJob(ctx, JobKind, DeliveryJob(delivery_id), time.Now(), MaxAttempts)
+	return err
+}
+
+// Subscribe is the event bus subscriber. It stores a delivery per subscribed
+// endpoint together with the job that sends it.
+func (d *Deliverer) Subscribe(ctx context.Context, event events.Event) error {
+
+	endpoints, err := d.dbClient.SubscribedWebhookEndpoints(ctx, event.Type)
+	if err != nil {
+		return err
+	}
+	if len(endpoints) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	for _, endpoint := range endpoints {
+		err = d.dbClient.WithTransaction(ctx, func(ctx context.Context) error {
+			delivery, err := d.dbClient.CreateWebhookDelivery(ctx, models.WebhookDelivery{
+				Endpoint_ID: endpoint.Endpoint_ID,
+				Event_ID:    event.ID,
+				Event_Type:  event.Type,
+				Body:        string(body),
+			})
+			if err != nil {
+				return err
+			}
+
+			return Enqueue(ctx, d.dbClient, delivery.Delivery_ID)
+		})
+		if err != nil && err != database.ErrDeliveryExists {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Handle is the job handler that sends one delivery
+func (d *Deliverer) Handle(ctx context.Context, job models.Job) error {
+
+	hexID, _ := job.Payload["delivery_id"].(string)
+	delivery_id, err := primitive.ObjectIDFromHex(hexID)
+	if err != nil {
+		return fmt.Errorf("job has no valid delivery_id: %w", err)
+	}
+
+	delivery, err := d.dbClient.GetWebhookDelivery(ctx, delivery_id)
+	if err != nil {
+		return err
+	}
+	if delivery.Status == constants.DeliveryDelivered {
+		return nil
+	}
+
+	attempt := models.WebhookAttempt{At: time.Now()}
+
+	endpoint, err := d.dbClient.GetWebhookEndpoint(ctx, delivery.Endpoint_ID)
+	if err == database.ErrCantFindWebhook {
+		// nothing to retry against any more
+		attempt.Error = err.Error()
+		return d.dbClient.RecordWebhookAttempt(ctx, delivery_id, attempt, constants.DeliveryDead)
+	}
+	if err != nil {
+		return err
+	}
+
+	if endpoint.Active {
+		attempt.Status_Code, err = d.send(ctx, endpoint, delivery)
+	} else {
+		err = ErrEndpointDisabled
+	}
+	attempt.Duration_Ms = time.Since(attempt.At).Milliseconds()
+
+	status := constants.DeliveryDelivered
+	if err != nil {
+		attempt.Error = err.Error()
+		status = constants.DeliveryFailed
+		if job.Attempts >= job.Max_Attempts {
+			status = constants.DeliveryDead
+		}
+	}
+
+	if recordErr := d.dbClient.RecordWebhookAttempt(ctx, delivery_id, attempt, status); recordErr != nil {
+		return recordErr
+	}
+
+	return err
+}
+
+// send posts the delivery body and treats any 2xx answer as success
+func (d *Deliverer) send(ctx context.Context, endpoint models.WebhookEndpoint, delivery models.WebhookDelivery) (int, error) {
+
+	body := []byte(delivery.Body)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, *endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "e-commerce-webhooks/1")
+	req.Header.Set(SignatureHeader, Sign(endpoint.Secret, time.Now(), body))
+	req.Header.Set(DeliveryHeader, delivery.Delivery_ID.Hex())
+	req.Header.Set(EventTypeHeader, delivery.Event_Type)
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint answered %s", resp.Status)
+	}
+
+	return resp.StatusCode, nil
+}
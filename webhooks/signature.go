@@ -0,0 +1,71 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers sent with every delivery
+const (
+	SignatureHeader = "X-Webhook-Signature"
+	DeliveryHeader  = "X-Webhook-Delivery"
+	EventTypeHeader = "X-Webhook-Event"
+)
+
+var (
+	ErrInvalidSignature = errors.New("webhook signature does not match")
+	ErrStaleSignature   = errors.New("webhook signature timestamp is outside the tolerance")
+)
+
+// Sign returns the signature header value "t=<unix seconds>,v1=<hex>" where
+// v1 is the HMAC-SHA256 of "<unix seconds>.<body>" keyed with the endpoint
+// secret. Signing the timestamp lets receivers reject replayed requests.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	t := strconv.FormatInt(timestamp.Unix(), 10)
+	return "t=" + t + ",v1=" + hex.EncodeToString(mac(secret, t, body))
+}
+
+// Verify checks a signature header made by Sign, for use by receivers
+func Verify(secret, header string, body []byte, tolerance time.Duration, now time.Time) error {
+
+	var t, v1 string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			t = value
+		case "v1":
+			v1 = value
+		}
+	}
+
+	unix, err := strconv.ParseInt(t, 10, 64)
+	if err != nil || v1 == "" {
+		return ErrInvalidSignature
+	}
+
+	got, err := hex.DecodeString(v1)
+	if err != nil || !hmac.Equal(got, mac(secret, t, body)) {
+		return ErrInvalidSignature
+	}
+
+	age := now.Sub(time.Unix(unix, 0))
+	if age > tolerance || age < -tolerance {
+		return ErrStaleSignature
+	}
+
+	return nil
+}
+
+func mac(secret, t string, body []byte) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(t))
+	h.Write([]byte("."))
+	h.Write(body)
+	return h.Sum(nil)
+}
@@ -0,0 +1,62 @@
+package webhooks
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignFormat(t *testing.T) {
+
+	now := time.Unix(1700000000, 0)
+	header := Sign("secret", now, []byte(`{"type":"order.placed"}`))
+
+	prefix := "t=" + strconv.FormatInt(now.Unix(), 10) + ",v1="
+	if !strings.HasPrefix(header, prefix) {
+		t.Fatalf("Sign = %q, want prefix %q", header, prefix)
+	}
+	if len(header)-len(prefix) != 64 {
+		t.Errorf("Sign = %q, want a hex SHA-256 after v1=", header)
+	}
+	if Sign("secret", now, []byte(`{"type":"order.placed"}`)) != header {
+		t.Error("Sign is not deterministic")
+	}
+}
+
+func TestVerify(t *testing.T) {
+
+	body := []byte(`{"type":"order.placed"}`)
+	signedAt := time.Unix(1700000000, 0)
+	header := Sign("secret", signedAt, body)
+	tolerance := 5 * time.Minute
+
+	tests := []struct {
+		name   string
+		secret string
+		header string
+		body   string
+		now    time.Time
+		want   error
+	}{
+		{"valid", "secret", header, string(body), signedAt, nil},
+		{"within tolerance", "secret", header, string(body), signedAt.Add(tolerance), nil},
+		{"clock behind", "secret", header, string(body), signedAt.Add(-tolerance), nil},
+		{"spaces after commas", "secret", strings.Replace(header, ",", ", ", 1), string(body), signedAt, nil},
+		{"too old", "secret", header, string(body), signedAt.Add(tolerance + time.Second), ErrStaleSignature},
+		{"too far ahead", "secret", header, string(body), signedAt.Add(-tolerance - time.Second), ErrStaleSignature},
+		{"wrong secret", "other", header, string(body), signedAt, ErrInvalidSignature},
+		{"changed body", "secret", header, `{"type":"order.cancelled"}`, signedAt, ErrInvalidSignature},
+		{"changed timestamp", "secret", strings.Replace(header, "t=1700000000", "t=1700000001", 1), string(body), signedAt, ErrInvalidSignature},
+		{"no timestamp", "secret", header[strings.Index(header, "v1="):], string(body), signedAt, ErrInvalidSignature},
+		{"no signature", "secret", "t=1700000000", string(body), signedAt, ErrInvalidSignature},
+		{"signature not hex", "secret", "t=1700000000,v1=zz", string(body), signedAt, ErrInvalidSignature},
+		{"empty header", "secret", "", string(body), signedAt, ErrInvalidSignature},
+	}
+
+	for _, test := range tests {
+		if err := Verify(test.secret, test.header, []byte(test.body), tolerance, test.now); err != test.want {
+			t.Errorf("%s: got %v, want %v", test.name, err, test.want)
+		}
+	}
+}
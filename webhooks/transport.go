@@ -0,0 +1,78 @@
+package webhooks
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenAddress is returned when an endpoint resolves to an address
+// inside the network the service runs in
+var ErrForbiddenAddress = errors.New("webhook endpoint resolves to a loopback, link-local or private address")
+
+// reservedNets are the ranges net.IP has no predicate for: this network,
+// carrier-grade NAT and benchmarking
+var reservedNets = []*net.IPNet{
+	mustParseCIDR("0.0.0.0/8"),
+	mustParseCIDR("100.64.0.0/10"),
+	mustParseCIDR("198.18.0.0/15"),
+}
+
+// transport dials endpoints only on public addresses. The check runs on the
+// address each connection is made to, after DNS and for every redirect, so
+// a name that resolves inside can't slip through.
+func transport(allowPrivate bool) http.RoundTripper {
+
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	if allowPrivate {
+		return t
+	}
+
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Control: publicOnly}
+	t.DialContext = dialer.DialContext
+	// a proxy would make the connection to the endpoint itself
+	t.Proxy = nil
+
+	return t
+}
+
+func publicOnly(network, address string, _ syscall.RawConn) error {
+
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || !publicIP(ip) {
+		return ErrForbiddenAddress
+	}
+
+	return nil
+}
+
+func publicIP(ip net.IP) bool {
+
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsMulticast() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() {
+		return false
+	}
+
+	for _, reserved := range reservedNets {
+		if reserved.Contains(ip) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func mustParseCIDR(cidr string) *net.IPNet {
+	_, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		panic(err)
+	}
+	return ipNet
+}